@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pzurek/lil/internal/cache"
+	"github.com/pzurek/lil/internal/config"
+	"github.com/pzurek/lil/internal/credentials"
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/menu"
+	"github.com/pzurek/lil/internal/oauth"
+)
+
+// commandUsage describes the subcommands for -help output.
+const commandUsage = `Commands:
+  list [--json] [--cached] [--filter KEY=VALUE]...
+                             Print assigned issues grouped like the menu,
+                             e.g. --filter teams=ENG,OPS --filter due_within=7
+  open IDENTIFIER            Open an issue (e.g. ENG-123) in the browser
+  create --team KEY [--project NAME] [--description TEXT] TITLE
+                             Create an issue assigned to you
+  auth login                 Sign in with OAuth in the browser, or store a
+                             Linear API key read from standard input
+  auth logout                Remove the stored API key
+  auth status                Show where the API key comes from and whose it is
+
+Every command accepts --workspace NAME to pick one of the configured
+workspaces; the first one is used by default.
+
+Without a command, lil runs in the menu bar / system tray.
+`
+
+// cli runs the headless subcommands. Its dependencies are fields so tests can
+// substitute fakes for Linear, the cache, the credential stores and the
+// browser.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	config *config.Config
+
+	// backend returns the Linear client and cache of the named workspace.
+	// An empty name selects the first workspace.
+	backend func(workspace string) (*backend, error)
+	open    func(url string)
+}
+
+// backend is what the subcommands use of a single workspace.
+type backend struct {
+	fetch       func(ctx context.Context, filter linear.IssueFilter) ([]linear.Issue, error)
+	fetchTeams  func(ctx context.Context) ([]linear.Team, error)
+	findProject func(ctx context.Context, name string) (linear.Project, error)
+	createIssue func(ctx context.Context, input linear.IssueInput) (linear.CreatedIssue, error)
+	loadCache   func() (cache.Entry, error)
+	saveCache   func([]linear.Issue) error
+
+	// account names the workspace's key in credentials.
+	account     string
+	credentials credentials.Store
+	// checkKey returns the user an API key belongs to.
+	checkKey func(ctx context.Context, key string) (linear.Viewer, error)
+	// oauthLogin runs the OAuth browser flow and stores the token. It is nil
+	// unless the workspace signs in with OAuth.
+	oauthLogin func(ctx context.Context, open func(url string)) error
+	// viewer returns the user the stored credentials belong to.
+	viewer func(ctx context.Context) (linear.Viewer, error)
+}
+
+// newCLI wires the subcommands to the real Linear clients, caches, credential
+// stores and browser.
+func newCLI(stdin io.Reader, stdout, stderr io.Writer) *cli {
+	cfg := currentConfig()
+	return &cli{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		config: cfg,
+		backend: func(name string) (*backend, error) {
+			ws, ok := cfg.Workspace(name)
+			if !ok {
+				return nil, fmt.Errorf("no workspace named %q in the config", name)
+			}
+			w := findWorkspace(ws.Name)
+			return &backend{
+				fetch:       w.client.FetchMatchingIssues,
+				fetchTeams:  w.client.FetchTeams,
+				findProject: w.client.FindProject,
+				createIssue: w.client.CreateIssue,
+				loadCache: func() (cache.Entry, error) {
+					return cacheStore.Load(w.name)
+				},
+				saveCache: func(issues []linear.Issue) error {
+					return cacheStore.SaveIssues(w.name, issues, time.Now())
+				},
+				account:     w.name,
+				credentials: credentials.Resolve(ws.Credentials),
+				checkKey: func(ctx context.Context, key string) (linear.Viewer, error) {
+					client := linear.NewClient(linear.WithUserAgent(userAgent()), linear.WithAPIKey(key))
+					return client.Viewer(ctx)
+				},
+				oauthLogin: oauthLogin(ws),
+				viewer:     w.client.Viewer,
+			}, nil
+		},
+		open: openURL,
+	}
+}
+
+// oauthLogin returns the OAuth browser flow of ws, or nil if it signs in
+// with an API key.
+func oauthLogin(ws config.Workspace) func(ctx context.Context, open func(url string)) error {
+	if !ws.Credentials.OAuth.Enabled() {
+		return nil
+	}
+	source := oauth.NewSource(oauth.NewConfig(ws.Credentials.OAuth), credentials.Resolve(ws.Credentials), ws.Name)
+	return source.Login
+}
+
+// filterTerms collects the terms of repeated --filter flags.
+type filterTerms []string
+
+func (f *filterTerms) String() string { return strings.Join(*f, " ") }
+
+func (f *filterTerms) Set(term string) error {
+	*f = append(*f, term)
+	return nil
+}
+
+// workspaceFlag adds the --workspace flag shared by every subcommand.
+func workspaceFlag(fs *flag.FlagSet) *string {
+	return fs.String("workspace", "", "Name of the configured workspace to use (default: the first one)")
+}
+
+// run executes the subcommand in args and returns the process exit code.
+func (c *cli) run(ctx context.Context, args []string) int {
+	var err error
+	switch args[0] {
+	case "list":
+		err = c.list(ctx, args[1:])
+	case "open":
+		err = c.openIssue(ctx, args[1:])
+	case "create":
+		err = c.create(ctx, args[1:])
+	case "auth":
+		err = c.auth(ctx, args[1:])
+	default:
+		fmt.Fprintf(c.stderr, "Unknown command %q\n\n%s", args[0], commandUsage)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// issues fetches the assigned issues matching filters, falling back to the
+// cache when Linear is unreachable. With cachedOnly it never touches the
+// network. Only issues fetched with the config file's filters are cached,
+// since those are what the menu shows.
+func (c *cli) issues(ctx context.Context, b *backend, filters config.Filters, cachedOnly bool) ([]linear.Issue, error) {
+	if cachedOnly {
+		entry, err := b.loadCache()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cached issues: %w", err)
+		}
+		return c.filterCached(entry.Issues, filters)
+	}
+
+	issues, err := b.fetch(ctx, filters.IssueFilter())
+	if err == nil {
+		if !reflect.DeepEqual(filters, c.config.Filters) {
+			return issues, nil
+		}
+		if cacheErr := b.saveCache(issues); cacheErr != nil {
+			log.Printf("Error caching issues: %v", cacheErr)
+		}
+		return issues, nil
+	}
+
+	cached, cacheErr := b.loadCache()
+	if cacheErr != nil {
+		return nil, err
+	}
+	issues, cacheErr = c.filterCached(cached.Issues, filters)
+	if cacheErr != nil {
+		return nil, err
+	}
+	fmt.Fprintf(c.stderr, "Warning: showing issues cached at %s, fetch failed: %v\n", cached.FetchedAt.Format(time.RFC1123), err)
+	return issues, nil
+}
+
+// filterCached narrows cached issues, which were fetched with the config
+// file's filters, down to filters. Whether an issue is in the current, next
+// or previous cycle can only be asked of Linear, so those filters fail.
+func (c *cli) filterCached(issues []linear.Issue, filters config.Filters) ([]linear.Issue, error) {
+	if reflect.DeepEqual(filters, c.config.Filters) {
+		return issues, nil
+	}
+	switch filters.Cycle {
+	case linear.CycleCurrent, linear.CycleNext, linear.CyclePrevious:
+		return nil, fmt.Errorf("cached issues can't be filtered by cycle=%s", filters.Cycle)
+	}
+	now := time.Now()
+	matching := []linear.Issue{}
+	for _, issue := range issues {
+		if filters.Matches(issue, now) {
+			matching = append(matching, issue)
+		}
+	}
+	return matching, nil
+}
+
+// list prints assigned issues in menu order, as text or JSON. The config
+// file's filters and grouping apply as they do to the menu, unless filters
+// are given with --filter.
+func (c *cli) list(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	jsonOutput := fs.Bool("json", false, "Print issues as JSON")
+	cachedOnly := fs.Bool("cached", false, "Use cached issues instead of fetching from Linear")
+	var terms filterTerms
+	fs.Var(&terms, "filter", "Only list issues matching `KEY=VALUE`, using the keys of the config file's filters (repeatable)")
+	workspace := workspaceFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	filters := c.config.Filters
+	if len(terms) > 0 {
+		parsed, err := config.ParseFilters(terms)
+		if err != nil {
+			return err
+		}
+		filters = parsed
+	}
+	b, err := c.backend(*workspace)
+	if err != nil {
+		return err
+	}
+
+	issues, err := c.issues(ctx, b, filters, *cachedOnly)
+	if err != nil {
+		return err
+	}
+	issues = filters.Apply(issues)
+	groups := menu.GroupBy(c.config.Grouping, issues, time.Now())
+	for _, group := range groups {
+		menu.SortIssues(c.config.SortingFor(c.config.Grouping), group.Issues)
+	}
+
+	if *jsonOutput {
+		ordered := []linear.Issue{}
+		for _, group := range groups {
+			ordered = append(ordered, group.Issues...)
+		}
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ordered)
+	}
+
+	if len(issues) == 0 {
+		fmt.Fprintln(c.stdout, "No active assigned issues")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if group.Name != "" {
+			fmt.Fprintln(w, group.Name)
+		}
+		for _, issue := range group.Issues {
+			line := "  " + issue.Identifier + "\t" + issue.Title
+			if due := menu.DueLabel(issue); due != "" {
+				line += "\t" + due
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	return w.Flush()
+}
+
+// openIssue opens an assigned issue by identifier.
+func (c *cli) openIssue(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("open", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	cachedOnly := fs.Bool("cached", false, "Use cached issues instead of fetching from Linear")
+	workspace := workspaceFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: lil open IDENTIFIER")
+	}
+	identifier := strings.ToUpper(fs.Arg(0))
+	b, err := c.backend(*workspace)
+	if err != nil {
+		return err
+	}
+
+	issues, err := c.issues(ctx, b, c.config.Filters, *cachedOnly)
+	if err != nil {
+		return err
+	}
+
+	url := issueURL(issues, identifier)
+	if url == "" {
+		return fmt.Errorf("issue %s not found and no workspace URL is known", identifier)
+	}
+	c.open(url)
+	return nil
+}
+
+// issueURL returns the URL of the issue with the given identifier. Issues that
+// aren't assigned to the viewer get a URL built from the workspace prefix of
+// any known issue, since Linear resolves /issue/ID within a workspace.
+func issueURL(issues []linear.Issue, identifier string) string {
+	for _, issue := range issues {
+		if strings.EqualFold(issue.Identifier, identifier) {
+			return issue.Url
+		}
+	}
+	for _, issue := range issues {
+		if i := strings.Index(issue.Url, "/issue/"); i >= 0 {
+			return issue.Url[:i] + "/issue/" + identifier
+		}
+	}
+	return ""
+}
+
+// create files a new issue in a team, assigned to the viewer.
+func (c *cli) create(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	teamRef := fs.String("team", "", "Key or name of the team to create the issue in (required)")
+	projectName := fs.String("project", "", "Name of the project to add the issue to")
+	description := fs.String("description", "", "Issue description, in Markdown")
+	workspace := workspaceFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	title := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if *teamRef == "" || title == "" {
+		return errors.New("usage: lil create --team KEY [--project NAME] [--description TEXT] TITLE")
+	}
+	b, err := c.backend(*workspace)
+	if err != nil {
+		return err
+	}
+
+	teams, err := b.fetchTeams(ctx)
+	if err != nil {
+		return err
+	}
+	team, ok := linear.FindTeam(teams, *teamRef)
+	if !ok {
+		return fmt.Errorf("no team with key or name %q", *teamRef)
+	}
+
+	input := linear.IssueInput{TeamID: team.Id, Title: title, Description: *description}
+	if *projectName != "" {
+		project, err := b.findProject(ctx, *projectName)
+		if err != nil {
+			return err
+		}
+		input.ProjectID = project.Id
+	}
+
+	issue, err := b.createIssue(ctx, input)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Created %s: %s\n%s\n", issue.Identifier, issue.Title, issue.Url)
+	return nil
+}
+
+// auth manages the API key of a workspace in its credential store.
+func (c *cli) auth(ctx context.Context, args []string) error {
+	const usage = "usage: lil auth login|logout|status [--workspace NAME]"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	fs := flag.NewFlagSet("auth "+args[0], flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	workspace := workspaceFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New(usage)
+	}
+	b, err := c.backend(*workspace)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "login":
+		return c.login(ctx, b)
+	case "logout":
+		return c.logout(b)
+	case "status":
+		return c.authStatus(ctx, b)
+	}
+	return errors.New(usage)
+}
+
+// loginTimeout bounds how long login waits for the user to authorize lil in
+// the browser.
+const loginTimeout = 5 * time.Minute
+
+// login stores the credentials of a workspace: an OAuth token from the
+// browser flow if OAuth is configured, or else an API key read from stdin
+// and checked with Linear.
+func (c *cli) login(ctx context.Context, b *backend) error {
+	if b.oauthLogin != nil {
+		return c.oauthLogin(ctx, b)
+	}
+
+	fmt.Fprintf(c.stderr, "Paste a personal API key for %s (Linear settings, Security & access): ", b.account)
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read API key: %w", err)
+	}
+	fmt.Fprintln(c.stderr)
+	key := strings.TrimSpace(line)
+	if key == "" {
+		return errors.New("no API key given")
+	}
+
+	viewer, err := b.checkKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("linear rejected the API key: %w", err)
+	}
+
+	store, err := storeAPIKey(b.credentials, b.account, key)
+	if errors.Is(err, credentials.ErrReadOnly) {
+		return readOnlyError(b)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store API key: %w", err)
+	}
+	fmt.Fprintf(c.stdout, "Signed in to %s as %s <%s>, key stored in %s\n", b.account, viewer.Name, viewer.Email, store.Name())
+
+	if used, _, err := locate(b); err == nil && used.Name() != store.Name() {
+		fmt.Fprintf(c.stderr, "Warning: the %s takes precedence over the stored key\n", used.Name())
+	}
+	return nil
+}
+
+// oauthLogin signs in through the browser and stores the token.
+func (c *cli) oauthLogin(ctx context.Context, b *backend) error {
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+	defer cancel()
+
+	err := b.oauthLogin(ctx, func(url string) {
+		fmt.Fprintf(c.stderr, "Opening Linear in your browser to sign in to %s. If it doesn't open, visit:\n  %s\n", b.account, url)
+		c.open(url)
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("gave up waiting for the browser after %s", loginTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	viewer, err := b.viewer(ctx)
+	if err != nil {
+		return fmt.Errorf("linear rejected the new access token: %w", err)
+	}
+	store, _, err := locate(b)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Signed in to %s as %s <%s>, token stored in %s\n", b.account, viewer.Name, viewer.Email, store.Name())
+	return nil
+}
+
+// logout removes the stored API key.
+func (c *cli) logout(b *backend) error {
+	err := b.credentials.Delete(b.account)
+	switch {
+	case errors.Is(err, credentials.ErrNotFound):
+		fmt.Fprintf(c.stdout, "No API key of %s is stored\n", b.account)
+		return nil
+	case errors.Is(err, credentials.ErrReadOnly):
+		return readOnlyError(b)
+	case err != nil:
+		return fmt.Errorf("failed to remove API key: %w", err)
+	}
+	fmt.Fprintf(c.stdout, "Removed the API key of %s from %s\n", b.account, b.credentials.Name())
+	return nil
+}
+
+// authStatus prints where the API key comes from and whose it is.
+func (c *cli) authStatus(ctx context.Context, b *backend) error {
+	store, key, err := locate(b)
+	if errors.Is(err, credentials.ErrNotFound) {
+		return fmt.Errorf("not signed in to %s (looked in %s), run lil auth login", b.account, b.credentials.Name())
+	}
+	if err != nil {
+		return err
+	}
+
+	var viewer linear.Viewer
+	if b.oauthLogin != nil {
+		fmt.Fprintf(c.stdout, "Workspace:  %s\nKey source: %s (OAuth token)\n", b.account, store.Name())
+		viewer, err = b.viewer(ctx)
+	} else {
+		fmt.Fprintf(c.stdout, "Workspace:  %s\nKey source: %s\n", b.account, store.Name())
+		viewer, err = b.checkKey(ctx, key)
+	}
+	if err != nil {
+		return fmt.Errorf("linear rejected the credentials: %w", err)
+	}
+	fmt.Fprintf(c.stdout, "Signed in:  %s <%s>\n", viewer.Name, viewer.Email)
+	return nil
+}
+
+// storeAPIKey saves key in store, or in the first writable store of a chain,
+// and returns the store it went to.
+func storeAPIKey(store credentials.Store, account, key string) (credentials.Store, error) {
+	if chain, ok := store.(credentials.Chain); ok {
+		return chain.Save(account, key)
+	}
+	return store, store.Set(account, key)
+}
+
+// locate returns the stored secret of the workspace and the store holding it.
+func locate(b *backend) (credentials.Store, string, error) {
+	if chain, ok := b.credentials.(credentials.Chain); ok {
+		return chain.Locate(b.account)
+	}
+	secret, err := b.credentials.Get(b.account)
+	return b.credentials, secret, err
+}
+
+func readOnlyError(b *backend) error {
+	return fmt.Errorf("lil can't change the API key of %s in the %s; set its credentials source to auto, system or encrypted_file", b.account, b.credentials.Name())
+}
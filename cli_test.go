@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pzurek/lil/internal/cache"
+	"github.com/pzurek/lil/internal/config"
+	"github.com/pzurek/lil/internal/credentials"
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/menu"
+)
+
+func cliTestIssues() []linear.Issue {
+	issues := []linear.Issue{
+		{Identifier: "ENG-3", Title: "Loose end", Url: "https://linear.app/acme/issue/ENG-3", CreatedAt: "2024-01-01T00:00:00Z"},
+		{Identifier: "ENG-1", Title: "Launch", Url: "https://linear.app/acme/issue/ENG-1", DueDate: "2024-03-01"},
+		{Identifier: "ENG-2", Title: "Prepare", Url: "https://linear.app/acme/issue/ENG-2", DueDate: "2024-02-01"},
+	}
+	issues[1].Project.Id = "p1"
+	issues[1].Project.Name = "Rocket"
+	issues[2].Project.Id = "p1"
+	issues[2].Project.Name = "Rocket"
+	return issues
+}
+
+// newTestCLI returns a cli backed by fakes, and its captured output.
+func newTestCLI(fetchErr error, cached []linear.Issue) (*cli, *bytes.Buffer, *bytes.Buffer, *[]string) {
+	c, stdout, stderr, opened, _ := newTestCLIWithCreate(fetchErr, cached)
+	return c, stdout, stderr, opened
+}
+
+// newTestCLIWithCreate is newTestCLI that also returns the created issues.
+func newTestCLIWithCreate(fetchErr error, cached []linear.Issue) (*cli, *bytes.Buffer, *bytes.Buffer, *[]string, *[]linear.IssueInput) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opened := &[]string{}
+	created := &[]linear.IssueInput{}
+	acme := &backend{
+		fetch: func(ctx context.Context, filter linear.IssueFilter) ([]linear.Issue, error) {
+			if fetchErr != nil {
+				return nil, fetchErr
+			}
+			return cliTestIssues(), nil
+		},
+		fetchTeams: func(ctx context.Context) ([]linear.Team, error) {
+			return []linear.Team{{Id: "team-eng", Key: "ENG", Name: "Engineering"}}, nil
+		},
+		findProject: func(ctx context.Context, name string) (linear.Project, error) {
+			if !strings.EqualFold(name, "Rocket") {
+				return linear.Project{}, errors.New("no project named " + name)
+			}
+			return linear.Project{Id: "p1", Name: "Rocket"}, nil
+		},
+		createIssue: func(ctx context.Context, input linear.IssueInput) (linear.CreatedIssue, error) {
+			*created = append(*created, input)
+			return linear.CreatedIssue{Id: "new", Identifier: "ENG-4", Title: input.Title, Url: "https://linear.app/acme/issue/ENG-4"}, nil
+		},
+		loadCache: func() (cache.Entry, error) {
+			if cached == nil {
+				return cache.Entry{}, cache.ErrNotCached
+			}
+			return cache.Entry{Workspace: "acme", Issues: cached}, nil
+		},
+		saveCache: func([]linear.Issue) error { return nil },
+	}
+	globex := &backend{
+		fetch: func(ctx context.Context, filter linear.IssueFilter) ([]linear.Issue, error) {
+			return []linear.Issue{{Identifier: "GLX-1", Title: "Globex issue", Url: "https://linear.app/globex/issue/GLX-1"}}, nil
+		},
+		saveCache: func([]linear.Issue) error { return nil },
+	}
+	c := &cli{
+		stdout: stdout,
+		stderr: stderr,
+		config: config.Default(),
+		backend: func(name string) (*backend, error) {
+			switch name {
+			case "", "acme":
+				return acme, nil
+			case "globex":
+				return globex, nil
+			}
+			return nil, errors.New("no workspace named " + name)
+		},
+		open: func(url string) { *opened = append(*opened, url) },
+	}
+	return c, stdout, stderr, opened, created
+}
+
+func TestCLIList(t *testing.T) {
+	c, stdout, _, _ := newTestCLI(nil, nil)
+	if code := c.run(context.Background(), []string{"list"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	expected := "Rocket\n" +
+		"  ENG-2  Prepare  due Feb 1, 2024\n" +
+		"  ENG-1  Launch   due Mar 1, 2024\n" +
+		"\n" +
+		"  ENG-3  Loose end\n"
+	if stdout.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nExpected:\n%s", stdout.String(), expected)
+	}
+}
+
+func TestCLIListUsesConfig(t *testing.T) {
+	c, stdout, _, _ := newTestCLI(nil, nil)
+	c.config.Grouping = menu.Flat
+	c.config.Filters.Projects = []string{"Rocket"}
+	if code := c.run(context.Background(), []string{"list"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	expected := "  ENG-2  Prepare  due Feb 1, 2024\n" +
+		"  ENG-1  Launch   due Mar 1, 2024\n"
+	if stdout.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nExpected:\n%s", stdout.String(), expected)
+	}
+}
+
+func TestCLIListFilter(t *testing.T) {
+	c, stdout, _, _ := newTestCLI(nil, nil)
+	b, _ := c.backend("")
+	var filters []linear.IssueFilter
+	fetch := b.fetch
+	b.fetch = func(ctx context.Context, filter linear.IssueFilter) ([]linear.Issue, error) {
+		filters = append(filters, filter)
+		return fetch(ctx, filter)
+	}
+	var saved int
+	b.saveCache = func([]linear.Issue) error { saved++; return nil }
+
+	c.config.Filters.Teams = []string{"OPS"}
+	if code := c.run(context.Background(), []string{"list", "--filter", "projects=rocket", "--filter", "due_within=30"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	expected := "Rocket\n" +
+		"  ENG-2  Prepare  due Feb 1, 2024\n" +
+		"  ENG-1  Launch   due Mar 1, 2024\n"
+	if stdout.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nExpected:\n%s", stdout.String(), expected)
+	}
+	want := linear.IssueFilter{Projects: []string{"rocket"}, DueWithinDays: 30}
+	if len(filters) != 1 || fmt.Sprintf("%+v", filters[0]) != fmt.Sprintf("%+v", want) {
+		t.Errorf("Expected the --filter terms to replace the config's filters, got %+v", filters)
+	}
+	if saved != 0 {
+		t.Error("Expected issues fetched with --filter not to be cached")
+	}
+
+	c, _, stderr, _ := newTestCLI(nil, nil)
+	if code := c.run(context.Background(), []string{"list", "--filter", "cycle=soon"}); code != 1 || !strings.Contains(stderr.String(), "cycle: must be current") {
+		t.Errorf("Expected an invalid filter to be refused, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestCLIListJSON(t *testing.T) {
+	c, stdout, _, _ := newTestCLI(nil, nil)
+	if code := c.run(context.Background(), []string{"list", "--json"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	var issues []linear.Issue
+	if err := json.Unmarshal(stdout.Bytes(), &issues); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	order := []string{}
+	for _, issue := range issues {
+		order = append(order, issue.Identifier)
+	}
+	if got := strings.Join(order, ","); got != "ENG-2,ENG-1,ENG-3" {
+		t.Errorf("Expected menu ordering ENG-2,ENG-1,ENG-3, got %s", got)
+	}
+}
+
+func TestCLIListFallsBackToCache(t *testing.T) {
+	cached := []linear.Issue{{Identifier: "OLD-1", Title: "Cached"}}
+	c, stdout, stderr, _ := newTestCLI(errors.New("offline"), cached)
+	if code := c.run(context.Background(), []string{"list"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "OLD-1") {
+		t.Errorf("Expected cached issue in output, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "offline") {
+		t.Errorf("Expected a warning about the failed fetch, got %q", stderr.String())
+	}
+
+	c, _, stderr, _ = newTestCLI(errors.New("offline"), nil)
+	if code := c.run(context.Background(), []string{"list"}); code != 1 {
+		t.Errorf("Expected exit code 1 without a cache, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "offline") {
+		t.Errorf("Expected the fetch error, got %q", stderr.String())
+	}
+
+	// --filter applies every filter to cached issues, and refuses the cache
+	// for cycle filters only Linear can check
+	cached = append(cached, linear.Issue{Identifier: "OLD-2", Title: "Labelled", DueDate: "2024-01-01"})
+	cached[1].Labels.Nodes = []linear.Label{{Name: "Bug"}}
+	c, stdout, _, _ = newTestCLI(errors.New("offline"), cached)
+	if code := c.run(context.Background(), []string{"list", "--filter", "labels=bug"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if strings.Contains(stdout.String(), "OLD-1") || !strings.Contains(stdout.String(), "OLD-2") {
+		t.Errorf("Expected only the labelled cached issue, got %q", stdout.String())
+	}
+	c, _, stderr, _ = newTestCLI(errors.New("offline"), cached)
+	if code := c.run(context.Background(), []string{"list", "--filter", "cycle=current"}); code != 1 || !strings.Contains(stderr.String(), "offline") {
+		t.Errorf("Expected the fetch error for a cycle filter, got %d: %q", code, stderr.String())
+	}
+	c, _, stderr, _ = newTestCLI(nil, cached)
+	if code := c.run(context.Background(), []string{"list", "--cached", "--filter", "cycle=current"}); code != 1 || !strings.Contains(stderr.String(), "cycle=current") {
+		t.Errorf("Expected cached issues not to be filtered by cycle, got %d: %q", code, stderr.String())
+	}
+}
+
+func TestCLIOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		opened string
+	}{
+		{name: "Assigned issue", args: []string{"open", "ENG-1"}, opened: "https://linear.app/acme/issue/ENG-1"},
+		{name: "Lowercase identifier", args: []string{"open", "eng-2"}, opened: "https://linear.app/acme/issue/ENG-2"},
+		{name: "Unassigned issue", args: []string{"open", "OPS-9"}, opened: "https://linear.app/acme/issue/OPS-9"},
+		{name: "Other workspace", args: []string{"open", "--workspace", "globex", "GLX-1"}, opened: "https://linear.app/globex/issue/GLX-1"},
+		{name: "Unknown workspace", args: []string{"open", "--workspace", "initech", "ENG-1"}, code: 1},
+		{name: "Missing identifier", args: []string{"open"}, code: 1},
+		{name: "Unknown command", args: []string{"frobnicate"}, code: 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _, _, opened := newTestCLI(nil, nil)
+			if code := c.run(context.Background(), tc.args); code != tc.code {
+				t.Fatalf("Expected exit code %d, got %d", tc.code, code)
+			}
+			if tc.opened == "" {
+				if len(*opened) != 0 {
+					t.Errorf("Expected nothing to be opened, got %v", *opened)
+				}
+				return
+			}
+			if len(*opened) != 1 || (*opened)[0] != tc.opened {
+				t.Errorf("Expected %s to be opened, got %v", tc.opened, *opened)
+			}
+		})
+	}
+}
+
+func TestCLICreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		expect linear.IssueInput
+	}{
+		{
+			name:   "Team key",
+			args:   []string{"create", "--team", "ENG", "Write", "docs"},
+			expect: linear.IssueInput{TeamID: "team-eng", Title: "Write docs"},
+		},
+		{
+			name:   "Team name and project",
+			args:   []string{"create", "--team", "engineering", "--project", "rocket", "--description", "Details", "Write docs"},
+			expect: linear.IssueInput{TeamID: "team-eng", Title: "Write docs", Description: "Details", ProjectID: "p1"},
+		},
+		{name: "Unknown team", args: []string{"create", "--team", "OPS", "Write docs"}, code: 1},
+		{name: "Unknown project", args: []string{"create", "--team", "ENG", "--project", "Moon", "Write docs"}, code: 1},
+		{name: "Missing team", args: []string{"create", "Write docs"}, code: 1},
+		{name: "Missing title", args: []string{"create", "--team", "ENG"}, code: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, stdout, _, _, created := newTestCLIWithCreate(nil, nil)
+			if code := c.run(context.Background(), tc.args); code != tc.code {
+				t.Fatalf("Expected exit code %d, got %d", tc.code, code)
+			}
+			if tc.code != 0 {
+				if len(*created) != 0 {
+					t.Errorf("Expected nothing to be created, got %v", *created)
+				}
+				return
+			}
+			if len(*created) != 1 || (*created)[0] != tc.expect {
+				t.Errorf("Expected %+v to be created, got %+v", tc.expect, *created)
+			}
+			if expected := "Created ENG-4: Write docs\nhttps://linear.app/acme/issue/ENG-4\n"; stdout.String() != expected {
+				t.Errorf("Expected output %q, got %q", expected, stdout.String())
+			}
+		})
+	}
+}
+
+// memoryCredentials is a writable credentials.Store backed by a map.
+type memoryCredentials map[string]string
+
+func (m memoryCredentials) Name() string { return "memory" }
+
+func (m memoryCredentials) Get(account string) (string, error) {
+	key, ok := m[account]
+	if !ok {
+		return "", credentials.ErrNotFound
+	}
+	return key, nil
+}
+
+func (m memoryCredentials) Set(account, key string) error {
+	m[account] = key
+	return nil
+}
+
+func (m memoryCredentials) Delete(account string) error {
+	if _, ok := m[account]; !ok {
+		return credentials.ErrNotFound
+	}
+	delete(m, account)
+	return nil
+}
+
+func TestCLIAuth(t *testing.T) {
+	t.Setenv("LIL_TEST_KEY", "")
+	stored := memoryCredentials{}
+	c, stdout, stderr, _ := newTestCLI(nil, nil)
+	b, _ := c.backend("acme")
+	b.account = "acme"
+	b.credentials = credentials.Chain{credentials.Env{Var: "LIL_TEST_KEY"}, stored}
+	b.checkKey = func(ctx context.Context, key string) (linear.Viewer, error) {
+		if key != "lin_api_good" && key != "lin_api_env" {
+			return linear.Viewer{}, errors.New("authentication failed")
+		}
+		return linear.Viewer{Id: "u1", Name: "Ada", Email: "ada@acme.test"}, nil
+	}
+	run := func(stdin string, args ...string) int {
+		t.Helper()
+		stdout.Reset()
+		stderr.Reset()
+		c.stdin = strings.NewReader(stdin)
+		return c.run(context.Background(), args)
+	}
+
+	if code := run("", "auth", "status"); code != 1 || !strings.Contains(stderr.String(), "not signed in to acme") {
+		t.Errorf("Expected status to report a missing key, got %d: %q", code, stderr.String())
+	}
+
+	if code := run("lin_api_bad\n", "auth", "login"); code != 1 || len(stored) != 0 {
+		t.Errorf("Expected a rejected key not to be stored, got %d: %v", code, stored)
+	}
+
+	if code := run("  lin_api_good\n", "auth", "login"); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if stored["acme"] != "lin_api_good" {
+		t.Errorf("Expected the key to be stored, got %v", stored)
+	}
+	if expected := "Signed in to acme as Ada <ada@acme.test>, key stored in memory\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	if code := run("", "auth", "status"); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := "Workspace:  acme\nKey source: memory\nSigned in:  Ada <ada@acme.test>\n"
+	if stdout.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nExpected:\n%s", stdout.String(), expected)
+	}
+
+	t.Setenv("LIL_TEST_KEY", "lin_api_env")
+	if code := run("", "auth", "status"); code != 0 || !strings.Contains(stdout.String(), "Key source: LIL_TEST_KEY environment variable") {
+		t.Errorf("Expected the environment variable to take precedence, got %d: %q", code, stdout.String())
+	}
+	t.Setenv("LIL_TEST_KEY", "")
+
+	if code := run("", "auth", "logout"); code != 0 || len(stored) != 0 {
+		t.Errorf("Expected the key to be removed, got %d: %v", code, stored)
+	}
+	if code := run("", "auth", "logout"); code != 0 || !strings.Contains(stdout.String(), "No API key of acme is stored") {
+		t.Errorf("Expected logout without a key to succeed, got %d: %q", code, stdout.String())
+	}
+
+	b.credentials = credentials.Env{Var: "LIL_TEST_KEY"}
+	if code := run("lin_api_good\n", "auth", "login"); code != 1 || !strings.Contains(stderr.String(), "can't change the API key") {
+		t.Errorf("Expected login to a read-only source to fail, got %d: %q", code, stderr.String())
+	}
+	if code := run("", "auth", "frobnicate"); code != 1 {
+		t.Errorf("Expected exit code 1 for an unknown auth command, got %d", code)
+	}
+}
+
+func TestCLIAuthOAuth(t *testing.T) {
+	stored := memoryCredentials{}
+	c, stdout, stderr, opened := newTestCLI(nil, nil)
+	b, _ := c.backend("acme")
+	b.account = "acme"
+	b.credentials = stored
+	b.oauthLogin = func(ctx context.Context, open func(url string)) error {
+		open("https://linear.app/oauth/authorize?client_id=lil")
+		stored["acme"] = `{"access_token":"token"}`
+		return nil
+	}
+	b.viewer = func(ctx context.Context) (linear.Viewer, error) {
+		return linear.Viewer{Id: "u1", Name: "Ada", Email: "ada@acme.test"}, nil
+	}
+
+	if code := c.run(context.Background(), []string{"auth", "login"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if len(*opened) != 1 || !strings.Contains(stderr.String(), (*opened)[0]) {
+		t.Errorf("Expected the authorization URL to be opened and printed, got %v and %q", *opened, stderr.String())
+	}
+	if expected := "Signed in to acme as Ada <ada@acme.test>, token stored in memory\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := c.run(context.Background(), []string{"auth", "status"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := "Workspace:  acme\nKey source: memory (OAuth token)\nSigned in:  Ada <ada@acme.test>\n"
+	if stdout.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nExpected:\n%s", stdout.String(), expected)
+	}
+}
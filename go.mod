@@ -6,7 +6,10 @@ tool github.com/Khan/genqlient
 
 require (
 	github.com/Khan/genqlient v0.8.0
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/progrium/darwinkit v0.5.1-0.20240715194340-61b9e31a12fa
+	github.com/vektah/gqlparser/v2 v2.5.19
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -15,9 +18,7 @@ require (
 	github.com/alexflint/go-scalar v1.0.0 // indirect
 	github.com/bmatcuk/doublestar/v4 v4.6.1 // indirect
 	github.com/google/uuid v1.6.0 // indirect
-	github.com/vektah/gqlparser/v2 v2.5.19 // indirect
 	golang.org/x/mod v0.20.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/tools v0.24.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -0,0 +1,220 @@
+// Package cache persists the last fetched issues and notifications so the
+// menu has something to show before the first fetch completes.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// FormatVersion is bumped whenever Entry or the Linear types it holds
+// change. Entries written with an older version are upgraded by migrations
+// where possible; other versions are discarded.
+const FormatVersion = 6
+
+// migrations upgrade decoded entries from the format version they are keyed
+// by to the next one.
+var migrations = map[int]func(*Entry){
+	// Version 5 added issue estimate, priority label, label color, team name
+	// and state name and color. They stay empty until the next fetch, which
+	// the menu copes with.
+	4: func(*Entry) {},
+	// Version 6 added the state position. It is zero until the next fetch,
+	// which only affects the order of state groups.
+	5: func(*Entry) {},
+}
+
+// ErrNotCached is returned by Load when there is no usable entry, either
+// because none was written or because it was incompatible.
+var ErrNotCached = errors.New("no cached data")
+
+// Entry is the cached state of one workspace.
+type Entry struct {
+	Version   int            `json:"version"`
+	Workspace string         `json:"workspace"`
+	FetchedAt time.Time      `json:"fetchedAt"`
+	Issues    []linear.Issue `json:"issues"`
+	Inbox     *linear.Inbox  `json:"inbox,omitempty"`
+}
+
+// Store reads and writes cache entries, one file per workspace. Writes to the
+// same workspace are serialized, so SaveIssues never undoes a concurrent
+// Save.
+type Store struct {
+	dir string
+	now func() time.Time
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// New returns a store that keeps its files in dir.
+func New(dir string) *Store {
+	return &Store{dir: dir, now: time.Now, locks: make(map[string]*sync.Mutex)}
+}
+
+// lock takes the write lock of workspace and returns its unlock.
+func (s *Store) lock(workspace string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[workspace]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[workspace] = l
+	}
+	s.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// DefaultDir returns the per-user cache directory for lil, e.g.
+// ~/.cache/lil on Linux or ~/Library/Caches/lil on macOS.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "lil"), nil
+}
+
+// Path returns the file the entry for workspace is stored in.
+func (s *Store) Path(workspace string) string {
+	return filepath.Join(s.dir, "issues-"+fileSafe(workspace)+".json")
+}
+
+// Load returns the cached entry for workspace. Entries from another format
+// version or that fail to decode are removed and reported as ErrNotCached.
+// An entry for another workspace is left alone, but reported as
+// ErrNotCached too.
+func (s *Store) Load(workspace string) (Entry, error) {
+	path := s.Path(workspace)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Entry{}, ErrNotCached
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	var entry Entry
+	reason := ""
+	if err := json.Unmarshal(data, &entry); err != nil {
+		reason = err.Error()
+	} else if migrate(&entry); entry.Version != FormatVersion {
+		reason = fmt.Sprintf("format version %d, expected %d", entry.Version, FormatVersion)
+	} else if entry.Workspace != workspace {
+		log.Printf("Ignoring cache %s: entry is for workspace %q", path, entry.Workspace)
+		return Entry{}, ErrNotCached
+	}
+	if reason != "" {
+		log.Printf("Discarding incompatible cache %s: %s", path, reason)
+		if err := os.Remove(path); err != nil {
+			log.Printf("Warning: Failed to remove %s: %v", path, err)
+		}
+		return Entry{}, ErrNotCached
+	}
+	return entry, nil
+}
+
+// migrate upgrades entry to FormatVersion, as far as migrations allow.
+func migrate(entry *Entry) {
+	for entry.Version < FormatVersion {
+		upgrade, ok := migrations[entry.Version]
+		if !ok {
+			return
+		}
+		upgrade(entry)
+		entry.Version++
+	}
+}
+
+// Save replaces the entry for entry.Workspace. The file is written to a
+// temporary file and renamed into place, so readers never see a partial
+// entry, and is only readable by the current user.
+func (s *Store) Save(entry Entry) error {
+	defer s.lock(entry.Workspace)()
+	return s.save(entry)
+}
+
+func (s *Store) save(entry Entry) error {
+	entry.Version = FormatVersion
+	if entry.FetchedAt.IsZero() {
+		entry.FetchedAt = s.now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".issues-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set cache permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.Path(entry.Workspace)); err != nil {
+		return fmt.Errorf("failed to replace cache: %w", err)
+	}
+	return nil
+}
+
+// SaveIssues replaces the cached issues of workspace, keeping any cached
+// inbox. fetchedAt is when the issues were fetched; a zero time keeps the
+// cached entry's, for issues changed without a fetch, e.g. by a webhook.
+func (s *Store) SaveIssues(workspace string, issues []linear.Issue, fetchedAt time.Time) error {
+	defer s.lock(workspace)()
+	entry, err := s.Load(workspace)
+	if err != nil && !errors.Is(err, ErrNotCached) {
+		return err
+	}
+	if fetchedAt.IsZero() {
+		fetchedAt = entry.FetchedAt
+	}
+	return s.save(Entry{Workspace: workspace, FetchedAt: fetchedAt, Issues: issues, Inbox: entry.Inbox})
+}
+
+// fileSafe maps a workspace name onto characters that are safe in a file
+// name on every platform. The readable part loses characters and case, so a
+// hash of the exact name keeps names apart, on case-insensitive file systems
+// too.
+func fileSafe(name string) string {
+	readable := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, name)
+	sum := sha256.Sum256([]byte(name))
+	return readable + "-" + hex.EncodeToString(sum[:6])
+}
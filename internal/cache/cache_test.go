@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := New(filepath.Join(t.TempDir(), "lil"))
+	s.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.Load("acme"); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("Expected ErrNotCached before saving, got %v", err)
+	}
+
+	inbox := &linear.Inbox{UnreadCount: 1}
+	err := s.Save(Entry{Workspace: "acme", Issues: []linear.Issue{{Identifier: "ENG-1"}}, Inbox: inbox})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	entry, err := s.Load("acme")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if entry.Version != FormatVersion || entry.Workspace != "acme" {
+		t.Errorf("Unexpected entry metadata %+v", entry)
+	}
+	if !entry.FetchedAt.Equal(s.now()) {
+		t.Errorf("Expected fetch time %v, got %v", s.now(), entry.FetchedAt)
+	}
+	if len(entry.Issues) != 1 || entry.Issues[0].Identifier != "ENG-1" || entry.Inbox.UnreadCount != 1 {
+		t.Errorf("Unexpected entry contents %+v", entry)
+	}
+
+	info, err := os.Stat(s.Path("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected cache file mode 0600, got %o", perm)
+	}
+	if _, err := s.Load("other"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Expected workspaces to be cached separately, got %v", err)
+	}
+
+	// Only the cache file is left behind
+	files, _ := os.ReadDir(s.dir)
+	if len(files) != 1 {
+		t.Errorf("Expected a single file in the cache directory, got %d", len(files))
+	}
+}
+
+func TestSaveIssuesKeepsInbox(t *testing.T) {
+	s := newTestStore(t)
+	fetchedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.Save(Entry{Workspace: "acme", FetchedAt: fetchedAt, Inbox: &linear.Inbox{UnreadCount: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveIssues("acme", []linear.Issue{{Identifier: "ENG-2"}}, time.Time{}); err != nil {
+		t.Fatalf("SaveIssues failed: %v", err)
+	}
+
+	entry, err := s.Load("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Issues) != 1 || entry.Inbox == nil || entry.Inbox.UnreadCount != 3 {
+		t.Errorf("Expected issues to be replaced and the inbox kept, got %+v", entry)
+	}
+	if !entry.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("Expected the fetch time %s to be kept, got %s", fetchedAt, entry.FetchedAt)
+	}
+
+	refetchedAt := fetchedAt.Add(time.Hour)
+	if err := s.SaveIssues("acme", nil, refetchedAt); err != nil {
+		t.Fatalf("SaveIssues failed: %v", err)
+	}
+	if entry, err = s.Load("acme"); err != nil || !entry.FetchedAt.Equal(refetchedAt) {
+		t.Errorf("Expected the fetch time %s, got %s (%v)", refetchedAt, entry.FetchedAt, err)
+	}
+}
+
+func TestConcurrentSaves(t *testing.T) {
+	s := newTestStore(t)
+	inbox := &linear.Inbox{UnreadCount: 3}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := s.Save(Entry{Workspace: "acme", Issues: []linear.Issue{{Identifier: fmt.Sprintf("ENG-%d", i)}}, Inbox: inbox}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := s.SaveIssues("acme", []linear.Issue{{Identifier: "ENG-0"}}, time.Time{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entry, err := s.Load("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Inbox == nil || entry.Inbox.UnreadCount != 3 {
+		t.Errorf("Expected every save to keep the inbox, got %+v", entry)
+	}
+}
+
+func TestLoadDiscardsIncompatibleEntries(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Legacy issue list", data: `[{"id":"1","identifier":"ENG-1"}]`},
+		{name: "Other format version", data: `{"version":999,"workspace":"acme","issues":[]}`},
+		{name: "Version without migration", data: `{"version":1,"workspace":"acme","issues":[]}`},
+		{name: "Truncated", data: `{"version":1,"works`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestStore(t)
+			if err := os.MkdirAll(s.dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(s.Path("acme"), []byte(tc.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.Load("acme"); !errors.Is(err, ErrNotCached) {
+				t.Errorf("Expected ErrNotCached, got %v", err)
+			}
+			if _, err := os.Stat(s.Path("acme")); !os.IsNotExist(err) {
+				t.Errorf("Expected the incompatible file to be removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadMigratesEntries(t *testing.T) {
+	s := newTestStore(t)
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"version":4,"workspace":"acme","fetchedAt":"2024-03-01T12:00:00Z","issues":[{"id":"1","identifier":"ENG-1","state":{"id":"s1","type":"started"}}]}`
+	if err := os.WriteFile(s.Path("acme"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := s.Load("acme")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Version != FormatVersion || len(entry.Issues) != 1 || entry.Issues[0].State.Type != "started" {
+		t.Errorf("Expected the entry to be upgraded with its issues, got %+v", entry)
+	}
+}
+
+func TestLoadKeepsOtherWorkspaceEntries(t *testing.T) {
+	s := newTestStore(t)
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"version":6,"workspace":"globex","issues":[]}`
+	if err := os.WriteFile(s.Path("acme"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Load("acme"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Expected ErrNotCached, got %v", err)
+	}
+	if _, err := os.Stat(s.Path("acme")); err != nil {
+		t.Errorf("Expected the other workspace's file to be kept, got %v", err)
+	}
+}
+
+func TestPathIsFileSafe(t *testing.T) {
+	s := New("/cache")
+	got := s.Path("../Acme Corp")
+	if dir, file := filepath.Split(got); dir != "/cache/" || !strings.HasPrefix(file, "issues-___acme_corp-") || !strings.HasSuffix(file, ".json") {
+		t.Errorf("Unexpected path %s", got)
+	}
+
+	// Names that only differ in characters or case that don't survive in
+	// file names still get files of their own
+	paths := map[string]string{}
+	for _, name := range []string{"Acme Inc", "Acme_Inc", "acme inc", "ACME INC"} {
+		path := strings.ToLower(s.Path(name))
+		if other, ok := paths[path]; ok {
+			t.Errorf("Expected %q and %q to be stored apart, both got %s", name, other, path)
+		}
+		paths[path] = name
+	}
+}
@@ -0,0 +1,603 @@
+// Package config loads lil's YAML configuration file and watches it for
+// changes.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/menu"
+)
+
+// MinRefreshInterval keeps a typo in the config from hammering the Linear API.
+const MinRefreshInterval = 30 * time.Second
+
+// SortDefault is the Sorting key for groupings without a strategy of their
+// own.
+const SortDefault = "default"
+
+// groupings are the valid grouping modes, in the order the menu offers them.
+var groupings = func() []string {
+	var modes []string
+	for _, grouping := range menu.Groupings {
+		modes = append(modes, grouping.Mode)
+	}
+	return modes
+}()
+
+// DefaultWorkspace names the workspace used when none are configured.
+const DefaultWorkspace = "default"
+
+// Credential sources.
+const (
+	// CredentialsAuto uses the environment variable if it is set, then the
+	// system store, then the encrypted file.
+	CredentialsAuto      = "auto"
+	CredentialsEnv       = "env"
+	CredentialsFile      = "file"
+	CredentialsSystem    = "system"
+	CredentialsEncrypted = "encrypted_file"
+)
+
+// Config is the contents of the config file. Fields left out of the file keep
+// the values from Default.
+type Config struct {
+	// RefreshInterval is the delay between background refreshes.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Sync            Sync          `yaml:"sync"`
+	Filters         Filters       `yaml:"filters"`
+	// Grouping is how issues are grouped in the menu, one of the grouping
+	// modes.
+	Grouping string `yaml:"grouping"`
+	// Sorting picks the sort strategy of the issues within each group, by
+	// grouping mode. Groupings left out use the SortDefault entry, or due
+	// date.
+	Sorting       map[string]string `yaml:"sorting"`
+	Display       Display           `yaml:"display"`
+	Notifications Notifications     `yaml:"notifications"`
+	Webhook       Webhook           `yaml:"webhook"`
+	Credentials   Credentials       `yaml:"credentials"`
+	// Workspaces lists the Linear organizations to show issues from. When
+	// empty, a single workspace using Credentials is shown.
+	Workspaces []Workspace `yaml:"workspaces"`
+}
+
+// Workspace is a Linear organization with its own credentials.
+type Workspace struct {
+	// Name identifies the workspace in the menu, the cache and the CLI's
+	// --workspace flag.
+	Name        string      `yaml:"name"`
+	Credentials Credentials `yaml:"credentials"`
+	// WebhookSecret is the signing secret of the workspace's webhook, if it
+	// differs from Webhook.Secret.
+	WebhookSecret string `yaml:"webhook_secret"`
+}
+
+// Sync controls how refreshes fetch issues.
+type Sync struct {
+	// Incremental fetches only the issues updated since the last refresh.
+	Incremental bool `yaml:"incremental"`
+	// FullInterval is how often all issues are fetched anyway, which is the
+	// only way to notice issues that were unassigned or deleted.
+	FullInterval time.Duration `yaml:"full_interval"`
+}
+
+// Filters narrow down which assigned issues are fetched and shown. Empty
+// lists match everything.
+type Filters struct {
+	// Teams are team keys, e.g. ENG.
+	Teams []string `yaml:"teams"`
+	// Projects are project names.
+	Projects []string `yaml:"projects"`
+	// Labels are label names; issues with any of them match.
+	Labels []string `yaml:"labels"`
+	// Priorities run from 1 (urgent) to 4 (low), with 0 meaning none.
+	Priorities []int `yaml:"priorities"`
+	// Cycle is "current", "next", "previous" or "none". Empty matches any.
+	Cycle string `yaml:"cycle"`
+	// States are the workflow state types to show, e.g. started.
+	States []string `yaml:"states"`
+	// ExcludeStates are workflow state types to hide, e.g. backlog or triage.
+	ExcludeStates []string `yaml:"exclude_states"`
+	// DueWithin is a number of days. When set, only issues due within that
+	// many days, or overdue, match.
+	DueWithin int `yaml:"due_within"`
+}
+
+// Display controls optional parts of the menu.
+type Display struct {
+	Tooltips bool `yaml:"tooltips"`
+	Inbox    bool `yaml:"inbox"`
+	// InboxLimit is the number of notifications listed in the Inbox section.
+	InboxLimit int `yaml:"inbox_limit"`
+	// HighlightDue colors the titles of overdue issues red and those of
+	// issues due today orange.
+	HighlightDue bool `yaml:"highlight_due"`
+	// DueSoon is how many days after today issues count as due soon.
+	DueSoon int `yaml:"due_soon"`
+	// Badge shows a count next to the tray icon: of overdue issues with
+	// "overdue", of those also due today with "today" and of those also due
+	// soon with "soon". "none" turns it off.
+	Badge string `yaml:"badge"`
+}
+
+// Notifications choose which changes to assigned issues show a desktop
+// notification.
+type Notifications struct {
+	Enabled bool `yaml:"enabled"`
+	// Assigned notifies about newly assigned issues.
+	Assigned bool `yaml:"assigned"`
+	// DueDate notifies about due dates that were set, moved or removed.
+	DueDate bool `yaml:"due_date"`
+	// State notifies about issues moved to another workflow state by
+	// someone else.
+	State bool `yaml:"state"`
+	// Priority notifies about issues whose priority was raised.
+	Priority bool `yaml:"priority"`
+}
+
+// Webhook configures the receiver for Linear webhook deliveries, which
+// applies issue changes as they happen instead of at the next refresh.
+type Webhook struct {
+	// Listen is the address deliveries are accepted on, e.g.
+	// 127.0.0.1:8470. Empty turns the receiver off.
+	Listen string `yaml:"listen"`
+	// Secret is the signing secret of the webhook, for workspaces without
+	// a webhook_secret of their own.
+	Secret string `yaml:"secret"`
+}
+
+// Credentials says where the Linear API key is read from.
+type Credentials struct {
+	// Source is "auto", "env", "file", "system" (the macOS Keychain or the
+	// Secret Service) or "encrypted_file".
+	Source string `yaml:"source"`
+	// Env is the environment variable read by the env and auto sources.
+	Env string `yaml:"env"`
+	// File is the path read by the file source, or the encrypted file used
+	// by the encrypted_file source. A leading ~ is expanded.
+	File string `yaml:"file"`
+	// OAuth signs in through a Linear OAuth application instead of with a
+	// personal API key. Its tokens are kept in the credential source.
+	OAuth OAuth `yaml:"oauth"`
+}
+
+// OAuth identifies a Linear OAuth application.
+type OAuth struct {
+	ClientID string `yaml:"client_id"`
+	// ClientSecret is only needed by applications that require one on top
+	// of PKCE.
+	ClientSecret string `yaml:"client_secret"`
+	// Scope is the comma separated scopes to request. Defaults to read,write.
+	Scope string `yaml:"scope"`
+	// RedirectPort is the loopback port the browser is redirected to after
+	// authorizing. Zero picks a free port, which works unless the application
+	// only accepts an exact callback URL.
+	RedirectPort int `yaml:"redirect_port"`
+}
+
+// Enabled reports whether OAuth is configured.
+func (o OAuth) Enabled() bool {
+	return o.ClientID != ""
+}
+
+// stateTypes are the workflow state types States and ExcludeStates may name.
+var stateTypes = map[string]bool{
+	"triage":    true,
+	"backlog":   true,
+	"unstarted": true,
+	"started":   true,
+	"completed": true,
+	"canceled":  true,
+}
+
+// Default returns the configuration used when there is no config file.
+func Default() *Config {
+	return &Config{
+		RefreshInterval: 5 * time.Minute,
+		Sync: Sync{
+			Incremental:  true,
+			FullInterval: time.Hour,
+		},
+		Grouping: menu.ByProject,
+		Display: Display{
+			Tooltips:     true,
+			Inbox:        true,
+			InboxLimit:   10,
+			HighlightDue: true,
+			DueSoon:      7,
+			Badge:        menu.BadgeNone,
+		},
+		Notifications: Notifications{
+			Enabled:  true,
+			Assigned: true,
+			DueDate:  true,
+			State:    true,
+			Priority: true,
+		},
+		Credentials: Credentials{
+			Source: CredentialsAuto,
+			Env:    "LINEAR_API_KEY",
+		},
+	}
+}
+
+// DefaultPath returns the config file location, e.g. ~/.config/lil/config.yaml
+// on Linux or ~/Library/Application Support/lil/config.yaml on macOS.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "lil", "config.yaml"), nil
+}
+
+// Load reads and validates the config file at path. A missing file yields
+// the defaults.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a config file. Unknown keys are errors so that
+// typos don't go unnoticed.
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting, naming each by its key in the file.
+func (c *Config) Validate() error {
+	var problems []string
+	if c.RefreshInterval < MinRefreshInterval {
+		problems = append(problems, fmt.Sprintf("refresh_interval: must be at least %s, got %s", MinRefreshInterval, c.RefreshInterval))
+	}
+	if c.Sync.Incremental && c.Sync.FullInterval < MinRefreshInterval {
+		problems = append(problems, fmt.Sprintf("sync.full_interval: must be at least %s, got %s", MinRefreshInterval, c.Sync.FullInterval))
+	}
+	if !slices.Contains(groupings, c.Grouping) {
+		problems = append(problems, fmt.Sprintf("grouping: must be one of %s, got %q", strings.Join(groupings, ", "), c.Grouping))
+	}
+	problems = append(problems, c.Filters.validate("filters.")...)
+	for _, grouping := range slices.Sorted(maps.Keys(c.Sorting)) {
+		strategy := c.Sorting[grouping]
+		if grouping != SortDefault && !slices.Contains(groupings, grouping) {
+			problems = append(problems, fmt.Sprintf("sorting: unknown grouping %q", grouping))
+		}
+		if !slices.Contains(menu.SortStrategies, strategy) {
+			problems = append(problems, fmt.Sprintf("sorting.%s: must be one of %s, got %q", grouping, strings.Join(menu.SortStrategies, ", "), strategy))
+		}
+	}
+	if c.Display.InboxLimit < 0 {
+		problems = append(problems, fmt.Sprintf("display.inbox_limit: must not be negative, got %d", c.Display.InboxLimit))
+	}
+	if c.Display.DueSoon < 0 {
+		problems = append(problems, fmt.Sprintf("display.due_soon: must not be negative, got %d", c.Display.DueSoon))
+	}
+	if !slices.Contains(menu.Badges, c.Display.Badge) {
+		problems = append(problems, fmt.Sprintf("display.badge: must be one of %s, got %q", strings.Join(menu.Badges, ", "), c.Display.Badge))
+	}
+	if c.Webhook.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Webhook.Listen); err != nil {
+			problems = append(problems, fmt.Sprintf("webhook.listen: must be host:port, got %q", c.Webhook.Listen))
+		}
+		secrets := c.Webhook.Secret != ""
+		for _, workspace := range c.Workspaces {
+			secrets = secrets || workspace.WebhookSecret != ""
+		}
+		if !secrets {
+			problems = append(problems, "webhook.secret: required when webhook.listen is set")
+		}
+	}
+	if len(c.Workspaces) == 0 {
+		problems = append(problems, c.Credentials.validate("credentials")...)
+	}
+	seen := map[string]bool{}
+	for i, workspace := range c.Workspaces {
+		key := fmt.Sprintf("workspaces[%d]", i)
+		name := strings.ToLower(workspace.Name)
+		switch {
+		case workspace.Name == "":
+			problems = append(problems, key+".name: required")
+		case seen[name]:
+			problems = append(problems, fmt.Sprintf("%s.name: %q is used by another workspace", key, workspace.Name))
+		}
+		seen[name] = true
+		problems = append(problems, workspace.Credentials.validate(key+".credentials")...)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// validate returns the problems with the filters, naming each setting with
+// prefix.
+func (f Filters) validate(prefix string) []string {
+	var problems []string
+	for _, state := range f.States {
+		switch {
+		case !stateTypes[state]:
+			problems = append(problems, fmt.Sprintf("%sstates: unknown state type %q", prefix, state))
+		case state == "completed" || state == "canceled":
+			problems = append(problems, fmt.Sprintf("%sstates: %s issues are never shown", prefix, state))
+		}
+	}
+	for _, state := range f.ExcludeStates {
+		if !stateTypes[state] {
+			problems = append(problems, fmt.Sprintf("%sexclude_states: unknown state type %q", prefix, state))
+		}
+	}
+	for _, priority := range f.Priorities {
+		if priority < 0 || priority > 4 {
+			problems = append(problems, fmt.Sprintf("%spriorities: must be between 0 and 4, got %d", prefix, priority))
+		}
+	}
+	switch f.Cycle {
+	case "", linear.CycleCurrent, linear.CycleNext, linear.CyclePrevious, linear.CycleNone:
+	default:
+		problems = append(problems, fmt.Sprintf("%scycle: must be current, next, previous or none, got %q", prefix, f.Cycle))
+	}
+	if f.DueWithin < 0 {
+		problems = append(problems, fmt.Sprintf("%sdue_within: must not be negative, got %d", prefix, f.DueWithin))
+	}
+	return problems
+}
+
+// validate returns the problems with credentials found under key.
+func (c Credentials) validate(key string) []string {
+	switch c.Source {
+	case CredentialsEnv:
+		if c.Env == "" {
+			return []string{key + ".env: required when source is env"}
+		}
+	case CredentialsFile:
+		if c.File == "" {
+			return []string{key + ".file: required when source is file"}
+		}
+	case CredentialsAuto, CredentialsSystem, CredentialsEncrypted:
+	default:
+		return []string{fmt.Sprintf("%s.source: must be one of %s, got %q", key,
+			strings.Join([]string{CredentialsAuto, CredentialsEnv, CredentialsFile, CredentialsSystem, CredentialsEncrypted}, ", "), c.Source)}
+	}
+
+	var problems []string
+	if c.OAuth.Enabled() && (c.Source == CredentialsEnv || c.Source == CredentialsFile) {
+		problems = append(problems, fmt.Sprintf("%s.oauth: tokens can't be stored with source %s, use %s, %s or %s", key, c.Source, CredentialsAuto, CredentialsSystem, CredentialsEncrypted))
+	}
+	if c.OAuth.RedirectPort < 0 || c.OAuth.RedirectPort > 65535 {
+		problems = append(problems, fmt.Sprintf("%s.oauth.redirect_port: must be between 0 and 65535, got %d", key, c.OAuth.RedirectPort))
+	}
+	if !c.OAuth.Enabled() && (c.OAuth != OAuth{}) {
+		problems = append(problems, key+".oauth.client_id: required when oauth is configured")
+	}
+	return problems
+}
+
+// AllWorkspaces returns the configured workspaces, or a single workspace named
+// DefaultWorkspace that uses Credentials when none are configured.
+func (c *Config) AllWorkspaces() []Workspace {
+	if len(c.Workspaces) == 0 {
+		return []Workspace{{Name: DefaultWorkspace, Credentials: c.Credentials}}
+	}
+	return c.Workspaces
+}
+
+// SortingFor returns the sort strategy of the issues when grouped by
+// grouping.
+func (c *Config) SortingFor(grouping string) string {
+	if strategy, ok := c.Sorting[grouping]; ok {
+		return strategy
+	}
+	if strategy, ok := c.Sorting[SortDefault]; ok {
+		return strategy
+	}
+	return menu.SortByDueDate
+}
+
+// Workspace returns the workspace with the given name, ignoring case. An
+// empty name returns the first workspace.
+func (c *Config) Workspace(name string) (Workspace, bool) {
+	workspaces := c.AllWorkspaces()
+	if name == "" {
+		return workspaces[0], true
+	}
+	for _, workspace := range workspaces {
+		if strings.EqualFold(workspace.Name, name) {
+			return workspace, true
+		}
+	}
+	return Workspace{}, false
+}
+
+// ExpandHome replaces a leading ~ in path with the user's home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// IssueFilter returns the filters as a query for the issues to fetch.
+func (f Filters) IssueFilter() linear.IssueFilter {
+	return linear.IssueFilter{
+		Teams:         f.Teams,
+		Projects:      f.Projects,
+		Labels:        f.Labels,
+		Priorities:    f.Priorities,
+		Cycle:         f.Cycle,
+		States:        f.States,
+		ExcludeStates: f.ExcludeStates,
+		DueWithinDays: f.DueWithin,
+	}
+}
+
+// Apply returns the issues that pass the filters, keeping their order. Only
+// teams, projects, priorities and states are checked; the fetch applies the
+// rest. This covers cached issues fetched under other filters and issues
+// updated into a filtered out state since.
+func (f Filters) Apply(issues []linear.Issue) []linear.Issue {
+	if len(f.Teams) == 0 && len(f.Projects) == 0 && len(f.Priorities) == 0 && len(f.States) == 0 && len(f.ExcludeStates) == 0 {
+		return issues
+	}
+	filtered := []linear.Issue{}
+	for _, issue := range issues {
+		if f.applies(issue) {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// applies checks the filters Apply checks.
+func (f Filters) applies(issue linear.Issue) bool {
+	switch {
+	case len(f.Teams) > 0 && !containsFold(f.Teams, issue.Team.Key):
+		return false
+	case len(f.Projects) > 0 && !containsFold(f.Projects, issue.Project.Name):
+		return false
+	case len(f.Priorities) > 0 && !slices.Contains(f.Priorities, int(issue.Priority)):
+		return false
+	case len(f.States) > 0 && !containsFold(f.States, issue.State.Type):
+		return false
+	case containsFold(f.ExcludeStates, issue.State.Type):
+		return false
+	}
+	return true
+}
+
+// Matches reports whether issue passes every filter that can be checked
+// without asking Linear, with due dates counted from the day of now. Of the
+// cycle filters other than none, only that the issue has a cycle is checked.
+func (f Filters) Matches(issue linear.Issue, now time.Time) bool {
+	if !f.applies(issue) {
+		return false
+	}
+	if len(f.Labels) > 0 && !slices.ContainsFunc(issue.Labels.Nodes, func(label linear.Label) bool {
+		return containsFold(f.Labels, label.Name)
+	}) {
+		return false
+	}
+	switch f.Cycle {
+	case "":
+	case linear.CycleNone:
+		if issue.Cycle.Id != "" {
+			return false
+		}
+	default:
+		if issue.Cycle.Id == "" {
+			return false
+		}
+	}
+	if f.DueWithin > 0 {
+		due, err := time.Parse("2006-01-02", issue.DueDate)
+		last := time.Date(now.Year(), now.Month(), now.Day()+f.DueWithin, 0, 0, 0, 0, time.UTC)
+		if err != nil || due.After(last) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseFilters parses filters given on the command line as terms like
+// teams=ENG,OPS or due_within=7, using the keys of the filters section.
+// Repeating a list key adds to it.
+func ParseFilters(terms []string) (Filters, error) {
+	var f Filters
+	var problems []string
+	for _, term := range terms {
+		key, value, ok := strings.Cut(term, "=")
+		if !ok || value == "" {
+			problems = append(problems, fmt.Sprintf("%q: must be KEY=VALUE", term))
+			continue
+		}
+		values := strings.Split(value, ",")
+		switch key {
+		case "teams":
+			f.Teams = append(f.Teams, values...)
+		case "projects":
+			f.Projects = append(f.Projects, values...)
+		case "labels":
+			f.Labels = append(f.Labels, values...)
+		case "states":
+			f.States = append(f.States, values...)
+		case "exclude_states":
+			f.ExcludeStates = append(f.ExcludeStates, values...)
+		case "cycle":
+			f.Cycle = value
+		case "priorities":
+			for _, v := range values {
+				priority, err := strconv.Atoi(v)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("priorities: %q is not a number", v))
+					continue
+				}
+				f.Priorities = append(f.Priorities, priority)
+			}
+		case "due_within":
+			days, err := strconv.Atoi(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("due_within: %q is not a number of days", value))
+				continue
+			}
+			f.DueWithin = days
+		default:
+			problems = append(problems, fmt.Sprintf("unknown filter %q", key))
+		}
+	}
+	problems = append(problems, f.validate("")...)
+	if len(problems) > 0 {
+		return Filters{}, fmt.Errorf("invalid filter: %s", strings.Join(problems, "; "))
+	}
+	return f, nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSecret returns the signing secret of the named workspace's webhook.
+// An empty name means the first workspace.
+func (c *Config) WebhookSecret(name string) (string, bool) {
+	workspace, ok := c.Workspace(name)
+	if !ok {
+		return "", false
+	}
+	if workspace.WebhookSecret != "" {
+		return workspace.WebhookSecret, true
+	}
+	return c.Webhook.Secret, c.Webhook.Secret != ""
+}
@@ -0,0 +1,340 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/menu"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`
+refresh_interval: 2m
+grouping: flat
+filters:
+  teams: [ENG]
+  exclude_states: [backlog]
+display:
+  tooltips: false
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.RefreshInterval != 2*time.Minute {
+		t.Errorf("Expected refresh interval 2m, got %s", cfg.RefreshInterval)
+	}
+	if cfg.Grouping != menu.Flat {
+		t.Errorf("Expected flat grouping, got %q", cfg.Grouping)
+	}
+	if cfg.Display.Tooltips {
+		t.Error("Expected tooltips to be turned off")
+	}
+	// Settings left out keep their defaults
+	if !cfg.Display.Inbox || cfg.Display.InboxLimit != 10 || cfg.Credentials.Env != "LINEAR_API_KEY" || !cfg.Sync.Incremental || cfg.Sync.FullInterval != time.Hour {
+		t.Errorf("Expected unset settings to keep their defaults, got %+v", cfg)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		expect []string
+	}{
+		{name: "Unknown key", data: "refresh: 1m", expect: []string{"field refresh not found"}},
+		{name: "Malformed duration", data: "refresh_interval: soon", expect: []string{"invalid config"}},
+		{name: "Interval too short", data: "refresh_interval: 1s", expect: []string{"refresh_interval: must be at least 30s"}},
+		{name: "Full sync too frequent", data: "sync: {full_interval: 10s}", expect: []string{"sync.full_interval: must be at least 30s"}},
+		{name: "Webhook without secret", data: "webhook: {listen: 127.0.0.1:8470}", expect: []string{"webhook.secret: required"}},
+		{name: "Webhook without port", data: "webhook: {listen: localhost, secret: s}", expect: []string{`webhook.listen: must be host:port, got "localhost"`}},
+		{name: "Unknown grouping", data: "grouping: assignee", expect: []string{`grouping: must be one of project, team, state, priority, cycle, label, due, flat, got "assignee"`}},
+		{name: "Unknown state type", data: "filters: {exclude_states: [done]}", expect: []string{`filters.exclude_states: unknown state type "done"`}},
+		{name: "Unknown sort strategy", data: "sorting: {flat: random}", expect: []string{`sorting.flat: must be one of due, priority, manual, updated, identifier, got "random"`}},
+		{name: "Sorting unknown grouping", data: "sorting: {assignee: due}", expect: []string{`sorting: unknown grouping "assignee"`}},
+		{name: "Completed state", data: "filters: {states: [started, completed]}", expect: []string{"filters.states: completed issues are never shown"}},
+		{name: "Unknown priority", data: "filters: {priorities: [5]}", expect: []string{"filters.priorities: must be between 0 and 4, got 5"}},
+		{name: "Unknown cycle", data: "filters: {cycle: upcoming}", expect: []string{`filters.cycle: must be current, next, previous or none, got "upcoming"`}},
+		{name: "Negative due within", data: "filters: {due_within: -1}", expect: []string{"filters.due_within: must not be negative"}},
+		{name: "Negative inbox limit", data: "display: {inbox_limit: -1}", expect: []string{"display.inbox_limit"}},
+		{name: "Negative due soon", data: "display: {due_soon: -2}", expect: []string{"display.due_soon: must not be negative, got -2"}},
+		{name: "Unknown badge", data: "display: {badge: all}", expect: []string{`display.badge: must be one of none, overdue, today, soon, got "all"`}},
+		{name: "Unknown credential source", data: "credentials: {source: vault}", expect: []string{"credentials.source"}},
+		{name: "File source without path", data: "credentials: {source: file}", expect: []string{"credentials.file: required"}},
+		{name: "OAuth with env source", data: "credentials: {source: env, oauth: {client_id: abc}}", expect: []string{"credentials.oauth: tokens can't be stored with source env"}},
+		{name: "OAuth without client ID", data: "credentials: {oauth: {redirect_port: 8000}}", expect: []string{"credentials.oauth.client_id: required"}},
+		{name: "Workspace without name", data: "workspaces: [{credentials: {source: env, env: KEY}}]", expect: []string{"workspaces[0].name: required"}},
+		{
+			name:   "Duplicate workspace",
+			data:   "workspaces: [{name: acme, credentials: {source: env, env: A}}, {name: ACME, credentials: {source: env, env: B}}]",
+			expect: []string{`workspaces[1].name: "ACME" is used by another workspace`},
+		},
+		{name: "Workspace without credentials", data: "workspaces: [{name: acme}]", expect: []string{"workspaces[0].credentials.source"}},
+		{
+			name:   "Every problem is reported",
+			data:   "refresh_interval: 1s\ngrouping: assignee",
+			expect: []string{"refresh_interval", "grouping"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tc.expect {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got %q", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	cfg := Default()
+	if got := cfg.AllWorkspaces(); len(got) != 1 || got[0].Name != DefaultWorkspace || got[0].Credentials.Env != "LINEAR_API_KEY" {
+		t.Errorf("Expected a single default workspace, got %+v", got)
+	}
+
+	cfg, err := Parse([]byte(`
+workspaces:
+  - name: acme
+    credentials: {source: env, env: ACME_KEY}
+  - name: globex
+    credentials: {source: file, file: /keys/globex}
+    webhook_secret: globex-secret
+webhook:
+  listen: 127.0.0.1:8470
+  secret: shared-secret
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := cfg.AllWorkspaces(); len(got) != 2 {
+		t.Fatalf("Expected 2 workspaces, got %+v", got)
+	}
+	tests := []struct {
+		name   string
+		found  bool
+		expect string
+	}{
+		{name: "", found: true, expect: "acme"},
+		{name: "Globex", found: true, expect: "globex"},
+		{name: "default", found: false},
+	}
+	for _, tc := range tests {
+		workspace, ok := cfg.Workspace(tc.name)
+		if ok != tc.found || workspace.Name != tc.expect {
+			t.Errorf("Workspace(%q): expected (%q, %v), got (%q, %v)", tc.name, tc.expect, tc.found, workspace.Name, ok)
+		}
+	}
+
+	for name, expect := range map[string]string{"": "shared-secret", "acme": "shared-secret", "globex": "globex-secret"} {
+		if secret, ok := cfg.WebhookSecret(name); !ok || secret != expect {
+			t.Errorf("WebhookSecret(%q): expected %q, got %q", name, expect, secret)
+		}
+	}
+	if _, ok := cfg.WebhookSecret("initech"); ok {
+		t.Error("Expected no webhook secret for an unknown workspace")
+	}
+}
+
+func TestSortingFor(t *testing.T) {
+	if got := Default().SortingFor(menu.ByTeam); got != menu.SortByDueDate {
+		t.Errorf("Expected due date sorting by default, got %q", got)
+	}
+	cfg, err := Parse([]byte("sorting: {flat: priority}"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := cfg.SortingFor(menu.Flat); got != menu.SortByPriority {
+		t.Errorf("Expected priority sorting when flat, got %q", got)
+	}
+	if got := cfg.SortingFor(menu.ByProject); got != menu.SortByDueDate {
+		t.Errorf("Expected the default sorting for other groupings, got %q", got)
+	}
+
+	cfg, err = Parse([]byte("sorting: {default: manual, label: updated}"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.SortingFor(menu.ByLabel) != menu.SortByUpdated || cfg.SortingFor(menu.ByTeam) != menu.SortManual {
+		t.Errorf("Expected label issues by update and others in manual order, got %v", cfg.Sorting)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.RefreshInterval != Default().RefreshInterval {
+		t.Errorf("Expected defaults for a missing file, got %+v", cfg)
+	}
+}
+
+func TestFiltersApply(t *testing.T) {
+	issues := []linear.Issue{{Identifier: "ENG-1"}, {Identifier: "ENG-2"}, {Identifier: "OPS-1"}}
+	issues[0].Team.Key, issues[0].State.Type, issues[0].Project.Name = "ENG", "started", "Rocket"
+	issues[1].Team.Key, issues[1].State.Type = "ENG", "backlog"
+	issues[2].Team.Key, issues[2].State.Type, issues[2].Project.Name = "OPS", "started", "Rocket"
+	issues[0].Priority, issues[1].Priority = 1, 3
+
+	tests := []struct {
+		name    string
+		filters Filters
+		expect  string
+	}{
+		{name: "No filters", expect: "ENG-1,ENG-2,OPS-1"},
+		{name: "Team", filters: Filters{Teams: []string{"eng"}}, expect: "ENG-1,ENG-2"},
+		{name: "Project", filters: Filters{Projects: []string{"rocket"}}, expect: "ENG-1,OPS-1"},
+		{name: "Excluded state", filters: Filters{ExcludeStates: []string{"backlog"}}, expect: "ENG-1,OPS-1"},
+		{name: "State", filters: Filters{States: []string{"backlog"}}, expect: "ENG-2"},
+		{name: "Priority", filters: Filters{Priorities: []int{0, 1}}, expect: "ENG-1,OPS-1"},
+		{name: "Labels are left to the fetch", filters: Filters{Labels: []string{"Bug"}, DueWithin: 7}, expect: "ENG-1,ENG-2,OPS-1"},
+		{name: "Combined", filters: Filters{Teams: []string{"ENG"}, ExcludeStates: []string{"backlog"}}, expect: "ENG-1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := []string{}
+			for _, issue := range tc.filters.Apply(issues) {
+				ids = append(ids, issue.Identifier)
+			}
+			if got := strings.Join(ids, ","); got != tc.expect {
+				t.Errorf("Expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestFiltersMatches(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	issue := linear.Issue{Identifier: "ENG-1", DueDate: "2024-03-05"}
+	issue.Team.Key, issue.State.Type = "ENG", "started"
+	issue.Labels.Nodes = []linear.Label{{Name: "Bug"}}
+	issue.Cycle.Id = "cycle-12"
+
+	tests := []struct {
+		name    string
+		filters Filters
+		expect  bool
+	}{
+		{name: "No filters", expect: true},
+		{name: "Other team", filters: Filters{Teams: []string{"OPS"}}},
+		{name: "Label", filters: Filters{Labels: []string{"feature", "bug"}}, expect: true},
+		{name: "Other label", filters: Filters{Labels: []string{"Feature"}}},
+		{name: "Current cycle", filters: Filters{Cycle: "current"}, expect: true},
+		{name: "No cycle", filters: Filters{Cycle: "none"}},
+		{name: "Due within", filters: Filters{DueWithin: 4}, expect: true},
+		{name: "Due later", filters: Filters{DueWithin: 3}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filters.Matches(issue, now); got != tc.expect {
+				t.Errorf("Expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		terms  []string
+		expect Filters
+		err    string
+	}{
+		{name: "None", expect: Filters{}},
+		{
+			name:   "Lists",
+			terms:  []string{"teams=ENG,OPS", "labels=Bug", "labels=Regression", "priorities=1,2"},
+			expect: Filters{Teams: []string{"ENG", "OPS"}, Labels: []string{"Bug", "Regression"}, Priorities: []int{1, 2}},
+		},
+		{
+			name:   "Values",
+			terms:  []string{"projects=Mobile App", "cycle=current", "states=started", "exclude_states=triage", "due_within=7"},
+			expect: Filters{Projects: []string{"Mobile App"}, Cycle: "current", States: []string{"started"}, ExcludeStates: []string{"triage"}, DueWithin: 7},
+		},
+		{name: "Not a term", terms: []string{"ENG"}, err: `"ENG": must be KEY=VALUE`},
+		{name: "Unknown key", terms: []string{"team=ENG"}, err: `unknown filter "team"`},
+		{name: "Bad priority", terms: []string{"priorities=high"}, err: `priorities: "high" is not a number`},
+		{name: "Invalid value", terms: []string{"cycle=soon", "due_within=-2"}, err: `cycle: must be current, next, previous or none, got "soon"; due_within: must not be negative`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseFilters(tc.terms)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if fmt.Sprintf("%+v", f) != fmt.Sprintf("%+v", tc.expect) {
+				t.Errorf("Expected %+v, got %+v", tc.expect, f)
+			}
+		})
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	var got *Config
+	var gotErr error
+	calls := 0
+	w := NewWatcher(path, func(cfg *Config, err error) {
+		calls++
+		got, gotErr = cfg, err
+	})
+
+	w.Check()
+	if calls != 0 {
+		t.Fatalf("Expected no reload without changes, got %d", calls)
+	}
+
+	write := func(data string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base := time.Now().Add(-time.Hour)
+	write("refresh_interval: 1m", base)
+	w.Check()
+	if calls != 1 || gotErr != nil || got.RefreshInterval != time.Minute {
+		t.Fatalf("Expected a reload with the new interval, got %d calls, %+v, %v", calls, got, gotErr)
+	}
+
+	w.Check()
+	if calls != 1 {
+		t.Errorf("Expected no reload for an unchanged file, got %d calls", calls)
+	}
+
+	write("refresh_interval: 1s", base.Add(time.Second))
+	w.Check()
+	if calls != 2 || gotErr == nil {
+		t.Errorf("Expected an invalid file to be reported, got %d calls, %v", calls, gotErr)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	w.Check()
+	if calls != 3 || gotErr != nil || got.RefreshInterval != Default().RefreshInterval {
+		t.Errorf("Expected a deleted file to restore the defaults, got %+v, %v", got, gotErr)
+	}
+}
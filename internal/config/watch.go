@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultPollInterval is how often Watcher checks the config file.
+const DefaultPollInterval = 2 * time.Second
+
+// Watcher polls a config file and reloads it when its modification time or
+// size changes. Polling works the same everywhere and catches editors that
+// replace the file instead of writing to it.
+type Watcher struct {
+	path     string
+	onChange func(*Config, error)
+
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher creates a watcher for the file at path. onChange is called with
+// the new config after every change, or with the error if the file became
+// invalid; a deleted file reloads the defaults. The file's current state is
+// taken as the starting point, so onChange is not called for it.
+func NewWatcher(path string, onChange func(*Config, error)) *Watcher {
+	w := &Watcher{path: path, onChange: onChange}
+	w.changed()
+	return w
+}
+
+// Run checks the file every interval until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Check reloads the file if it changed since the last check.
+func (w *Watcher) Check() {
+	if w.changed() {
+		w.onChange(Load(w.path))
+	}
+}
+
+// changed records the file's current state and reports whether it differs
+// from the previous one.
+func (w *Watcher) changed() bool {
+	info, err := os.Stat(w.path)
+	exists := err == nil
+	var modTime time.Time
+	var size int64
+	if exists {
+		modTime, size = info.ModTime(), info.Size()
+	}
+
+	changed := exists != w.exists || !modTime.Equal(w.modTime) || size != w.size
+	w.exists, w.modTime, w.size = exists, modTime, size
+	return changed
+}
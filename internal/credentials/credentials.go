@@ -0,0 +1,262 @@
+// Package credentials stores and looks up the Linear API keys of lil's
+// workspaces in the macOS Keychain, the freedesktop Secret Service, an
+// encrypted file or the environment.
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pzurek/lil/internal/config"
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// Service identifies lil's secrets in the system stores.
+const Service = "lil"
+
+var (
+	// ErrNotFound is returned by Get and Delete when no secret is stored for
+	// the account.
+	ErrNotFound = errors.New("no stored API key")
+	// ErrReadOnly is returned by Set and Delete of stores lil can't write to.
+	ErrReadOnly = errors.New("credential store is read-only")
+	// ErrUnavailable is returned when a store can't be used on this system,
+	// e.g. because no Secret Service is running.
+	ErrUnavailable = errors.New("credential store is unavailable")
+)
+
+// Store keeps one secret per account. Accounts are workspace names.
+type Store interface {
+	// Name describes the store in messages, e.g. "macOS Keychain".
+	Name() string
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// Env reads the secret of every account from an environment variable.
+type Env struct {
+	Var string
+}
+
+func (e Env) Name() string { return e.Var + " environment variable" }
+
+func (e Env) Get(string) (string, error) {
+	secret := strings.TrimSpace(os.Getenv(e.Var))
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s environment variable is empty", ErrNotFound, e.Var)
+	}
+	return secret, nil
+}
+
+func (e Env) Set(string, string) error { return ErrReadOnly }
+func (e Env) Delete(string) error      { return ErrReadOnly }
+
+// File reads the secret of every account from a plain text file. It is read
+// on every lookup, so the file can be rewritten without restarting.
+type File struct {
+	Path string
+}
+
+func (f File) Name() string { return "file " + f.Path }
+
+func (f File) Get(string) (string, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrNotFound, f.Path)
+	}
+	return secret, nil
+}
+
+func (f File) Set(string, string) error { return ErrReadOnly }
+func (f File) Delete(string) error      { return ErrReadOnly }
+
+// Chain tries its stores in order. Get returns the first secret found; Set
+// writes to the first store that accepts it.
+type Chain []Store
+
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, store := range c {
+		names[i] = store.Name()
+	}
+	return strings.Join(names, ", then ")
+}
+
+func (c Chain) Get(account string) (string, error) {
+	_, secret, err := c.Locate(account)
+	return secret, err
+}
+
+// Locate returns the secret of account and the store it was found in.
+func (c Chain) Locate(account string) (Store, string, error) {
+	for _, store := range c {
+		secret, err := store.Get(account)
+		if err == nil {
+			return store, secret, nil
+		}
+		if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrUnavailable) {
+			return nil, "", fmt.Errorf("%s: %w", store.Name(), err)
+		}
+	}
+	return nil, "", fmt.Errorf("%w in %s", ErrNotFound, c.Name())
+}
+
+func (c Chain) Set(account, secret string) error {
+	_, err := c.Save(account, secret)
+	return err
+}
+
+// Save stores the secret of account in the first store that is neither
+// read-only nor unavailable, and returns that store.
+func (c Chain) Save(account, secret string) (Store, error) {
+	var errs []error
+	for _, store := range c {
+		err := store.Set(account, secret)
+		if err == nil {
+			return store, nil
+		}
+		if errors.Is(err, ErrReadOnly) {
+			continue
+		}
+		if !errors.Is(err, ErrUnavailable) {
+			return nil, fmt.Errorf("%s: %w", store.Name(), err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", store.Name(), err))
+	}
+	if len(errs) == 0 {
+		return nil, ErrReadOnly
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Delete removes the secret of account from every store that holds one.
+func (c Chain) Delete(account string) error {
+	deleted := false
+	for _, store := range c {
+		err := store.Delete(account)
+		switch {
+		case err == nil:
+			deleted = true
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrReadOnly), errors.Is(err, ErrUnavailable):
+		default:
+			return fmt.Errorf("%s: %w", store.Name(), err)
+		}
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// System returns the operating system's secret store: the Keychain on macOS
+// and the Secret Service on Linux. It returns nil on other systems.
+func System() Store {
+	switch runtime.GOOS {
+	case "darwin":
+		return NewKeychain()
+	case "linux":
+		return NewSecretService()
+	}
+	return nil
+}
+
+// DefaultEncryptedFilePath returns where the encrypted_file source keeps its
+// secrets unless the config names another file.
+func DefaultEncryptedFilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "lil", "credentials.enc")
+}
+
+// Resolve returns the store the credentials in the config point to.
+func Resolve(c config.Credentials) Store {
+	encryptedPath := DefaultEncryptedFilePath()
+	if c.Source == config.CredentialsEncrypted && c.File != "" {
+		encryptedPath = config.ExpandHome(c.File)
+	}
+	encrypted := NewEncryptedFile(encryptedPath)
+
+	switch c.Source {
+	case config.CredentialsEnv:
+		return Env{Var: c.Env}
+	case config.CredentialsFile:
+		return File{Path: config.ExpandHome(c.File)}
+	case config.CredentialsSystem:
+		if system := System(); system != nil {
+			return system
+		}
+		return unavailable{name: "system credential store"}
+	case config.CredentialsEncrypted:
+		return encrypted
+	}
+
+	// The auto source keeps a key exported in the environment working and
+	// otherwise prefers the system store over the encrypted file. OAuth
+	// tokens are never read from the environment.
+	var chain Chain
+	if c.Env != "" && !c.OAuth.Enabled() {
+		chain = append(chain, Env{Var: c.Env})
+	}
+	if system := System(); system != nil {
+		chain = append(chain, system)
+	}
+	return append(chain, encrypted)
+}
+
+// unavailable stands in for a store that doesn't exist on this system.
+type unavailable struct {
+	name string
+}
+
+func (u unavailable) Name() string               { return u.name }
+func (u unavailable) Get(string) (string, error) { return "", u.err() }
+func (u unavailable) Set(string, string) error   { return u.err() }
+func (u unavailable) Delete(string) error        { return u.err() }
+
+func (u unavailable) err() error {
+	return fmt.Errorf("%w: no %s on %s", ErrUnavailable, u.name, runtime.GOOS)
+}
+
+// CacheTTL is how long APIKeySource reuses a key before looking it up again.
+// System stores can be slow or prompt the user, so they aren't consulted on
+// every request.
+const CacheTTL = time.Minute
+
+// APIKeySource returns a linear.APIKeySource that looks the key of account up
+// in store, reusing it for CacheTTL.
+func APIKeySource(store Store, account string) linear.APIKeySource {
+	var (
+		mu      sync.Mutex
+		key     string
+		fetched time.Time
+	)
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if key != "" && time.Since(fetched) < CacheTTL {
+			return key, nil
+		}
+		secret, err := store.Get(account)
+		if errors.Is(err, ErrNotFound) {
+			return "", fmt.Errorf("%w: %v", linear.ErrMissingAPIKey, err)
+		}
+		if err != nil {
+			return "", err
+		}
+		key, fetched = secret, time.Now()
+		return key, nil
+	}
+}
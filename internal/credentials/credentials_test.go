@@ -0,0 +1,320 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+func init() {
+	// Keep key derivation fast in tests
+	kdfIterations = 1000
+}
+
+// memoryStore is a writable Store backed by a map.
+type memoryStore struct {
+	name    string
+	secrets map[string]string
+	err     error
+	gets    int
+}
+
+func (m *memoryStore) Name() string { return m.name }
+
+func (m *memoryStore) Get(account string) (string, error) {
+	m.gets++
+	if m.err != nil {
+		return "", m.err
+	}
+	secret, ok := m.secrets[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (m *memoryStore) Set(account, secret string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.secrets[account] = secret
+	return nil
+}
+
+func (m *memoryStore) Delete(account string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.secrets[account]; !ok {
+		return ErrNotFound
+	}
+	delete(m.secrets, account)
+	return nil
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	store := File{Path: path}
+
+	if _, err := store.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing file, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("lin_api_123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := store.Get("acme"); err != nil || key != "lin_api_123" {
+		t.Errorf("Expected key lin_api_123, got %q (%v)", key, err)
+	}
+	if err := store.Set("acme", "other"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Setenv("LIL_TEST_KEY", "")
+	offline := &memoryStore{name: "offline", err: ErrUnavailable}
+	system := &memoryStore{name: "system", secrets: map[string]string{}}
+	chain := Chain{Env{Var: "LIL_TEST_KEY"}, offline, system}
+
+	if _, err := chain.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound from an empty chain, got %v", err)
+	}
+
+	store, err := chain.Save("acme", "lin_api_system")
+	if err != nil || store != system {
+		t.Fatalf("Expected the key to be saved in the first writable store, got %v (%v)", store, err)
+	}
+	if key, err := chain.Get("acme"); err != nil || key != "lin_api_system" {
+		t.Errorf("Expected the stored key, got %q (%v)", key, err)
+	}
+
+	t.Setenv("LIL_TEST_KEY", "lin_api_env")
+	if store, key, err := chain.Locate("acme"); err != nil || store.Name() != "LIL_TEST_KEY environment variable" || key != "lin_api_env" {
+		t.Errorf("Expected the environment to win, got %q from %v (%v)", key, store, err)
+	}
+
+	if err := chain.Delete("acme"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(system.secrets) != 0 {
+		t.Errorf("Expected the stored key to be deleted, got %v", system.secrets)
+	}
+	if err := chain.Delete("acme"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when nothing is stored, got %v", err)
+	}
+
+	if _, err := (Chain{Env{Var: "LIL_TEST_KEY"}, offline}).Save("acme", "key"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable without a writable store, got %v", err)
+	}
+}
+
+func TestEncryptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lil", "credentials.enc")
+	passphrase := "correct horse"
+	store := &EncryptedFile{Path: path, Passphrase: func() (string, error) { return passphrase, nil }}
+
+	if _, err := store.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound before anything is stored, got %v", err)
+	}
+	if err := store.Set("acme", "lin_api_acme"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Set("globex", "lin_api_globex"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected mode 0600, got %o", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "lin_api") {
+		t.Errorf("Expected keys to be encrypted, got %s", data)
+	}
+
+	if key, err := store.Get("acme"); err != nil || key != "lin_api_acme" {
+		t.Errorf("Expected lin_api_acme, got %q (%v)", key, err)
+	}
+	if err := store.Delete("acme"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := store.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+	}
+	if key, err := store.Get("globex"); err != nil || key != "lin_api_globex" {
+		t.Errorf("Expected the other account to be kept, got %q (%v)", key, err)
+	}
+
+	passphrase = "wrong"
+	if _, err := store.Get("globex"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Expected a wrong passphrase error, got %v", err)
+	}
+
+	t.Setenv(PassphraseEnv, "")
+	if _, err := NewEncryptedFile(path).Get("globex"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable without a passphrase, got %v", err)
+	}
+}
+
+func TestEncryptedFilePrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lil", "credentials.enc")
+	t.Setenv(PassphraseEnv, "")
+	prompts := 0
+	answer := "correct horse"
+	PromptPassphrase = func(string) (string, bool) {
+		prompts++
+		return answer, answer != ""
+	}
+	defer func() { PromptPassphrase = nil }()
+
+	if err := NewEncryptedFile(path).Set("acme", "lin_api_acme"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := NewEncryptedFile(path).Set("globex", "lin_api_globex"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if key, err := NewEncryptedFile(path).Get("acme"); err != nil || key != "lin_api_acme" {
+		t.Errorf("Expected lin_api_acme, got %q (%v)", key, err)
+	}
+	if prompts != 1 {
+		t.Errorf("Expected the passphrase to be asked for once, got %d prompts", prompts)
+	}
+
+	// Another run has to ask again, and gets nowhere when cancelled
+	sessionKeys.Lock()
+	delete(sessionKeys.byPath, path)
+	sessionKeys.Unlock()
+	defer func() {
+		sessionKeys.Lock()
+		delete(sessionKeys.declined, path)
+		sessionKeys.Unlock()
+	}()
+	answer = ""
+	if _, err := NewEncryptedFile(path).Get("acme"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable when the prompt is cancelled, got %v", err)
+	}
+
+	// Lookups don't ask again after a cancelled prompt, writes do
+	answer = "correct horse"
+	if _, err := NewEncryptedFile(path).Get("globex"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable after a cancelled prompt, got %v", err)
+	}
+	if prompts != 2 {
+		t.Errorf("Expected no prompt after the cancelled one, got %d prompts", prompts)
+	}
+	if err := NewEncryptedFile(path).Set("initech", "lin_api_initech"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if key, err := NewEncryptedFile(path).Get("globex"); err != nil || key != "lin_api_globex" {
+		t.Errorf("Expected lin_api_globex, got %q (%v)", key, err)
+	}
+	if prompts != 3 {
+		t.Errorf("Expected the write to ask once more, got %d prompts", prompts)
+	}
+}
+
+func TestEncryptedFileConcurrentSets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lil", "credentials.enc")
+	passphrase := func() (string, error) { return "correct horse", nil }
+
+	// Each workspace has a store of its own for the same file
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			account := fmt.Sprintf("workspace-%d", i)
+			store := &EncryptedFile{Path: path, Passphrase: passphrase}
+			if err := store.Set(account, "token-"+account); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	store := &EncryptedFile{Path: path, Passphrase: passphrase}
+	for i := range 8 {
+		account := fmt.Sprintf("workspace-%d", i)
+		if secret, err := store.Get(account); err != nil || secret != "token-"+account {
+			t.Errorf("Expected the token of %s to survive concurrent writes, got %q (%v)", account, secret, err)
+		}
+	}
+}
+
+func TestKeychain(t *testing.T) {
+	items := map[string]string{}
+	var calls []string
+	store := &Keychain{run: func(stdin string, args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		switch args[0] {
+		case "find-generic-password":
+			if secret, ok := items[args[4]]; ok {
+				return secret + "\n", nil
+			}
+		case "delete-generic-password":
+			if _, ok := items[args[4]]; ok {
+				delete(items, args[4])
+				return "", nil
+			}
+		case "-i":
+			want := `add-generic-password -U -s "lil" -a "acme" -l "Linear API key (acme)" -w "lin_\"api\\"` + "\n"
+			if stdin != want {
+				t.Errorf("Unexpected command:\n%s\nExpected:\n%s", stdin, want)
+			}
+			items["acme"] = `lin_"api\`
+			return "", nil
+		}
+		return "", errors.New("security: SecKeychainSearchCopyNext: The specified item could not be found in the keychain.")
+	}}
+
+	if _, err := store.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := store.Set("acme", `lin_"api\`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if key, err := store.Get("acme"); err != nil || key != `lin_"api\` {
+		t.Errorf("Expected the stored key, got %q (%v)", key, err)
+	}
+	if err := store.Delete("acme"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Delete("acme"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	for _, call := range calls {
+		if strings.Contains(call, "lin_") {
+			t.Errorf("Expected the key to stay out of the arguments, got %q", call)
+		}
+	}
+}
+
+func TestAPIKeySource(t *testing.T) {
+	store := &memoryStore{name: "memory", secrets: map[string]string{}}
+	source := APIKeySource(store, "acme")
+
+	if _, err := source(); !errors.Is(err, linear.ErrMissingAPIKey) {
+		t.Errorf("Expected ErrMissingAPIKey, got %v", err)
+	}
+	store.secrets["acme"] = "lin_api_acme"
+	for range 3 {
+		if key, err := source(); err != nil || key != "lin_api_acme" {
+			t.Fatalf("Expected lin_api_acme, got %q (%v)", key, err)
+		}
+	}
+	if store.gets != 2 {
+		t.Errorf("Expected the key to be looked up once and then reused, got %d lookups", store.gets)
+	}
+}
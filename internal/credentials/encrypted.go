@@ -0,0 +1,341 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PassphraseEnv is the environment variable the encrypted file's passphrase
+// is read from.
+const PassphraseEnv = "LIL_CREDENTIALS_PASSPHRASE"
+
+// kdfIterations is the PBKDF2 work factor for new files. Tests lower it.
+var kdfIterations = 600_000
+
+// PromptPassphrase, when set, asks the user for the passphrase of the
+// encrypted file at path if PassphraseEnv isn't set. It reports false if the
+// user cancelled. The tray sets it, as apps started at login or from the
+// Finder don't get the variable.
+var PromptPassphrase func(path string) (string, bool)
+
+// sessionKeys holds the keys derived from the passphrases read so far, by
+// file path, so the user is asked once per run rather than on every lookup.
+// Files whose prompt was cancelled are remembered too, and lookups don't ask
+// for them again until a write, e.g. from entering a new key, does. The lock
+// also keeps several workspaces from prompting at the same time.
+var sessionKeys = struct {
+	sync.Mutex
+	byPath   map[string]derivedKey
+	declined map[string]bool
+}{byPath: map[string]derivedKey{}, declined: map[string]bool{}}
+
+// fileLocks serializes the writes to each file within lil. Each write loads
+// the whole file and saves it back, so two workspaces saving refreshed
+// tokens at once would otherwise lose one of them.
+var fileLocks = struct {
+	sync.Mutex
+	byPath map[string]*sync.Mutex
+}{byPath: map[string]*sync.Mutex{}}
+
+// derivedKey is a file key along with the KDF parameters it was derived with.
+type derivedKey struct {
+	salt       []byte
+	iterations int
+	key        []byte
+}
+
+// EncryptedFile keeps the secrets of every account in one file, encrypted
+// with AES-256-GCM under a key derived from a passphrase. It is the fallback
+// for systems without a keychain or Secret Service.
+type EncryptedFile struct {
+	Path string
+	// Passphrase returns the passphrase the file is encrypted with. When
+	// nil, PassphraseEnv is read, or else PromptPassphrase asked, and the
+	// derived key is kept for the rest of the run.
+	Passphrase func() (string, error)
+}
+
+// encryptedFileFormat is the file's JSON layout. Byte fields are base64.
+type encryptedFileFormat struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewEncryptedFile returns a store backed by the file at path, reading the
+// passphrase from PassphraseEnv or PromptPassphrase.
+func NewEncryptedFile(path string) *EncryptedFile {
+	return &EncryptedFile{Path: path}
+}
+
+// sessionPassphrase reads the passphrase of the file at path from
+// PassphraseEnv, or asks the user for it. It reports false if the user
+// cancelled.
+func sessionPassphrase(path string) (string, bool, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, true, nil
+	}
+	if PromptPassphrase == nil {
+		return "", true, fmt.Errorf("%w: the encrypted credentials file can't be used without %s, which apps started at login or from the Finder don't get; store the key in the system credential store instead", ErrUnavailable, PassphraseEnv)
+	}
+	passphrase, ok := PromptPassphrase(path)
+	if !ok || passphrase == "" {
+		return "", false, errNoPassphrase(path)
+	}
+	return passphrase, true, nil
+}
+
+func errNoPassphrase(path string) error {
+	return fmt.Errorf("%w: no passphrase entered for %s", ErrUnavailable, path)
+}
+
+func (f *EncryptedFile) Name() string { return "encrypted file " + f.Path }
+
+func (f *EncryptedFile) Get(account string) (string, error) {
+	secrets, err := f.load(false)
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (f *EncryptedFile) Set(account, secret string) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	secrets, err := f.load(true)
+	if errors.Is(err, ErrNotFound) {
+		secrets = map[string]string{}
+	} else if err != nil {
+		return err
+	}
+	secrets[account] = secret
+	return f.save(secrets)
+}
+
+func (f *EncryptedFile) Delete(account string) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	secrets, err := f.load(true)
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[account]; !ok {
+		return ErrNotFound
+	}
+	delete(secrets, account)
+	if len(secrets) == 0 {
+		return os.Remove(f.Path)
+	}
+	return f.save(secrets)
+}
+
+// lock serializes changes to the file with the other stores of its path and
+// with other lil processes, and returns the unlock.
+func (f *EncryptedFile) lock() (func(), error) {
+	fileLocks.Lock()
+	mu, ok := fileLocks.byPath[f.Path]
+	if !ok {
+		mu = &sync.Mutex{}
+		fileLocks.byPath[f.Path] = mu
+	}
+	fileLocks.Unlock()
+
+	mu.Lock()
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	unlockFile, err := lockFile(f.Path + ".lock")
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to lock %s: %w", f.Path, err)
+	}
+	return func() {
+		unlockFile()
+		mu.Unlock()
+	}, nil
+}
+
+// load decrypts the file. A missing file holds no secrets and is reported as
+// ErrNotFound without asking for the passphrase. ask asks for it even if the
+// user cancelled before.
+func (f *EncryptedFile) load(ask bool) (map[string]string, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	var file encryptedFileFormat
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", f.Path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported credentials file version %d", file.Version)
+	}
+
+	gcm, err := f.cipher(file.Salt, file.Iterations, ask)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		f.forgetKey()
+		return nil, fmt.Errorf("failed to decrypt %s: wrong passphrase or corrupted file", f.Path)
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", f.Path, err)
+	}
+	return secrets, nil
+}
+
+// save encrypts secrets with a fresh salt and nonce and atomically replaces
+// the file, which only the current user can read.
+func (f *EncryptedFile) save(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	file := encryptedFileFormat{Version: 1, Iterations: kdfIterations, Salt: make([]byte, 16)}
+	if key, ok := f.sessionKey(); ok {
+		// Keep the salt so the key entered this run still opens the file;
+		// every write gets a fresh nonce
+		file.Salt, file.Iterations = key.salt, key.iterations
+	} else if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	gcm, err := f.cipher(file.Salt, file.Iterations, true)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".credentials-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create credentials file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set credentials permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return fmt.Errorf("failed to replace credentials: %w", err)
+	}
+	return nil
+}
+
+// cipher derives the file key from the passphrase, or reuses the key derived
+// earlier in the run.
+func (f *EncryptedFile) cipher(salt []byte, iterations int, ask bool) (cipher.AEAD, error) {
+	key, err := f.key(salt, iterations, ask)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// key returns the file key for salt and iterations. Unless ask is set, the
+// user isn't asked for the passphrase again after cancelling once.
+func (f *EncryptedFile) key(salt []byte, iterations int, ask bool) ([]byte, error) {
+	if f.Passphrase != nil {
+		passphrase, err := f.Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	}
+
+	sessionKeys.Lock()
+	defer sessionKeys.Unlock()
+	if cached, ok := sessionKeys.byPath[f.Path]; ok && bytes.Equal(cached.salt, salt) && cached.iterations == iterations {
+		return cached.key, nil
+	}
+	if sessionKeys.declined[f.Path] && !ask {
+		return nil, errNoPassphrase(f.Path)
+	}
+	passphrase, ok, err := sessionPassphrase(f.Path)
+	if !ok {
+		sessionKeys.declined[f.Path] = true
+	}
+	if err != nil {
+		return nil, err
+	}
+	delete(sessionKeys.declined, f.Path)
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	sessionKeys.byPath[f.Path] = derivedKey{salt: salt, iterations: iterations, key: key}
+	return key, nil
+}
+
+// sessionKey returns the key kept for the file, if any.
+func (f *EncryptedFile) sessionKey() (derivedKey, bool) {
+	if f.Passphrase != nil {
+		return derivedKey{}, false
+	}
+	sessionKeys.Lock()
+	defer sessionKeys.Unlock()
+	key, ok := sessionKeys.byPath[f.Path]
+	return key, ok
+}
+
+// forgetKey drops the key kept for the file after it failed to decrypt it,
+// so the user is asked again.
+func (f *EncryptedFile) forgetKey() {
+	if f.Passphrase != nil {
+		return
+	}
+	sessionKeys.Lock()
+	defer sessionKeys.Unlock()
+	delete(sessionKeys.byPath, f.Path)
+}
@@ -0,0 +1,85 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Keychain stores secrets as generic passwords in the macOS login keychain,
+// using the security command line tool.
+type Keychain struct {
+	// run executes security with args, feeding it stdin, and returns its
+	// standard output. Failures include the tool's standard error.
+	run func(stdin string, args ...string) (string, error)
+}
+
+// NewKeychain returns a store backed by the login keychain.
+func NewKeychain() *Keychain {
+	return &Keychain{run: runSecurity}
+}
+
+func (k *Keychain) Name() string { return "macOS Keychain" }
+
+func (k *Keychain) Get(account string) (string, error) {
+	out, err := k.run("", "find-generic-password", "-s", Service, "-a", account, "-w")
+	if err != nil {
+		return "", keychainError(err)
+	}
+	secret := strings.TrimSpace(out)
+	if secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set adds or updates the secret of account. The command is fed to security
+// in interactive mode so the secret never shows up in the process list.
+func (k *Keychain) Set(account, secret string) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -l %s -w %s\n",
+		quoteSecurityArg(Service), quoteSecurityArg(account), quoteSecurityArg("Linear API key ("+account+")"), quoteSecurityArg(secret))
+	if _, err := k.run(command, "-i"); err != nil {
+		return keychainError(err)
+	}
+	return nil
+}
+
+func (k *Keychain) Delete(account string) error {
+	if _, err := k.run("", "delete-generic-password", "-s", Service, "-a", account); err != nil {
+		return keychainError(err)
+	}
+	return nil
+}
+
+// keychainError maps failures of the security tool onto the package errors.
+func keychainError(err error) error {
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	case strings.Contains(err.Error(), "could not be found"):
+		return ErrNotFound
+	}
+	return err
+}
+
+// quoteSecurityArg quotes s for the command parser of security -i, which
+// splits on whitespace and honors double quotes and backslash escapes.
+func quoteSecurityArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func runSecurity(stdin string, args ...string) (string, error) {
+	cmd := exec.Command("security", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("security %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("security %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,202 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Secret Service D-Bus names, see
+// https://specifications.freedesktop.org/secret-service-spec/latest/
+const (
+	secretsName           = "org.freedesktop.secrets"
+	secretsPath           = dbus.ObjectPath("/org/freedesktop/secrets")
+	defaultCollection     = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	secretServiceIface    = "org.freedesktop.Secret.Service"
+	secretCollectionIface = "org.freedesktop.Secret.Collection"
+	secretItemIface       = "org.freedesktop.Secret.Item"
+	secretSessionIface    = "org.freedesktop.Secret.Session"
+	secretPromptIface     = "org.freedesktop.Secret.Prompt"
+	noPrompt              = dbus.ObjectPath("/")
+)
+
+// secret is the Secret Service's (oayays) secret struct.
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretService stores secrets in the default collection of the freedesktop
+// Secret Service, as provided by GNOME Keyring or KWallet. Items are found
+// by their service and account attributes.
+type SecretService struct {
+	// conn returns the session bus connection to use.
+	conn func() (*dbus.Conn, error)
+}
+
+// NewSecretService returns a store backed by the Secret Service on the
+// session bus.
+func NewSecretService() *SecretService {
+	return &SecretService{conn: dbus.SessionBus}
+}
+
+func (s *SecretService) Name() string { return "Secret Service" }
+
+func (s *SecretService) Get(account string) (string, error) {
+	conn, session, err := s.open()
+	if err != nil {
+		return "", err
+	}
+	defer closeSession(conn, session)
+
+	item, err := s.find(conn, account)
+	if err != nil {
+		return "", err
+	}
+	var value secret
+	if err := conn.Object(secretsName, item).Call(secretItemIface+".GetSecret", 0, session).Store(&value); err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return string(value.Value), nil
+}
+
+func (s *SecretService) Set(account, value string) error {
+	conn, session, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer closeSession(conn, session)
+
+	properties := map[string]dbus.Variant{
+		secretItemIface + ".Label":      dbus.MakeVariant("Linear API key (" + account + ")"),
+		secretItemIface + ".Attributes": dbus.MakeVariant(attributes(account)),
+	}
+	data := secret{Session: session, Value: []byte(value), ContentType: "text/plain"}
+	var item, prompt dbus.ObjectPath
+	call := conn.Object(secretsName, defaultCollection).Call(secretCollectionIface+".CreateItem", 0, properties, data, true)
+	if err := call.Store(&item, &prompt); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	if _, err := runPrompt(conn, prompt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *SecretService) Delete(account string) error {
+	conn, session, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer closeSession(conn, session)
+
+	item, err := s.find(conn, account)
+	if err != nil {
+		return err
+	}
+	var prompt dbus.ObjectPath
+	if err := conn.Object(secretsName, item).Call(secretItemIface+".Delete", 0).Store(&prompt); err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	_, err = runPrompt(conn, prompt)
+	return err
+}
+
+// open connects to the service and opens a plain session, which is as safe
+// as the bus connection secrets travel over.
+func (s *SecretService) open() (*dbus.Conn, dbus.ObjectPath, error) {
+	conn, err := s.conn()
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	call := conn.Object(secretsName, secretsPath).Call(secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant(""))
+	if err := call.Store(&output, &session); err != nil {
+		var dbusErr dbus.Error
+		if errors.As(err, &dbusErr) && dbusErr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" {
+			return nil, "", fmt.Errorf("%w: no Secret Service is running", ErrUnavailable)
+		}
+		return nil, "", fmt.Errorf("failed to open Secret Service session: %w", err)
+	}
+	return conn, session, nil
+}
+
+// find returns the item holding the secret of account, unlocking it if
+// necessary.
+func (s *SecretService) find(conn *dbus.Conn, account string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	call := conn.Object(secretsName, secretsPath).Call(secretServiceIface+".SearchItems", 0, attributes(account))
+	if err := call.Store(&unlocked, &locked); err != nil {
+		return "", fmt.Errorf("failed to search secrets: %w", err)
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", ErrNotFound
+	}
+
+	var prompt dbus.ObjectPath
+	call = conn.Object(secretsName, secretsPath).Call(secretServiceIface+".Unlock", 0, locked[:1])
+	if err := call.Store(&unlocked, &prompt); err != nil {
+		return "", fmt.Errorf("failed to unlock secret: %w", err)
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	result, err := runPrompt(conn, prompt)
+	if err != nil {
+		return "", err
+	}
+	if paths, ok := result.Value().([]dbus.ObjectPath); ok && len(paths) > 0 {
+		return paths[0], nil
+	}
+	return "", errors.New("secret stayed locked")
+}
+
+// runPrompt shows a prompt the service asked for, such as a keyring password
+// dialog, and waits for the user to complete it.
+func runPrompt(conn *dbus.Conn, prompt dbus.ObjectPath) (dbus.Variant, error) {
+	if prompt == noPrompt || prompt == "" {
+		return dbus.Variant{}, nil
+	}
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := conn.AddMatchSignal(match...); err != nil {
+		return dbus.Variant{}, err
+	}
+	defer conn.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	if err := conn.Object(secretsName, prompt).Call(secretPromptIface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, fmt.Errorf("failed to show prompt: %w", err)
+	}
+	for signal := range signals {
+		if signal.Path != prompt || signal.Name != secretPromptIface+".Completed" || len(signal.Body) != 2 {
+			continue
+		}
+		if dismissed, _ := signal.Body[0].(bool); dismissed {
+			return dbus.Variant{}, errors.New("prompt was dismissed")
+		}
+		result, _ := signal.Body[1].(dbus.Variant)
+		return result, nil
+	}
+	return dbus.Variant{}, errors.New("connection closed while waiting for prompt")
+}
+
+func closeSession(conn *dbus.Conn, session dbus.ObjectPath) {
+	conn.Object(secretsName, session).Call(secretSessionIface+".Close", 0)
+}
+
+func attributes(account string) map[string]string {
+	return map[string]string{"service": Service, "account": account}
+}
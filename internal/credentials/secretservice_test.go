@@ -0,0 +1,144 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/pzurek/lil/internal/dbustest"
+)
+
+// fakeSecretService implements the parts of the Secret Service API lil uses,
+// keeping unlocked items in memory. Calls arrive on the connection's
+// goroutines, hence the mutex.
+type fakeSecretService struct {
+	conn *dbus.Conn
+
+	mu    sync.Mutex
+	items map[dbus.ObjectPath]*fakeItem
+	next  int
+}
+
+// count returns the number of stored items.
+func (s *fakeSecretService) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+type fakeItem struct {
+	service    *fakeSecretService
+	path       dbus.ObjectPath
+	attributes map[string]string
+	value      []byte
+}
+
+func (s *fakeSecretService) OpenSession(algorithm string, input dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.NewError("org.freedesktop.DBus.Error.NotSupported", nil)
+	}
+	return dbus.MakeVariant(""), "/org/freedesktop/secrets/session/1", nil
+}
+
+func (s *fakeSecretService) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlocked := []dbus.ObjectPath{}
+	for path, item := range s.items {
+		if fmt.Sprint(item.attributes) == fmt.Sprint(attributes) {
+			unlocked = append(unlocked, path)
+		}
+	}
+	return unlocked, []dbus.ObjectPath{}, nil
+}
+
+func (s *fakeSecretService) CreateItem(properties map[string]dbus.Variant, value secret, replace bool) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	attributes, _ := properties[secretItemIface+".Attributes"].Value().(map[string]string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, item := range s.items {
+		if replace && fmt.Sprint(item.attributes) == fmt.Sprint(attributes) {
+			item.value = value.Value
+			return item.path, noPrompt, nil
+		}
+	}
+	s.next++
+	item := &fakeItem{
+		service:    s,
+		path:       dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/collection/login/%d", s.next)),
+		attributes: attributes,
+		value:      value.Value,
+	}
+	s.items[item.path] = item
+	if err := s.conn.Export(item, item.path, secretItemIface); err != nil {
+		return "", "", dbus.MakeFailedError(err)
+	}
+	return item.path, noPrompt, nil
+}
+
+func (i *fakeItem) GetSecret(session dbus.ObjectPath) (secret, *dbus.Error) {
+	i.service.mu.Lock()
+	defer i.service.mu.Unlock()
+	return secret{Session: session, Value: i.value, ContentType: "text/plain"}, nil
+}
+
+func (i *fakeItem) Delete() (dbus.ObjectPath, *dbus.Error) {
+	i.service.mu.Lock()
+	delete(i.service.items, i.path)
+	i.service.mu.Unlock()
+	i.service.conn.Export(nil, i.path, secretItemIface)
+	return noPrompt, nil
+}
+
+func TestSecretService(t *testing.T) {
+	address := dbustest.StartBus(t)
+
+	store := &SecretService{conn: func() (*dbus.Conn, error) { return dbustest.Connect(t, address), nil }}
+	if _, err := store.Get("acme"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Expected ErrUnavailable without a Secret Service, got %v", err)
+	}
+
+	conn := dbustest.Connect(t, address)
+	service := &fakeSecretService{conn: conn, items: map[dbus.ObjectPath]*fakeItem{}}
+	if err := conn.Export(service, secretsPath, secretServiceIface); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Export(service, defaultCollection, secretCollectionIface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := conn.RequestName(secretsName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("Failed to own %s: %v", secretsName, err)
+	}
+
+	if _, err := store.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := store.Set("acme", "lin_api_old"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Set("acme", "lin_api_new"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Set("globex", "lin_api_globex"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := service.count(); n != 2 {
+		t.Errorf("Expected setting a key twice to replace the item, got %d items", n)
+	}
+	if key, err := store.Get("acme"); err != nil || key != "lin_api_new" {
+		t.Errorf("Expected lin_api_new, got %q (%v)", key, err)
+	}
+
+	if err := store.Delete("acme"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := store.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+	}
+	if key, err := store.Get("globex"); err != nil || key != "lin_api_globex" {
+		t.Errorf("Expected the other account to be kept, got %q (%v)", key, err)
+	}
+}
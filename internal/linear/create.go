@@ -0,0 +1,124 @@
+package linear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pzurek/lil/internal/linear/schema"
+)
+
+// Team is a team issues can be created in, as returned by the GetTeams query.
+type Team = schema.GetTeamsTeamsTeamConnectionNodesTeam
+
+// Project is a project matched by name, as returned by the FindProjects query.
+type Project = schema.FindProjectsProjectsProjectConnectionNodesProject
+
+// Viewer is the authenticated user, as returned by the GetViewer query.
+type Viewer = schema.GetViewerViewerUser
+
+// CreatedIssue is the issue returned by the CreateIssue mutation.
+type CreatedIssue = schema.CreateIssueIssueCreateIssuePayloadIssue
+
+// IssueInput describes an issue to create. Only TeamID and Title are
+// required; an empty AssigneeID assigns the issue to the viewer.
+type IssueInput struct {
+	TeamID      string
+	Title       string
+	Description string
+	ProjectID   string
+	AssigneeID  string
+}
+
+// FetchTeams retrieves every team visible to the viewer.
+func (c *Client) FetchTeams(ctx context.Context) ([]Team, error) {
+	var teams []Team
+	after := ""
+
+	for page := 1; ; page++ {
+		resp, err := schema.GetTeams(ctx, c.gql, c.pageSize, after)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute GetTeams query (page %d): %w", page, classify(err))
+		}
+
+		if resp == nil {
+			return nil, errors.New("received nil response from GetTeams query")
+		}
+
+		connection := resp.Teams
+		teams = append(teams, connection.Nodes...)
+
+		if !connection.PageInfo.HasNextPage || page >= c.maxPages {
+			break
+		}
+		if connection.PageInfo.EndCursor == "" || connection.PageInfo.EndCursor == after {
+			return nil, fmt.Errorf("GetTeams page %d reported more results without advancing the cursor", page)
+		}
+		after = connection.PageInfo.EndCursor
+	}
+
+	return teams, nil
+}
+
+// FindTeam returns the team whose key or name matches ref, ignoring case.
+func FindTeam(teams []Team, ref string) (Team, bool) {
+	for _, team := range teams {
+		if strings.EqualFold(team.Key, ref) || strings.EqualFold(team.Name, ref) {
+			return team, true
+		}
+	}
+	return Team{}, false
+}
+
+// FindProject returns the project with the given name, ignoring case.
+func (c *Client) FindProject(ctx context.Context, name string) (Project, error) {
+	resp, err := schema.FindProjects(ctx, c.gql, name)
+	if err != nil {
+		return Project{}, fmt.Errorf("failed to execute FindProjects query: %w", classify(err))
+	}
+	if resp == nil || len(resp.Projects.Nodes) == 0 {
+		return Project{}, fmt.Errorf("no project named %q", name)
+	}
+	return resp.Projects.Nodes[0], nil
+}
+
+// Viewer returns the authenticated user.
+func (c *Client) Viewer(ctx context.Context) (Viewer, error) {
+	resp, err := schema.GetViewer(ctx, c.gql)
+	if err != nil {
+		return Viewer{}, fmt.Errorf("failed to execute GetViewer query: %w", classify(err))
+	}
+	if resp == nil || resp.Viewer.Id == "" {
+		return Viewer{}, errors.New("received no viewer from GetViewer query")
+	}
+	return resp.Viewer, nil
+}
+
+// CreateIssue creates an issue and returns it. Unless input names an
+// assignee, the issue is assigned to the viewer so it shows up in the menu.
+func (c *Client) CreateIssue(ctx context.Context, input IssueInput) (CreatedIssue, error) {
+	if input.TeamID == "" {
+		return CreatedIssue{}, errors.New("a team is required to create an issue")
+	}
+	if strings.TrimSpace(input.Title) == "" {
+		return CreatedIssue{}, errors.New("a title is required to create an issue")
+	}
+
+	if input.AssigneeID == "" {
+		viewer, err := c.Viewer(ctx)
+		if err != nil {
+			return CreatedIssue{}, err
+		}
+		input.AssigneeID = viewer.Id
+	}
+
+	resp, err := schema.CreateIssue(ctx, c.gql, input.TeamID, strings.TrimSpace(input.Title), input.Description, input.ProjectID, input.AssigneeID)
+	if err != nil {
+		return CreatedIssue{}, fmt.Errorf("failed to execute CreateIssue mutation: %w", classify(err))
+	}
+	if resp == nil || !resp.IssueCreate.Success {
+		return CreatedIssue{}, errors.New("linear did not create the issue")
+	}
+	return resp.IssueCreate.Issue, nil
+}
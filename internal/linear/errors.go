@@ -0,0 +1,123 @@
+package linear
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Kinds of failures, to be checked with errors.Is. ErrMissingAPIKey and
+// *RateLimitError complete the set.
+var (
+	// ErrUnauthorized means Linear rejected the API key or access token.
+	ErrUnauthorized = errors.New("linear rejected the credentials")
+	// ErrOffline means Linear could not be reached at all.
+	ErrOffline = errors.New("linear is unreachable")
+	// ErrSchemaMismatch means a response didn't fit the generated queries,
+	// usually because Linear changed its API.
+	ErrSchemaMismatch = errors.New("unexpected response from linear")
+)
+
+// GraphQLError is an error Linear reported in a GraphQL response.
+type GraphQLError struct {
+	Message string
+	// Code is the error's extensions.code, e.g. FORBIDDEN or INVALID_INPUT.
+	Code string
+	// Extensions holds everything Linear attached to the error.
+	Extensions map[string]interface{}
+}
+
+func (e *GraphQLError) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+// classify maps an error returned by genqlient or the transports onto the
+// package's error kinds, keeping the original message.
+func classify(err error) error {
+	var limitErr *RateLimitError
+	switch {
+	case err == nil,
+		errors.Is(err, ErrMissingAPIKey),
+		errors.Is(err, ErrUnauthorized),
+		errors.As(err, &limitErr),
+		errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %v", ErrOffline, err)
+	}
+
+	var httpErr *graphql.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusUnauthorized:
+			return fmt.Errorf("%w: %s", ErrUnauthorized, gqlMessage(httpErr.Response.Errors, httpErr.StatusCode))
+		case httpErr.StatusCode >= 500:
+			return fmt.Errorf("linear returned HTTP %d: %s", httpErr.StatusCode, gqlMessage(httpErr.Response.Errors, httpErr.StatusCode))
+		case len(httpErr.Response.Errors) > 0 && httpErr.Response.Errors[0].Extensions != nil:
+			return classifyGraphQL(httpErr.Response.Errors)
+		}
+		return fmt.Errorf("linear returned HTTP %d: %s", httpErr.StatusCode, gqlMessage(httpErr.Response.Errors, httpErr.StatusCode))
+	}
+
+	var gqlErrs gqlerror.List
+	if errors.As(err, &gqlErrs) && len(gqlErrs) > 0 {
+		return classifyGraphQL(gqlErrs)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return fmt.Errorf("%w: %v", ErrSchemaMismatch, err)
+	}
+
+	// Transport errors come wrapped in a *url.Error, which is a net.Error
+	// itself, so look at what it wraps.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		var netErr net.Error
+		if errors.As(urlErr.Err, &netErr) || errors.Is(urlErr.Err, io.EOF) || errors.Is(urlErr.Err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: %v", ErrOffline, err)
+		}
+	}
+	return err
+}
+
+// classifyGraphQL turns the first error of a GraphQL response into a
+// *GraphQLError, marked as ErrUnauthorized or ErrSchemaMismatch where its
+// code says so.
+func classifyGraphQL(errs gqlerror.List) error {
+	first := errs[0]
+	gqlErr := &GraphQLError{Message: first.Message, Extensions: first.Extensions}
+	gqlErr.Code, _ = first.Extensions["code"].(string)
+	if len(errs) > 1 {
+		gqlErr.Message = fmt.Sprintf("%s (and %d more errors)", first.Message, len(errs)-1)
+	}
+
+	switch {
+	case gqlErr.Code == "AUTHENTICATION_ERROR":
+		return fmt.Errorf("%w: %w", ErrUnauthorized, gqlErr)
+	case gqlErr.Code == "GRAPHQL_VALIDATION_FAILED", strings.HasPrefix(first.Message, "Cannot query field"):
+		return fmt.Errorf("%w: %w", ErrSchemaMismatch, gqlErr)
+	}
+	return gqlErr
+}
+
+// gqlMessage returns the first message of errs, or the status text.
+func gqlMessage(errs gqlerror.List, status int) string {
+	if len(errs) > 0 && errs[0].Message != "" {
+		return errs[0].Message
+	}
+	return http.StatusText(status)
+}
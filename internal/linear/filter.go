@@ -0,0 +1,88 @@
+package linear
+
+import "fmt"
+
+// Cycles an IssueFilter can select.
+const (
+	CycleCurrent  = "current"
+	CycleNext     = "next"
+	CyclePrevious = "previous"
+	CycleNone     = "none"
+)
+
+// IssueFilter narrows down the assigned issues fetched from Linear. Zero
+// fields match every issue. Completed and canceled issues never match.
+type IssueFilter struct {
+	// Teams are team keys, e.g. ENG.
+	Teams []string
+	// Projects and Labels are names. Issues with any of the labels match.
+	Projects []string
+	Labels   []string
+	// Priorities run from 1 (urgent) to 4 (low), with 0 meaning none.
+	Priorities []int
+	// Cycle is one of the Cycle constants.
+	Cycle string
+	// States and ExcludeStates are workflow state types, e.g. started.
+	States        []string
+	ExcludeStates []string
+	// DueWithinDays matches issues due at most that many days from today,
+	// including overdue ones. Zero matches any due date or none.
+	DueWithinDays int
+}
+
+// variable compiles f into the IssueFilter input of the GetAssignedIssues
+// query.
+func (f IssueFilter) variable() map[string]interface{} {
+	filter := f.attributes()
+	types := map[string]interface{}{"nin": append([]string{"completed", "canceled"}, f.ExcludeStates...)}
+	if len(f.States) > 0 {
+		types["in"] = f.States
+	}
+	filter["state"] = map[string]interface{}{"type": types}
+	return filter
+}
+
+// attributes compiles everything in f but the state types. Incremental
+// fetches leave those out so they still see issues that left them.
+func (f IssueFilter) attributes() map[string]interface{} {
+	filter := map[string]interface{}{}
+	if len(f.Teams) > 0 {
+		filter["team"] = anyOf("key", f.Teams)
+	}
+	if len(f.Projects) > 0 {
+		filter["project"] = anyOf("name", f.Projects)
+	}
+	if len(f.Labels) > 0 {
+		filter["labels"] = map[string]interface{}{"some": anyOf("name", f.Labels)}
+	}
+	if len(f.Priorities) > 0 {
+		filter["priority"] = map[string]interface{}{"in": f.Priorities}
+	}
+	switch f.Cycle {
+	case CycleCurrent:
+		filter["cycle"] = map[string]interface{}{"isActive": map[string]interface{}{"eq": true}}
+	case CycleNext:
+		filter["cycle"] = map[string]interface{}{"isNext": map[string]interface{}{"eq": true}}
+	case CyclePrevious:
+		filter["cycle"] = map[string]interface{}{"isPrevious": map[string]interface{}{"eq": true}}
+	case CycleNone:
+		filter["cycle"] = map[string]interface{}{"null": true}
+	}
+	if f.DueWithinDays > 0 {
+		// Linear resolves durations relative to today
+		filter["dueDate"] = map[string]interface{}{"lte": fmt.Sprintf("P%dD", f.DueWithinDays)}
+	}
+	return filter
+}
+
+// anyOf matches entities whose field equals one of values, ignoring case.
+func anyOf(field string, values []string) map[string]interface{} {
+	if len(values) == 1 {
+		return map[string]interface{}{field: map[string]interface{}{"eqIgnoreCase": values[0]}}
+	}
+	var or []interface{}
+	for _, value := range values {
+		or = append(or, anyOf(field, []string{value}))
+	}
+	return map[string]interface{}{"or": or}
+}
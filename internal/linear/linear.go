@@ -4,74 +4,317 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/Khan/genqlient/graphql"
 
 	"github.com/pzurek/lil/internal/linear/schema"
 )
 
+// DefaultEndpoint is Linear's public GraphQL API.
+const DefaultEndpoint = "https://api.linear.app/graphql"
+
+// DefaultTimeout bounds each attempt at an HTTP request to Linear.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultPageSize is the number of issues requested per page.
+const DefaultPageSize = 50
+
+// DefaultMaxPages caps how many pages FetchAssignedIssues will walk so a
+// misbehaving server can't keep us paginating forever.
+const DefaultMaxPages = 20
+
+// Issue is an assigned issue as returned by the GetAssignedIssues query.
+type Issue = schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue
+
+// Label is one of an Issue's labels.
+type Label = schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel
+
+// ErrMissingAPIKey is returned when the configured key source yields no key.
+var ErrMissingAPIKey = errors.New("linear API key not set")
+
+// APIKeySource returns the API key to authenticate a request with. It is
+// consulted on every request so rotated keys are picked up without rebuilding
+// the client.
+type APIKeySource func() (string, error)
+
+// EnvAPIKey returns an APIKeySource that reads the key from the named
+// environment variable.
+func EnvAPIKey(name string) APIKeySource {
+	return func() (string, error) {
+		key := os.Getenv(name)
+		if key == "" {
+			return "", fmt.Errorf("%w: %s environment variable is empty", ErrMissingAPIKey, name)
+		}
+		return key, nil
+	}
+}
+
+// StaticAPIKey returns an APIKeySource that always yields key.
+func StaticAPIKey(key string) APIKeySource {
+	return func() (string, error) {
+		if key == "" {
+			return "", ErrMissingAPIKey
+		}
+		return key, nil
+	}
+}
+
+// ErrNoToken is returned by a TokenSource that has no OAuth token to offer,
+// making the client authenticate with its API key instead.
+var ErrNoToken = errors.New("no OAuth token")
+
+// TokenSource supplies OAuth access tokens, which are sent as Bearer tokens.
+type TokenSource interface {
+	// Token returns the access token to authenticate a request with.
+	Token(ctx context.Context) (string, error)
+	// Refresh returns a new access token after Linear rejected the given one.
+	Refresh(ctx context.Context, rejected string) (string, error)
+}
+
 // authTransport is a custom transport that adds the Authorization header correctly.
 type authTransport struct {
-	apiKey string
-	base   http.RoundTripper
+	apiKey    APIKeySource
+	tokens    TokenSource
+	userAgent string
+	base      http.RoundTripper
 }
 
-// RoundTrip adds the Authorization header to the request without the "Bearer" prefix.
+// RoundTrip authenticates the request with an OAuth access token if the
+// token source has one, and with the API key otherwise.
 func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	// Use the original request to avoid modifying it globally if base transport reuses it
+	if t.tokens != nil {
+		token, err := t.tokens.Token(req.Context())
+		if err == nil {
+			return t.roundTripBearer(req, token)
+		}
+		if !errors.Is(err, ErrNoToken) {
+			return nil, err
+		}
+	}
+
+	apiKey, err := t.apiKey()
+	if err != nil {
+		return nil, err
+	}
+	// API keys go into the Authorization header without the "Bearer" prefix
+	return t.base.RoundTrip(t.authorize(req, apiKey))
+}
+
+// roundTripBearer sends the request with an access token. If Linear rejects
+// the token, it is refreshed and the request is sent once more.
+func (t *authTransport) roundTripBearer(req *http.Request, token string) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(t.authorize(req, "Bearer "+token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil // can't replay the body
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	token, err = t.tokens.Refresh(req.Context(), token)
+	if err != nil {
+		if errors.Is(err, ErrMissingAPIKey) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: refreshing the access token failed: %w", ErrUnauthorized, err)
+	}
+	retry := req
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+	return t.base.RoundTrip(t.authorize(retry, "Bearer "+token))
+}
+
+// authorize returns a copy of req with the Authorization and other headers
+// set, leaving the original untouched as RoundTrippers must.
+func (t *authTransport) authorize(req *http.Request, authorization string) *http.Request {
 	reqClone := req.Clone(req.Context())
-	reqClone.Header.Set("Authorization", t.apiKey)          // Set header directly
+	reqClone.Header.Set("Authorization", authorization)
 	reqClone.Header.Set("Content-Type", "application/json") // Ensure content type is set
-	// Use the base transport (e.g., http.DefaultTransport) to execute the request
-	return t.base.RoundTrip(reqClone)
+	if t.userAgent != "" {
+		reqClone.Header.Set("User-Agent", t.userAgent)
+	}
+	return reqClone
 }
 
-// GetClient creates and returns a new GraphQL client configured for Linear.
-func GetClient() (graphql.Client, error) {
-	apiKey := os.Getenv("LINEAR_API_KEY")
-	if apiKey == "" {
-		return nil, errors.New("LINEAR_API_KEY environment variable not set")
+// Client talks to the Linear GraphQL API. It is safe for concurrent use and
+// reuses its underlying HTTP connections across calls.
+type Client struct {
+	endpoint  string
+	apiKey    APIKeySource
+	tokens    TokenSource
+	base      http.RoundTripper
+	timeout   time.Duration
+	userAgent string
+	pageSize  int
+	maxPages  int
+	retries   int
+
+	limits *retryTransport
+	gql    graphql.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithEndpoint points the client at a different GraphQL URL, such as a proxy
+// or a local fake server.
+func WithEndpoint(url string) Option {
+	return func(c *Client) { c.endpoint = url }
+}
+
+// WithAPIKeySource sets where the client gets its API key from.
+func WithAPIKeySource(src APIKeySource) Option {
+	return func(c *Client) { c.apiKey = src }
+}
+
+// WithTokenSource authenticates requests with OAuth access tokens from src,
+// falling back to the API key while src returns ErrNoToken.
+func WithTokenSource(src TokenSource) Option {
+	return func(c *Client) { c.tokens = src }
+}
+
+// WithAPIKey authenticates every request with a fixed key.
+func WithAPIKey(key string) Option {
+	return WithAPIKeySource(StaticAPIKey(key))
+}
+
+// WithTransport sets the base RoundTripper that authenticated requests are
+// sent through. Defaults to http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.base = rt }
+}
+
+// WithTimeout bounds each attempt at an HTTP request; a retry gets the full
+// timeout again. Zero disables the timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithPageSize sets how many issues are requested per page.
+func WithPageSize(n int) Option {
+	return func(c *Client) { c.pageSize = n }
+}
+
+// WithMaxPages caps how many pages a paginated fetch will walk.
+func WithMaxPages(n int) Option {
+	return func(c *Client) { c.maxPages = n }
+}
+
+// WithMaxRetries sets how often a query that hit a rate limit or a server
+// error is retried. Zero disables retries.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) { c.retries = n }
+}
+
+// NewClient creates a Linear client. Without options it talks to
+// DefaultEndpoint using the LINEAR_API_KEY environment variable.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		endpoint: DefaultEndpoint,
+		apiKey:   EnvAPIKey("LINEAR_API_KEY"),
+		base:     http.DefaultTransport,
+		timeout:  DefaultTimeout,
+		pageSize: DefaultPageSize,
+		maxPages: DefaultMaxPages,
+		retries:  DefaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.pageSize <= 0 {
+		c.pageSize = DefaultPageSize
+	}
+	if c.maxPages <= 0 {
+		c.maxPages = DefaultMaxPages
 	}
 
-	// Create the custom transport
-	authTransport := &authTransport{
-		apiKey: apiKey,
-		base:   http.DefaultTransport, // Use default transport as base
+	c.limits = &retryTransport{
+		base:       c.base,
+		maxRetries: max(c.retries, 0),
+		timeout:    c.timeout,
+		now:        time.Now,
+		sleep:      sleepContext,
 	}
 
 	// Create an http.Client using the custom transport
 	httpClient := &http.Client{
-		Transport: authTransport,
+		Transport: &authTransport{
+			apiKey:    c.apiKey,
+			tokens:    c.tokens,
+			userAgent: c.userAgent,
+			base:      c.limits,
+		},
 	}
 
 	// Create the genqlient client using the custom http.Client
-	client := graphql.NewClient("https://api.linear.app/graphql", httpClient)
-	return client, nil
+	c.gql = graphql.NewClient(c.endpoint, httpClient)
+	return c
 }
 
-// FetchAssignedIssues retrieves the assigned issues for the current user.
-func FetchAssignedIssues(ctx context.Context) ([]schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue, error) {
-	client, err := GetClient()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get linear client: %w", err)
-	}
+// RateLimit returns Linear's rate limit budget as reported with the last
+// response.
+func (c *Client) RateLimit() RateLimit {
+	return c.limits.RateLimit()
+}
 
-	resp, err := schema.GetAssignedIssues(ctx, client)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute GetAssignedIssues query: %w", err)
-	}
+// FetchAssignedIssues retrieves the active assigned issues for the current
+// user, following pagination until every page has been read or the page cap
+// is reached.
+func (c *Client) FetchAssignedIssues(ctx context.Context) ([]Issue, error) {
+	return c.FetchMatchingIssues(ctx, IssueFilter{})
+}
 
-	if resp == nil {
-		return nil, errors.New("received nil response from GetAssignedIssues query")
-	}
+// FetchMatchingIssues is FetchAssignedIssues for the issues matching filter.
+func (c *Client) FetchMatchingIssues(ctx context.Context, filter IssueFilter) ([]Issue, error) {
+	return c.fetchIssues(ctx, filter.variable())
+}
 
-	if resp.Viewer.AssignedIssues.Nodes == nil {
-		return []schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue{}, nil
-	}
+// fetchIssues retrieves the assigned issues matching filter, page by page.
+func (c *Client) fetchIssues(ctx context.Context, filter map[string]interface{}) ([]Issue, error) {
+	issues := []Issue{}
+	after := ""
 
-	return resp.Viewer.AssignedIssues.Nodes, nil
-}
+	for page := 1; ; page++ {
+		resp, err := schema.GetAssignedIssues(ctx, c.gql, c.pageSize, after, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute GetAssignedIssues query (page %d): %w", page, classify(err))
+		}
 
-// Note: All other functions, structs, constants, authTransport removed.
+		if resp == nil {
+			return nil, errors.New("received nil response from GetAssignedIssues query")
+		}
+
+		connection := resp.Viewer.AssignedIssues
+		issues = append(issues, connection.Nodes...)
+
+		if !connection.PageInfo.HasNextPage {
+			return issues, nil
+		}
+		if connection.PageInfo.EndCursor == "" || connection.PageInfo.EndCursor == after {
+			return nil, fmt.Errorf("GetAssignedIssues page %d reported more results without advancing the cursor", page)
+		}
+		if page >= c.maxPages {
+			log.Printf("Warning: stopped after %d pages (%d issues); more assigned issues are available", page, len(issues))
+			return issues, nil
+		}
+		after = connection.PageInfo.EndCursor
+	}
+}
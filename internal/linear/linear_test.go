@@ -0,0 +1,848 @@
+package linear
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLinear is a minimal stand-in for the Linear GraphQL endpoint that serves
+// assignedIssues in pages.
+type fakeLinear struct {
+	t        *testing.T
+	total    int  // number of issues to serve
+	endless  bool // always report another page
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	headers  []http.Header
+}
+
+func (f *fakeLinear) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("Failed to decode request: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, req.Variables)
+	f.headers = append(f.headers, r.Header.Clone())
+	f.mu.Unlock()
+
+	first := int(req.Variables["first"].(float64))
+	start := 0
+	if after, ok := req.Variables["after"].(string); ok {
+		start, _ = strconv.Atoi(after)
+	}
+
+	nodes := []map[string]interface{}{}
+	end := start
+	for ; end < start+first && (end < f.total || f.endless); end++ {
+		nodes = append(nodes, map[string]interface{}{
+			"id":         fmt.Sprintf("id-%d", end),
+			"identifier": fmt.Sprintf("ENG-%d", end),
+			"title":      fmt.Sprintf("Issue %d", end),
+		})
+	}
+
+	resp := map[string]interface{}{
+		"data": map[string]interface{}{
+			"viewer": map[string]interface{}{
+				"assignedIssues": map[string]interface{}{
+					"pageInfo": map[string]interface{}{
+						"hasNextPage": f.endless || end < f.total,
+						"endCursor":   strconv.Itoa(end),
+					},
+					"nodes": nodes,
+				},
+			},
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func TestFetchAssignedIssuesPagination(t *testing.T) {
+	tests := []struct {
+		name          string
+		total         int
+		endless       bool
+		pageSize      int
+		maxPages      int
+		expectIssues  int
+		expectQueries int
+	}{
+		{name: "Single page", total: 3, pageSize: 5, maxPages: 10, expectIssues: 3, expectQueries: 1},
+		{name: "Exact page boundary", total: 10, pageSize: 5, maxPages: 10, expectIssues: 10, expectQueries: 2},
+		{name: "Multiple pages", total: 12, pageSize: 5, maxPages: 10, expectIssues: 12, expectQueries: 3},
+		{name: "Empty", total: 0, pageSize: 5, maxPages: 10, expectIssues: 0, expectQueries: 1},
+		{name: "Hard cap", endless: true, pageSize: 5, maxPages: 4, expectIssues: 20, expectQueries: 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeLinear{t: t, total: tc.total, endless: tc.endless}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			client := NewClient(
+				WithEndpoint(server.URL),
+				WithAPIKey("test-key"),
+				WithTransport(server.Client().Transport),
+				WithPageSize(tc.pageSize),
+				WithMaxPages(tc.maxPages),
+			)
+			issues, err := client.FetchAssignedIssues(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(issues) != tc.expectIssues {
+				t.Errorf("Expected %d issues, got %d", tc.expectIssues, len(issues))
+			}
+			if len(fake.requests) != tc.expectQueries {
+				t.Errorf("Expected %d queries, got %d", tc.expectQueries, len(fake.requests))
+			}
+			if _, ok := fake.requests[0]["after"]; ok {
+				t.Errorf("Expected first query to omit the after cursor, got %v", fake.requests[0]["after"])
+			}
+			for i, issue := range issues {
+				if want := fmt.Sprintf("ENG-%d", i); issue.Identifier != want {
+					t.Errorf("Expected issue %d to be %s, got %s", i, want, issue.Identifier)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestFetchAssignedIssuesStuckCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"viewer":{"assignedIssues":{"pageInfo":{"hasNextPage":true,"endCursor":""},"nodes":[]}}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+	if _, err := client.FetchAssignedIssues(context.Background()); err == nil {
+		t.Error("Expected an error when the cursor does not advance")
+	}
+}
+
+func TestClientSendsCredentialsAndUserAgent(t *testing.T) {
+	fake := &fakeLinear{t: t, total: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	key := "first-key"
+	client := NewClient(
+		WithEndpoint(server.URL),
+		WithAPIKeySource(func() (string, error) { return key, nil }),
+		WithUserAgent("lil/test"),
+	)
+
+	for _, want := range []string{"first-key", "rotated-key"} {
+		key = want
+		if _, err := client.FetchAssignedIssues(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		h := fake.headers[len(fake.headers)-1]
+		if got := h.Get("Authorization"); got != want {
+			t.Errorf("Expected Authorization %q, got %q", want, got)
+		}
+		if got := h.Get("User-Agent"); got != "lil/test" {
+			t.Errorf("Expected User-Agent lil/test, got %q", got)
+		}
+	}
+}
+
+func TestClientMissingAPIKey(t *testing.T) {
+	fake := &fakeLinear{t: t, total: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	t.Setenv("LIL_TEST_EMPTY_KEY", "")
+	client := NewClient(WithEndpoint(server.URL), WithAPIKeySource(EnvAPIKey("LIL_TEST_EMPTY_KEY")))
+
+	_, err := client.FetchAssignedIssues(context.Background())
+	if !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Expected ErrMissingAPIKey, got %v", err)
+	}
+	if len(fake.requests) != 0 {
+		t.Errorf("Expected no requests to reach the server, got %d", len(fake.requests))
+	}
+}
+
+// fakeTokens is a TokenSource that hands out "stale" until refreshed.
+type fakeTokens struct {
+	token     string
+	err       error
+	refreshed []string
+}
+
+func (f *fakeTokens) Token(ctx context.Context) (string, error) {
+	return f.token, f.err
+}
+
+func (f *fakeTokens) Refresh(ctx context.Context, rejected string) (string, error) {
+	f.refreshed = append(f.refreshed, rejected)
+	f.token = "fresh"
+	return f.token, nil
+}
+
+func TestClientRefreshesRejectedToken(t *testing.T) {
+	fake := &fakeLinear{t: t, total: 1}
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	tokens := &fakeTokens{token: "stale"}
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("unused-key"), WithTokenSource(tokens))
+	for range 2 {
+		if _, err := client.FetchAssignedIssues(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := []string{"Bearer stale", "Bearer fresh", "Bearer fresh"}
+	if fmt.Sprint(authorizations) != fmt.Sprint(expected) {
+		t.Errorf("Expected authorizations %v, got %v", expected, authorizations)
+	}
+	if len(tokens.refreshed) != 1 || tokens.refreshed[0] != "stale" {
+		t.Errorf("Expected the stale token to be refreshed once, got %v", tokens.refreshed)
+	}
+	if len(fake.requests) != 2 || fake.requests[0]["first"] == nil {
+		t.Errorf("Expected the retried request to carry its body, got %v", fake.requests)
+	}
+}
+
+func TestClientFallsBackToAPIKey(t *testing.T) {
+	fake := &fakeLinear{t: t, total: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"), WithTokenSource(&fakeTokens{err: ErrNoToken}))
+	if _, err := client.FetchAssignedIssues(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.headers[0].Get("Authorization"); got != "test-key" {
+		t.Errorf("Expected the API key without a token, got %q", got)
+	}
+}
+
+func TestFetchWorkflowStates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"workflowStates":{"pageInfo":{"hasNextPage":false},"nodes":[
+			{"id":"done","name":"Done","type":"completed","position":0,"team":{"id":"eng"}},
+			{"id":"review","name":"In Review","type":"started","position":2,"team":{"id":"eng"}},
+			{"id":"todo","name":"Todo","type":"unstarted","position":0,"team":{"id":"eng"}},
+			{"id":"progress","name":"In Progress","type":"started","position":1,"team":{"id":"eng"}},
+			{"id":"ops-todo","name":"Todo","type":"unstarted","position":0,"team":{"id":"ops"}}
+		]}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+	states, err := client.FetchWorkflowStates(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	order := []string{}
+	for _, state := range states["eng"] {
+		order = append(order, state.Id)
+	}
+	if got := fmt.Sprint(order); got != "[todo progress review done]" {
+		t.Errorf("Expected eng states in workflow order, got %s", got)
+	}
+	if len(states["ops"]) != 1 {
+		t.Errorf("Expected 1 ops state, got %d", len(states["ops"]))
+	}
+}
+
+func TestUpdateIssueState(t *testing.T) {
+	var variables map[string]interface{}
+	success := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		variables = req.Variables
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":{"issueUpdate":{"success":%t,"issue":{"id":"issue-1","state":{"id":"done","type":"completed"}}}}}`, success)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+	if err := client.UpdateIssueState(context.Background(), "issue-1", "done"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if variables["id"] != "issue-1" || variables["stateId"] != "done" {
+		t.Errorf("Unexpected mutation variables %v", variables)
+	}
+
+	success = false
+	if err := client.UpdateIssueState(context.Background(), "issue-1", "done"); err == nil {
+		t.Error("Expected an error when Linear reports failure")
+	}
+}
+
+func TestCreateIssue(t *testing.T) {
+	var operations []string
+	var variables map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		operations = append(operations, req.OperationName)
+		w.Header().Set("Content-Type", "application/json")
+		switch req.OperationName {
+		case "GetViewer":
+			fmt.Fprint(w, `{"data":{"viewer":{"id":"me","name":"Me"}}}`)
+		case "CreateIssue":
+			variables = req.Variables
+			fmt.Fprint(w, `{"data":{"issueCreate":{"success":true,"issue":{"id":"issue-1","identifier":"ENG-7","title":"Write docs","url":"https://linear.app/acme/issue/ENG-7"}}}}`)
+		default:
+			t.Errorf("Unexpected operation %s", req.OperationName)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+	issue, err := client.CreateIssue(context.Background(), IssueInput{TeamID: "eng", Title: " Write docs "})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if issue.Identifier != "ENG-7" {
+		t.Errorf("Expected ENG-7, got %s", issue.Identifier)
+	}
+	if fmt.Sprint(operations) != "[GetViewer CreateIssue]" {
+		t.Errorf("Expected the viewer to be looked up before creating, got %v", operations)
+	}
+	if variables["teamId"] != "eng" || variables["title"] != "Write docs" || variables["assigneeId"] != "me" {
+		t.Errorf("Unexpected mutation variables %v", variables)
+	}
+	for _, name := range []string{"description", "projectId"} {
+		if _, ok := variables[name]; ok {
+			t.Errorf("Expected empty %s to be omitted, got %v", name, variables[name])
+		}
+	}
+
+	operations = nil
+	if _, err := client.CreateIssue(context.Background(), IssueInput{TeamID: "eng", Title: "Pair", AssigneeID: "someone", ProjectID: "p1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(operations) != "[CreateIssue]" {
+		t.Errorf("Expected an explicit assignee to skip the viewer lookup, got %v", operations)
+	}
+	if variables["assigneeId"] != "someone" || variables["projectId"] != "p1" {
+		t.Errorf("Unexpected mutation variables %v", variables)
+	}
+
+	operations = nil
+	if _, err := client.CreateIssue(context.Background(), IssueInput{TeamID: "eng", Title: "  "}); err == nil {
+		t.Error("Expected an error for an empty title")
+	}
+	if len(operations) != 0 {
+		t.Errorf("Expected no requests for an invalid issue, got %v", operations)
+	}
+}
+
+func TestFindTeam(t *testing.T) {
+	teams := []Team{{Id: "1", Key: "ENG", Name: "Engineering"}, {Id: "2", Key: "OPS", Name: "Operations"}}
+	tests := []struct {
+		ref    string
+		found  bool
+		expect string
+	}{
+		{ref: "ENG", found: true, expect: "1"},
+		{ref: "ops", found: true, expect: "2"},
+		{ref: "engineering", found: true, expect: "1"},
+		{ref: "DES", found: false},
+	}
+
+	for _, tc := range tests {
+		team, ok := FindTeam(teams, tc.ref)
+		if ok != tc.found || team.Id != tc.expect {
+			t.Errorf("FindTeam(%q): expected (%q, %v), got (%q, %v)", tc.ref, tc.expect, tc.found, team.Id, ok)
+		}
+	}
+}
+
+func TestFetchInbox(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"notificationsUnreadCount":2,"notifications":{"nodes":[
+			{"__typename":"IssueNotification","id":"n1","type":"issueComment","title":"ENG-1 Ship it","subtitle":"Ada commented","url":"https://linear.app/acme/issue/ENG-1"},
+			{"__typename":"IssueNotification","id":"n2","type":"issueAssignedToYou","title":"ENG-2 Later","snoozedUntilAt":"2999-01-01T00:00:00Z"},
+			{"__typename":"ProjectNotification","id":"n3","type":"projectUpdateCreated","title":"Rocket","readAt":"2024-01-01T00:00:00Z","snoozedUntilAt":"2000-01-01T00:00:00Z"}
+		]}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+	inbox, err := client.FetchInbox(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if inbox.UnreadCount != 2 {
+		t.Errorf("Expected 2 unread, got %d", inbox.UnreadCount)
+	}
+	ids := []string{}
+	for _, notification := range inbox.Notifications {
+		ids = append(ids, notification.Id)
+	}
+	if got := fmt.Sprint(ids); got != "[n1 n3]" {
+		t.Errorf("Expected snoozed notifications to be skipped, got %s", got)
+	}
+}
+
+func TestNotificationActions(t *testing.T) {
+	var operations []string
+	var variables []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		operations = append(operations, req.OperationName)
+		variables = append(variables, req.Variables)
+		w.Header().Set("Content-Type", "application/json")
+		switch req.OperationName {
+		case "MarkNotificationRead":
+			fmt.Fprint(w, `{"data":{"notificationUpdate":{"success":true}}}`)
+		case "ArchiveNotification":
+			fmt.Fprint(w, `{"data":{"notificationArchive":{"success":false}}}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+	if err := client.MarkNotificationRead(context.Background(), "n1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if variables[0]["id"] != "n1" {
+		t.Errorf("Unexpected mutation variables %v", variables[0])
+	}
+	if readAt, _ := variables[0]["readAt"].(string); readAt == "" {
+		t.Error("Expected readAt to be set")
+	}
+
+	if err := client.ArchiveNotification(context.Background(), "n1"); err == nil {
+		t.Error("Expected an error when Linear reports failure")
+	}
+	if fmt.Sprint(operations) != "[MarkNotificationRead ArchiveNotification]" {
+		t.Errorf("Unexpected operations %v", operations)
+	}
+}
+
+// newRateLimitedClient returns a client for server that records retry waits
+// instead of sleeping.
+func newRateLimitedClient(server *httptest.Server, now time.Time) (*Client, *[]time.Duration) {
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+	waits := &[]time.Duration{}
+	client.limits.now = func() time.Time { return now }
+	client.limits.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return client, waits
+}
+
+func TestClientRetries(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	reset := strconv.FormatInt(now.Add(20*time.Minute).UnixMilli(), 10)
+	ok := func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Requests-Limit", "1500")
+		w.Header().Set("X-RateLimit-Requests-Remaining", "1200")
+		w.Header().Set("X-RateLimit-Requests-Reset", reset)
+		w.Header().Set("X-RateLimit-Complexity-Limit", "250000")
+		w.Header().Set("X-RateLimit-Complexity-Remaining", "10000")
+		w.Header().Set("X-RateLimit-Complexity-Reset", reset)
+		fmt.Fprint(w, `{"data":{"viewer":{"assignedIssues":{"pageInfo":{"hasNextPage":false},"nodes":[]}}}}`)
+	}
+
+	tests := []struct {
+		name      string
+		responses []func(w http.ResponseWriter)
+		mutation  bool
+		requests  int
+		waits     []time.Duration
+		limited   bool
+	}{
+		{
+			name: "Retry-After in seconds",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "2")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+				ok,
+			},
+			requests: 2,
+			waits:    []time.Duration{2 * time.Second},
+		},
+		{
+			name: "Retry-After as a date",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", now.Add(5*time.Second).Format(http.TimeFormat))
+					w.WriteHeader(http.StatusServiceUnavailable)
+				},
+				ok,
+			},
+			requests: 2,
+			waits:    []time.Duration{5 * time.Second},
+		},
+		{
+			name: "GraphQL rate limit error",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprint(w, `{"errors":[{"message":"Rate limit exceeded","extensions":{"code":"RATELIMITED"}}]}`)
+				},
+				ok,
+			},
+			requests: 2,
+			waits:    []time.Duration{time.Second},
+		},
+		{
+			name: "Long wait gives up",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "900")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+			},
+			requests: 1,
+			limited:  true,
+		},
+		{
+			name: "Mutations are not retried",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+			},
+			mutation: true,
+			requests: 1,
+			limited:  true,
+		},
+		{
+			name: "Server errors give up after the retries",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			},
+			requests: DefaultMaxRetries + 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !bytes.Contains(body, []byte(`"query"`)) {
+					t.Errorf("Expected every attempt to carry the query, got %s", body)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				tc.responses[min(requests, len(tc.responses)-1)](w)
+				requests++
+			}))
+			defer server.Close()
+			client, waits := newRateLimitedClient(server, now)
+
+			var err error
+			if tc.mutation {
+				err = client.ArchiveNotification(context.Background(), "n1")
+			} else {
+				_, err = client.FetchAssignedIssues(context.Background())
+			}
+
+			if requests != tc.requests {
+				t.Errorf("Expected %d requests, got %d", tc.requests, requests)
+			}
+			if tc.waits != nil && fmt.Sprint(*waits) != fmt.Sprint(tc.waits) {
+				t.Errorf("Expected waits %v, got %v", tc.waits, *waits)
+			}
+			var limitErr *RateLimitError
+			if tc.limited != errors.As(err, &limitErr) {
+				t.Fatalf("Expected rate limit error %v, got %v", tc.limited, err)
+			}
+			if tc.limited && client.RateLimit().HoldUntil(now).IsZero() {
+				t.Error("Expected the client to hold off while rate limited")
+			}
+			if len(tc.responses) > 1 && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientTimesOutEachAttempt(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		select {
+		case <-time.After(150 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"viewer":{"assignedIssues":{"pageInfo":{"hasNextPage":false},"nodes":[]}}}}`)
+	}))
+	defer server.Close()
+
+	// Three attempts take longer than the timeout, but each fits in it
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"), WithTimeout(300*time.Millisecond))
+	client.limits.sleep = func(context.Context, time.Duration) error { return nil }
+	if _, err := client.FetchAssignedIssues(context.Background()); err != nil {
+		t.Fatalf("Unexpected error after %d requests: %v", requests, err)
+	}
+
+	// A single attempt that takes too long still times out
+	client = NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"), WithTimeout(50*time.Millisecond))
+	if _, err := client.FetchAssignedIssues(context.Background()); !errors.Is(err, ErrOffline) || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("Expected the attempt to time out, got %v", err)
+	}
+}
+
+func TestRateLimitBudget(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	remaining := "1200"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Requests-Limit", "1500")
+		w.Header().Set("X-RateLimit-Requests-Remaining", remaining)
+		w.Header().Set("X-RateLimit-Requests-Reset", strconv.FormatInt(now.Add(30*time.Minute).UnixMilli(), 10))
+		fmt.Fprint(w, `{"data":{"viewer":{"assignedIssues":{"pageInfo":{"hasNextPage":false},"nodes":[]}}}}`)
+	}))
+	defer server.Close()
+	client, _ := newRateLimitedClient(server, now)
+
+	if _, err := client.FetchAssignedIssues(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	limit := client.RateLimit()
+	if limit.Requests.Limit != 1500 || limit.Requests.Remaining != 1200 || !limit.Requests.Reset.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("Unexpected request budget %+v", limit.Requests)
+	}
+	if until := limit.HoldUntil(now); !until.IsZero() {
+		t.Errorf("Expected no hold with budget left, got %s", until)
+	}
+
+	remaining = "100"
+	if _, err := client.FetchAssignedIssues(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if until := client.RateLimit().HoldUntil(now); !until.Equal(now.Add(30 * time.Minute)) {
+		t.Errorf("Expected a hold until the budget resets, got %s", until)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	gqlError := func(status int, message, code string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"errors":[{"message":%q,"extensions":{"code":%q}}]}`, message, code)
+		}
+	}
+	body := func(data string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, data)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		endpoint string
+		is       error
+		code     string
+	}{
+		{name: "Unauthorized", handler: gqlError(http.StatusUnauthorized, "Authentication required", "AUTHENTICATION_ERROR"), is: ErrUnauthorized},
+		{name: "Unauthorized without body", handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "invalid key", http.StatusUnauthorized)
+		}), is: ErrUnauthorized},
+		{name: "Authentication error", handler: gqlError(http.StatusOK, "Authentication required", "AUTHENTICATION_ERROR"), is: ErrUnauthorized, code: "AUTHENTICATION_ERROR"},
+		{name: "Validation failed", handler: gqlError(http.StatusBadRequest, `Cannot query field "foo" on type "Issue".`, "GRAPHQL_VALIDATION_FAILED"), is: ErrSchemaMismatch, code: "GRAPHQL_VALIDATION_FAILED"},
+		{name: "Forbidden", handler: gqlError(http.StatusOK, "Forbidden", "FORBIDDEN"), code: "FORBIDDEN"},
+		{name: "Malformed response", handler: body(`{"data":nope}`), is: ErrSchemaMismatch},
+		{name: "Mistyped response", handler: body(`{"data":{"viewer":{"assignedIssues":{"nodes":"oops"}}}}`), is: ErrSchemaMismatch},
+		{name: "Offline", endpoint: closed.URL, is: ErrOffline},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := tc.endpoint
+			if tc.handler != nil {
+				server := httptest.NewServer(tc.handler)
+				defer server.Close()
+				endpoint = server.URL
+			}
+			client := NewClient(WithEndpoint(endpoint), WithAPIKey("test-key"), WithMaxRetries(0))
+
+			_, err := client.FetchAssignedIssues(context.Background())
+			if tc.is != nil && !errors.Is(err, tc.is) {
+				t.Errorf("Expected %v, got %v", tc.is, err)
+			}
+			var gqlErr *GraphQLError
+			if errors.As(err, &gqlErr) != (tc.code != "") || (gqlErr != nil && gqlErr.Code != tc.code) {
+				t.Errorf("Expected a GraphQL error with code %q, got %v", tc.code, err)
+			}
+		})
+	}
+}
+
+func TestFetchUpdatedIssues(t *testing.T) {
+	fake := &fakeLinear{t: t, total: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := NewClient(WithEndpoint(server.URL), WithAPIKey("test-key"))
+
+	if _, err := client.FetchAssignedIssues(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	since := time.Date(2024, 3, 1, 14, 30, 0, 0, time.FixedZone("CET", 3600))
+	if _, err := client.FetchUpdatedIssues(context.Background(), since, IssueFilter{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filter := IssueFilter{Teams: []string{"ENG"}, States: []string{"started"}}
+	if _, err := client.FetchMatchingIssues(context.Background(), filter); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.FetchUpdatedIssues(context.Background(), since, filter); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ok, err := client.IssueMatches(context.Background(), "issue-1", IssueFilter{Cycle: CycleCurrent}); err != nil || !ok {
+		t.Fatalf("Expected the issue to match, got %v (%v)", ok, err)
+	}
+
+	expected := []string{
+		`{"state":{"type":{"nin":["completed","canceled"]}}}`,
+		`{"updatedAt":{"gt":"2024-03-01T13:30:00Z"}}`,
+		`{"state":{"type":{"in":["started"],"nin":["completed","canceled"]}},"team":{"key":{"eqIgnoreCase":"ENG"}}}`,
+		`{"team":{"key":{"eqIgnoreCase":"ENG"}},"updatedAt":{"gt":"2024-03-01T13:30:00Z"}}`,
+		`{"cycle":{"isActive":{"eq":true}},"id":{"eq":"issue-1"},"state":{"type":{"nin":["completed","canceled"]}}}`,
+	}
+	for i, want := range expected {
+		if got, _ := json.Marshal(fake.requests[i]["filter"]); string(got) != want {
+			t.Errorf("Query %d: expected filter %s, got %s", i+1, want, got)
+		}
+	}
+}
+
+func TestIssueFilterVariable(t *testing.T) {
+	const active = `"state":{"type":{"nin":["completed","canceled"]}}`
+	tests := []struct {
+		name   string
+		filter IssueFilter
+		expect string
+	}{
+		{name: "Empty", expect: `{` + active + `}`},
+		{name: "Teams", filter: IssueFilter{Teams: []string{"ENG", "OPS"}}, expect: `{` + active + `,"team":{"or":[{"key":{"eqIgnoreCase":"ENG"}},{"key":{"eqIgnoreCase":"OPS"}}]}}`},
+		{name: "Project", filter: IssueFilter{Projects: []string{"Rocket"}}, expect: `{"project":{"name":{"eqIgnoreCase":"Rocket"}},` + active + `}`},
+		{name: "Labels", filter: IssueFilter{Labels: []string{"Bug", "Regression"}}, expect: `{"labels":{"some":{"or":[{"name":{"eqIgnoreCase":"Bug"}},{"name":{"eqIgnoreCase":"Regression"}}]}},` + active + `}`},
+		{name: "Priorities", filter: IssueFilter{Priorities: []int{1, 2}}, expect: `{"priority":{"in":[1,2]},` + active + `}`},
+		{name: "Current cycle", filter: IssueFilter{Cycle: CycleCurrent}, expect: `{"cycle":{"isActive":{"eq":true}},` + active + `}`},
+		{name: "No cycle", filter: IssueFilter{Cycle: CycleNone}, expect: `{"cycle":{"null":true},` + active + `}`},
+		{name: "Due within", filter: IssueFilter{DueWithinDays: 7}, expect: `{"dueDate":{"lte":"P7D"},` + active + `}`},
+		{
+			name:   "States",
+			filter: IssueFilter{States: []string{"unstarted", "started"}, ExcludeStates: []string{"triage"}},
+			expect: `{"state":{"type":{"in":["unstarted","started"],"nin":["completed","canceled","triage"]}}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.Marshal(tc.filter.variable())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.expect {
+				t.Errorf("Expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestMergeAndDiff(t *testing.T) {
+	issue := func(id, state, updatedAt string) Issue {
+		i := Issue{Id: id, Identifier: "ENG-" + id, UpdatedAt: updatedAt}
+		i.State.Type = state
+		return i
+	}
+	issues := []Issue{
+		issue("1", "started", "2024-03-01T10:00:00Z"),
+		issue("2", "started", "2024-03-01T11:00:00Z"),
+		issue("3", "unstarted", "2024-03-01T12:00:00Z"),
+	}
+	updated := []Issue{
+		issue("2", "completed", "2024-03-02T09:00:00Z"),
+		issue("3", "started", "2024-03-02T10:00:00Z"),
+		issue("4", "backlog", "2024-03-02T11:00:00Z"),
+		issue("5", "canceled", "2024-03-02T12:00:00Z"),
+	}
+
+	merged, changes := Merge(issues, updated)
+	var ids []string
+	for _, issue := range merged {
+		ids = append(ids, issue.Id)
+	}
+	if fmt.Sprint(ids) != "[1 3 4]" || merged[1].State.Type != "started" {
+		t.Errorf("Expected issues 1, 3 (updated) and 4, got %+v", merged)
+	}
+	if want := (Changes{Added: []string{"4"}, Changed: []string{"3"}, Removed: []string{"2"}}); fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("Expected changes %+v, got %+v", want, changes)
+	}
+
+	// A full fetch finds issue 1 unassigned and issue 4 unchanged
+	if changes := Diff(merged, merged[1:]); fmt.Sprint(changes) != fmt.Sprint(Changes{Removed: []string{"1"}}) {
+		t.Errorf("Expected only issue 1 to be removed, got %+v", changes)
+	}
+	if !Diff(merged, merged).Empty() {
+		t.Error("Expected no changes between identical lists")
+	}
+
+	since := time.Date(2024, 3, 2, 11, 30, 0, 0, time.UTC)
+	if got := Watermark(since, updated); !got.Equal(time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the latest update as watermark, got %s", got)
+	}
+	if got := Watermark(since, issues); !got.Equal(since) {
+		t.Errorf("Expected the watermark not to move back, got %s", got)
+	}
+}
@@ -0,0 +1,67 @@
+package linear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear/schema"
+)
+
+// Notification is an inbox notification, as returned by the GetNotifications
+// query.
+type Notification = schema.GetNotificationsNotificationsNotificationConnectionNodesNotification
+
+// Inbox is the viewer's Linear inbox.
+type Inbox struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unreadCount"`
+}
+
+// FetchInbox retrieves the most recent page of inbox notifications, newest
+// first, leaving out notifications that are currently snoozed.
+func (c *Client) FetchInbox(ctx context.Context) (Inbox, error) {
+	resp, err := schema.GetNotifications(ctx, c.gql, c.pageSize)
+	if err != nil {
+		return Inbox{}, fmt.Errorf("failed to execute GetNotifications query: %w", classify(err))
+	}
+	if resp == nil {
+		return Inbox{}, errors.New("received nil response from GetNotifications query")
+	}
+
+	inbox := Inbox{UnreadCount: resp.NotificationsUnreadCount}
+	now := time.Now()
+	for _, notification := range resp.Notifications.Nodes {
+		if snoozedUntil, err := time.Parse(time.RFC3339, notification.SnoozedUntilAt); err == nil && snoozedUntil.After(now) {
+			continue
+		}
+		inbox.Notifications = append(inbox.Notifications, notification)
+	}
+	return inbox, nil
+}
+
+// MarkNotificationRead marks an inbox notification as read.
+func (c *Client) MarkNotificationRead(ctx context.Context, id string) error {
+	readAt := time.Now().UTC().Format(time.RFC3339)
+	resp, err := schema.MarkNotificationRead(ctx, c.gql, id, readAt)
+	if err != nil {
+		return fmt.Errorf("failed to execute MarkNotificationRead mutation: %w", classify(err))
+	}
+	if resp == nil || !resp.NotificationUpdate.Success {
+		return fmt.Errorf("linear did not mark notification %s as read", id)
+	}
+	return nil
+}
+
+// ArchiveNotification removes a notification from the inbox.
+func (c *Client) ArchiveNotification(ctx context.Context, id string) error {
+	resp, err := schema.ArchiveNotification(ctx, c.gql, id)
+	if err != nil {
+		return fmt.Errorf("failed to execute ArchiveNotification mutation: %w", classify(err))
+	}
+	if resp == nil || !resp.NotificationArchive.Success {
+		return fmt.Errorf("linear did not archive notification %s", id)
+	}
+	return nil
+}
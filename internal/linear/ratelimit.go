@@ -0,0 +1,312 @@
+package linear
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRetries is how often a failed query is retried.
+const DefaultMaxRetries = 3
+
+// Retry timings. Waits longer than maxRetryWait aren't worth blocking a
+// refresh for; the request fails and the scheduler tries again later.
+const (
+	minRetryWait = time.Second
+	maxRetryWait = 10 * time.Second
+)
+
+// lowBudget is the fraction of a rate limit budget below which RateLimit
+// asks callers to hold off until it resets.
+const lowBudget = 0.1
+
+// Budget is what is left of one of Linear's rate limits.
+type Budget struct {
+	Limit     int
+	Remaining int
+	// Reset is when the budget is replenished.
+	Reset time.Time
+}
+
+// Low reports whether less than a tenth of the budget is left.
+func (b Budget) Low() bool {
+	return b.Limit > 0 && float64(b.Remaining) < float64(b.Limit)*lowBudget
+}
+
+// RateLimit is Linear's request and complexity budget as reported with the
+// last response.
+type RateLimit struct {
+	Requests   Budget
+	Complexity Budget
+	// LimitedUntil is set while Linear rejects requests for exceeding a
+	// limit.
+	LimitedUntil time.Time
+}
+
+// HoldUntil returns when requests should resume: the end of a rate limit in
+// force, or the reset of a nearly exhausted budget. It returns the zero time
+// if requests can be made now.
+func (r RateLimit) HoldUntil(now time.Time) time.Time {
+	var until time.Time
+	if r.LimitedUntil.After(now) {
+		until = r.LimitedUntil
+	}
+	for _, b := range []Budget{r.Requests, r.Complexity} {
+		if b.Low() && b.Reset.After(now) && b.Reset.After(until) {
+			until = b.Reset
+		}
+	}
+	return until
+}
+
+// RateLimitError is returned when Linear keeps rejecting requests for
+// exceeding a rate limit.
+type RateLimitError struct {
+	// Until is when Linear is expected to accept requests again.
+	Until time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return "rate limited by Linear until " + e.Until.Local().Format("15:04")
+}
+
+// retryTransport records Linear's rate limit headers and retries queries
+// that failed with a rate limit or server error. Mutations are never
+// retried, as Linear may have applied them before failing. Each attempt gets
+// its own timeout, so waiting between retries doesn't cut the last ones short.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	// timeout bounds each attempt, up to reading its response body. Zero
+	// disables it.
+	timeout time.Duration
+	now     func() time.Time
+	// sleep waits for d or until ctx is done.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu    sync.Mutex
+	limit RateLimit
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if until := t.RateLimit().LimitedUntil; until.After(t.now()) {
+		return nil, &RateLimitError{Until: until}
+	}
+	retryable := isQuery(req)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+		if err != nil {
+			return nil, err
+		}
+		limited, err := t.record(resp)
+		if err != nil {
+			return nil, err
+		}
+		if !limited && !isServerError(resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait := t.retryWait(resp, attempt)
+		if limited {
+			if until := t.now().Add(wait); attempt >= t.maxRetries || !retryable || wait > maxRetryWait {
+				resp.Body.Close()
+				t.mu.Lock()
+				t.limit.LimitedUntil = until
+				t.mu.Unlock()
+				return nil, &RateLimitError{Until: until}
+			}
+		} else if attempt >= t.maxRetries || !retryable || wait > maxRetryWait {
+			return resp, nil
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends req once, within the attempt timeout. The timeout keeps
+// running until the response body is closed.
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases an attempt's context once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// RateLimit returns the budget reported with the last response.
+func (t *retryTransport) RateLimit() RateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limit
+}
+
+// record updates the budget from resp's headers and reports whether the
+// request was rejected for exceeding a rate limit. Linear signals that with
+// HTTP 429 or with a RATELIMITED GraphQL error, so the body of 400 responses
+// is inspected and restored.
+func (t *retryTransport) record(resp *http.Response) (bool, error) {
+	t.mu.Lock()
+	if b, ok := parseBudget(resp.Header, "Requests"); ok {
+		t.limit.Requests = b
+	}
+	if b, ok := parseBudget(resp.Header, "Complexity"); ok {
+		t.limit.Complexity = b
+	}
+	t.mu.Unlock()
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true, nil
+	case http.StatusBadRequest:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return false, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return bytes.Contains(body, []byte(`"RATELIMITED"`)), nil
+	}
+	return false, nil
+}
+
+// retryWait returns how long to wait before retrying: what Retry-After asks
+// for, else until an exhausted budget resets, else an exponential backoff
+// with jitter.
+func (t *retryTransport) retryWait(resp *http.Response, attempt int) time.Duration {
+	now := t.now()
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		return wait
+	}
+	t.mu.Lock()
+	limit := t.limit
+	t.mu.Unlock()
+	for _, b := range []Budget{limit.Requests, limit.Complexity} {
+		if b.Limit > 0 && b.Remaining <= 0 && b.Reset.After(now) {
+			return b.Reset.Sub(now)
+		}
+	}
+	wait := minRetryWait << attempt
+	return wait + time.Duration(rand.Int63n(int64(wait)/2+1))
+}
+
+// parseBudget reads the X-RateLimit-<kind>-Limit, -Remaining and -Reset
+// headers. Reset is a Unix timestamp in milliseconds.
+func parseBudget(h http.Header, kind string) (Budget, bool) {
+	limit, err1 := strconv.Atoi(h.Get("X-RateLimit-" + kind + "-Limit"))
+	remaining, err2 := strconv.Atoi(h.Get("X-RateLimit-" + kind + "-Remaining"))
+	if err1 != nil || err2 != nil {
+		return Budget{}, false
+	}
+	b := Budget{Limit: limit, Remaining: remaining}
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-"+kind+"-Reset"), 10, 64); err == nil {
+		b.Reset = time.UnixMilli(reset)
+	}
+	return b, true
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func isServerError(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isQuery reports whether req is a GraphQL query, which is safe to repeat,
+// rather than a mutation. Requests whose body can't be replayed are never
+// retried.
+func isQuery(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req.Method == http.MethodGet
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	defer body.Close()
+	var payload struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return false
+	}
+	return !strings.HasPrefix(strings.TrimSpace(payload.Query), "mutation")
+}
+
+// rewind returns req with a fresh copy of its body for another attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay request body: %w", err)
+	}
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	return retry, nil
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -8,6 +8,121 @@ import (
 	"github.com/Khan/genqlient/graphql"
 )
 
+// ArchiveNotificationNotificationArchiveNotificationArchivePayload includes the requested fields of the GraphQL type NotificationArchivePayload.
+// The GraphQL type's documentation follows.
+//
+// A generic payload return from entity archive mutations.
+type ArchiveNotificationNotificationArchiveNotificationArchivePayload struct {
+	// Whether the operation was successful.
+	Success bool `json:"success"`
+}
+
+// GetSuccess returns ArchiveNotificationNotificationArchiveNotificationArchivePayload.Success, and is useful for accessing the field via an interface.
+func (v *ArchiveNotificationNotificationArchiveNotificationArchivePayload) GetSuccess() bool {
+	return v.Success
+}
+
+// ArchiveNotificationResponse is returned by ArchiveNotification on success.
+type ArchiveNotificationResponse struct {
+	// Archives a notification.
+	NotificationArchive ArchiveNotificationNotificationArchiveNotificationArchivePayload `json:"notificationArchive"`
+}
+
+// GetNotificationArchive returns ArchiveNotificationResponse.NotificationArchive, and is useful for accessing the field via an interface.
+func (v *ArchiveNotificationResponse) GetNotificationArchive() ArchiveNotificationNotificationArchiveNotificationArchivePayload {
+	return v.NotificationArchive
+}
+
+// CreateIssueIssueCreateIssuePayload includes the requested fields of the GraphQL type IssuePayload.
+type CreateIssueIssueCreateIssuePayload struct {
+	// Whether the operation was successful.
+	Success bool `json:"success"`
+	// The issue that was created or updated.
+	Issue CreateIssueIssueCreateIssuePayloadIssue `json:"issue"`
+}
+
+// GetSuccess returns CreateIssueIssueCreateIssuePayload.Success, and is useful for accessing the field via an interface.
+func (v *CreateIssueIssueCreateIssuePayload) GetSuccess() bool { return v.Success }
+
+// GetIssue returns CreateIssueIssueCreateIssuePayload.Issue, and is useful for accessing the field via an interface.
+func (v *CreateIssueIssueCreateIssuePayload) GetIssue() CreateIssueIssueCreateIssuePayloadIssue {
+	return v.Issue
+}
+
+// CreateIssueIssueCreateIssuePayloadIssue includes the requested fields of the GraphQL type Issue.
+// The GraphQL type's documentation follows.
+//
+// An issue.
+type CreateIssueIssueCreateIssuePayloadIssue struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// Issue's human readable identifier (e.g. ENG-123).
+	Identifier string `json:"identifier"`
+	// The issue's title.
+	Title string `json:"title"`
+	// Issue URL.
+	Url string `json:"url"`
+}
+
+// GetId returns CreateIssueIssueCreateIssuePayloadIssue.Id, and is useful for accessing the field via an interface.
+func (v *CreateIssueIssueCreateIssuePayloadIssue) GetId() string { return v.Id }
+
+// GetIdentifier returns CreateIssueIssueCreateIssuePayloadIssue.Identifier, and is useful for accessing the field via an interface.
+func (v *CreateIssueIssueCreateIssuePayloadIssue) GetIdentifier() string { return v.Identifier }
+
+// GetTitle returns CreateIssueIssueCreateIssuePayloadIssue.Title, and is useful for accessing the field via an interface.
+func (v *CreateIssueIssueCreateIssuePayloadIssue) GetTitle() string { return v.Title }
+
+// GetUrl returns CreateIssueIssueCreateIssuePayloadIssue.Url, and is useful for accessing the field via an interface.
+func (v *CreateIssueIssueCreateIssuePayloadIssue) GetUrl() string { return v.Url }
+
+// CreateIssueResponse is returned by CreateIssue on success.
+type CreateIssueResponse struct {
+	// Creates a new issue.
+	IssueCreate CreateIssueIssueCreateIssuePayload `json:"issueCreate"`
+}
+
+// GetIssueCreate returns CreateIssueResponse.IssueCreate, and is useful for accessing the field via an interface.
+func (v *CreateIssueResponse) GetIssueCreate() CreateIssueIssueCreateIssuePayload {
+	return v.IssueCreate
+}
+
+// FindProjectsProjectsProjectConnection includes the requested fields of the GraphQL type ProjectConnection.
+type FindProjectsProjectsProjectConnection struct {
+	Nodes []FindProjectsProjectsProjectConnectionNodesProject `json:"nodes"`
+}
+
+// GetNodes returns FindProjectsProjectsProjectConnection.Nodes, and is useful for accessing the field via an interface.
+func (v *FindProjectsProjectsProjectConnection) GetNodes() []FindProjectsProjectsProjectConnectionNodesProject {
+	return v.Nodes
+}
+
+// FindProjectsProjectsProjectConnectionNodesProject includes the requested fields of the GraphQL type Project.
+// The GraphQL type's documentation follows.
+//
+// A project.
+type FindProjectsProjectsProjectConnectionNodesProject struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The project's name.
+	Name string `json:"name"`
+}
+
+// GetId returns FindProjectsProjectsProjectConnectionNodesProject.Id, and is useful for accessing the field via an interface.
+func (v *FindProjectsProjectsProjectConnectionNodesProject) GetId() string { return v.Id }
+
+// GetName returns FindProjectsProjectsProjectConnectionNodesProject.Name, and is useful for accessing the field via an interface.
+func (v *FindProjectsProjectsProjectConnectionNodesProject) GetName() string { return v.Name }
+
+// FindProjectsResponse is returned by FindProjects on success.
+type FindProjectsResponse struct {
+	// All projects.
+	Projects FindProjectsProjectsProjectConnection `json:"projects"`
+}
+
+// GetProjects returns FindProjectsResponse.Projects, and is useful for accessing the field via an interface.
+func (v *FindProjectsResponse) GetProjects() FindProjectsProjectsProjectConnection { return v.Projects }
+
 // GetAssignedIssuesResponse is returned by GetAssignedIssues on success.
 type GetAssignedIssuesResponse struct {
 	// The currently authenticated user.
@@ -33,7 +148,13 @@ func (v *GetAssignedIssuesViewerUser) GetAssignedIssues() GetAssignedIssuesViewe
 
 // GetAssignedIssuesViewerUserAssignedIssuesIssueConnection includes the requested fields of the GraphQL type IssueConnection.
 type GetAssignedIssuesViewerUserAssignedIssuesIssueConnection struct {
-	Nodes []GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue `json:"nodes"`
+	PageInfo GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo     `json:"pageInfo"`
+	Nodes    []GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue `json:"nodes"`
+}
+
+// GetPageInfo returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnection.PageInfo, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnection) GetPageInfo() GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo {
+	return v.PageInfo
 }
 
 // GetNodes returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnection.Nodes, and is useful for accessing the field via an interface.
@@ -58,10 +179,27 @@ type GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue struct {
 	DueDate string `json:"dueDate"`
 	// The time at which the entity was created.
 	CreatedAt string `json:"createdAt"`
+	// The last time at which the entity was meaningfully updated. This is the same as the creation time if the entity hasn't
+	// been updated after creation.
+	UpdatedAt string `json:"updatedAt"`
+	// The priority of the issue. 0 = No priority, 1 = Urgent, 2 = High, 3 = Normal, 4 = Low.
+	Priority float64 `json:"priority"`
+	// Label for the priority.
+	PriorityLabel string `json:"priorityLabel"`
+	// The estimate of the complexity of the issue..
+	Estimate float64 `json:"estimate"`
+	// The order of the item in relation to other items in the organization.
+	SortOrder float64 `json:"sortOrder"`
 	// The project that the issue is associated with.
 	Project GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueProject `json:"project"`
 	// The workflow state that the issue is associated with.
 	State GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState `json:"state"`
+	// The team that the issue is associated with.
+	Team GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam `json:"team"`
+	// The cycle that the issue is associated with.
+	Cycle GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle `json:"cycle"`
+	// Labels associated with this issue.
+	Labels GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnection `json:"labels"`
 	// The user to whom the issue is assigned to.
 	Assignee GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueAssigneeUser `json:"assignee"`
 }
@@ -96,6 +234,31 @@ func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) Get
 	return v.CreatedAt
 }
 
+// GetUpdatedAt returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.UpdatedAt, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetUpdatedAt() string {
+	return v.UpdatedAt
+}
+
+// GetPriority returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.Priority, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetPriority() float64 {
+	return v.Priority
+}
+
+// GetPriorityLabel returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.PriorityLabel, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetPriorityLabel() string {
+	return v.PriorityLabel
+}
+
+// GetEstimate returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.Estimate, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetEstimate() float64 {
+	return v.Estimate
+}
+
+// GetSortOrder returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.SortOrder, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetSortOrder() float64 {
+	return v.SortOrder
+}
+
 // GetProject returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.Project, and is useful for accessing the field via an interface.
 func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetProject() GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueProject {
 	return v.Project
@@ -106,6 +269,21 @@ func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) Get
 	return v.State
 }
 
+// GetTeam returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.Team, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetTeam() GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam {
+	return v.Team
+}
+
+// GetCycle returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.Cycle, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetCycle() GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle {
+	return v.Cycle
+}
+
+// GetLabels returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.Labels, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetLabels() GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnection {
+	return v.Labels
+}
+
 // GetAssignee returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue.Assignee, and is useful for accessing the field via an interface.
 func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) GetAssignee() GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueAssigneeUser {
 	return v.Assignee
@@ -139,6 +317,79 @@ func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueAssig
 	return v.DisplayName
 }
 
+// GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle includes the requested fields of the GraphQL type Cycle.
+// The GraphQL type's documentation follows.
+//
+// A set of issues to be resolved in a specified amount of time.
+type GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The number of the cycle.
+	Number float64 `json:"number"`
+	// The custom name of the cycle.
+	Name string `json:"name"`
+	// The start time of the cycle.
+	StartsAt string `json:"startsAt"`
+}
+
+// GetId returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle.Id, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle) GetId() string {
+	return v.Id
+}
+
+// GetNumber returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle.Number, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle) GetNumber() float64 {
+	return v.Number
+}
+
+// GetName returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle.Name, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle) GetName() string {
+	return v.Name
+}
+
+// GetStartsAt returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle.StartsAt, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueCycle) GetStartsAt() string {
+	return v.StartsAt
+}
+
+// GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnection includes the requested fields of the GraphQL type IssueLabelConnection.
+type GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnection struct {
+	Nodes []GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel `json:"nodes"`
+}
+
+// GetNodes returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnection.Nodes, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnection) GetNodes() []GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel {
+	return v.Nodes
+}
+
+// GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel includes the requested fields of the GraphQL type IssueLabel.
+// The GraphQL type's documentation follows.
+//
+// Labels that can be associated with issues.
+type GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The label's name.
+	Name string `json:"name"`
+	// The label's color as a HEX string.
+	Color string `json:"color"`
+}
+
+// GetId returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel.Id, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel) GetId() string {
+	return v.Id
+}
+
+// GetName returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel.Name, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel) GetName() string {
+	return v.Name
+}
+
+// GetColor returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel.Color, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueLabelsIssueLabelConnectionNodesIssueLabel) GetColor() string {
+	return v.Color
+}
+
 // GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueProject includes the requested fields of the GraphQL type Project.
 // The GraphQL type's documentation follows.
 //
@@ -174,8 +425,14 @@ func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueProje
 type GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState struct {
 	// The unique identifier of the entity.
 	Id string `json:"id"`
+	// The state's name.
+	Name string `json:"name"`
 	// The type of the state. One of "triage", "backlog", "unstarted", "started", "completed", "canceled".
 	Type string `json:"type"`
+	// The state's UI color as a HEX string.
+	Color string `json:"color"`
+	// The position of the state in the team flow.
+	Position float64 `json:"position"`
 }
 
 // GetId returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState.Id, and is useful for accessing the field via an interface.
@@ -183,56 +440,1039 @@ func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueState
 	return v.Id
 }
 
+// GetName returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState.Name, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState) GetName() string {
+	return v.Name
+}
+
 // GetType returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState.Type, and is useful for accessing the field via an interface.
 func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState) GetType() string {
 	return v.Type
 }
 
-// The query executed by GetAssignedIssues.
-const GetAssignedIssues_Operation = `
-query GetAssignedIssues {
-	viewer {
-		assignedIssues(filter: {state:{type:{nin:["completed","canceled"]}}}) {
-			nodes {
-				id
-				identifier
-				title
-				url
-				dueDate
-				createdAt
-				project {
-					id
-					name
-					targetDate
-				}
-				state {
-					id
-					type
-				}
-				assignee {
-					id
-					name
-					displayName
-				}
-			}
-		}
-	}
+// GetColor returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState.Color, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState) GetColor() string {
+	return v.Color
 }
-`
 
-// file: internal/linear/schema/operations.graphql
-// This query fetches the ID, identifier (like ENG-123), and title
-// for all issues assigned to the currently authenticated user (viewer).
-func GetAssignedIssues(
-	ctx_ context.Context,
-	client_ graphql.Client,
-) (data_ *GetAssignedIssuesResponse, err_ error) {
-	req_ := &graphql.Request{
-		OpName: "GetAssignedIssues",
-		Query:  GetAssignedIssues_Operation,
-	}
+// GetPosition returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState.Position, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState) GetPosition() float64 {
+	return v.Position
+}
 
-	data_ = &GetAssignedIssuesResponse{}
+// GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam includes the requested fields of the GraphQL type Team.
+// The GraphQL type's documentation follows.
+//
+// An organizational unit that contains issues.
+type GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The team's unique key. The key is used in URLs.
+	Key string `json:"key"`
+	// The team's name.
+	Name string `json:"name"`
+}
+
+// GetId returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam.Id, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam) GetId() string {
+	return v.Id
+}
+
+// GetKey returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam.Key, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam) GetKey() string {
+	return v.Key
+}
+
+// GetName returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam.Name, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueTeam) GetName() string {
+	return v.Name
+}
+
+// GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo includes the requested fields of the GraphQL type PageInfo.
+type GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo struct {
+	// Indicates if there are more results when paginating forward.
+	HasNextPage bool `json:"hasNextPage"`
+	// Cursor representing the last result in the paginated results.
+	EndCursor string `json:"endCursor"`
+}
+
+// GetHasNextPage returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo.HasNextPage, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo) GetHasNextPage() bool {
+	return v.HasNextPage
+}
+
+// GetEndCursor returns GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo.EndCursor, and is useful for accessing the field via an interface.
+func (v *GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionPageInfo) GetEndCursor() string {
+	return v.EndCursor
+}
+
+// GetNotificationsNotificationsNotificationConnection includes the requested fields of the GraphQL type NotificationConnection.
+type GetNotificationsNotificationsNotificationConnection struct {
+	Nodes []GetNotificationsNotificationsNotificationConnectionNodesNotification `json:"nodes"`
+}
+
+// GetNodes returns GetNotificationsNotificationsNotificationConnection.Nodes, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnection) GetNodes() []GetNotificationsNotificationsNotificationConnectionNodesNotification {
+	return v.Nodes
+}
+
+// GetNotificationsNotificationsNotificationConnectionNodesNotification includes the requested fields of the GraphQL type Notification.
+// The GraphQL type's documentation follows.
+//
+// A notification sent to a user.
+type GetNotificationsNotificationsNotificationConnectionNodesNotification struct {
+	Typename string `json:"__typename"`
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// Notification type.
+	Type string `json:"type"`
+	// [Internal] Notification title.
+	Title string `json:"title"`
+	// [Internal] Notification subtitle.
+	Subtitle string `json:"subtitle"`
+	// [Internal] URL to the target of the notification.
+	Url string `json:"url"`
+	// The time at when the user marked the notification as read. Null, if the the user hasn't read the notification
+	ReadAt string `json:"readAt"`
+	// The time until a notification will be snoozed. After that it will appear in the inbox again.
+	SnoozedUntilAt string `json:"snoozedUntilAt"`
+	// The time at which the entity was created.
+	CreatedAt string `json:"createdAt"`
+}
+
+// GetTypename returns GetNotificationsNotificationsNotificationConnectionNodesNotification.Typename, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetTypename() string {
+	return v.Typename
+}
+
+// GetId returns GetNotificationsNotificationsNotificationConnectionNodesNotification.Id, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetId() string {
+	return v.Id
+}
+
+// GetType returns GetNotificationsNotificationsNotificationConnectionNodesNotification.Type, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetType() string {
+	return v.Type
+}
+
+// GetTitle returns GetNotificationsNotificationsNotificationConnectionNodesNotification.Title, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetTitle() string {
+	return v.Title
+}
+
+// GetSubtitle returns GetNotificationsNotificationsNotificationConnectionNodesNotification.Subtitle, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetSubtitle() string {
+	return v.Subtitle
+}
+
+// GetUrl returns GetNotificationsNotificationsNotificationConnectionNodesNotification.Url, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetUrl() string {
+	return v.Url
+}
+
+// GetReadAt returns GetNotificationsNotificationsNotificationConnectionNodesNotification.ReadAt, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetReadAt() string {
+	return v.ReadAt
+}
+
+// GetSnoozedUntilAt returns GetNotificationsNotificationsNotificationConnectionNodesNotification.SnoozedUntilAt, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetSnoozedUntilAt() string {
+	return v.SnoozedUntilAt
+}
+
+// GetCreatedAt returns GetNotificationsNotificationsNotificationConnectionNodesNotification.CreatedAt, and is useful for accessing the field via an interface.
+func (v *GetNotificationsNotificationsNotificationConnectionNodesNotification) GetCreatedAt() string {
+	return v.CreatedAt
+}
+
+// GetNotificationsResponse is returned by GetNotifications on success.
+type GetNotificationsResponse struct {
+	// [Internal] A number of unread notifications.
+	NotificationsUnreadCount int `json:"notificationsUnreadCount"`
+	// All notifications.
+	Notifications GetNotificationsNotificationsNotificationConnection `json:"notifications"`
+}
+
+// GetNotificationsUnreadCount returns GetNotificationsResponse.NotificationsUnreadCount, and is useful for accessing the field via an interface.
+func (v *GetNotificationsResponse) GetNotificationsUnreadCount() int {
+	return v.NotificationsUnreadCount
+}
+
+// GetNotifications returns GetNotificationsResponse.Notifications, and is useful for accessing the field via an interface.
+func (v *GetNotificationsResponse) GetNotifications() GetNotificationsNotificationsNotificationConnection {
+	return v.Notifications
+}
+
+// GetTeamsResponse is returned by GetTeams on success.
+type GetTeamsResponse struct {
+	// All teams whose issues can be accessed by the user. This might be different from `administrableTeams`, which also includes teams whose settings can be changed by the user.
+	Teams GetTeamsTeamsTeamConnection `json:"teams"`
+}
+
+// GetTeams returns GetTeamsResponse.Teams, and is useful for accessing the field via an interface.
+func (v *GetTeamsResponse) GetTeams() GetTeamsTeamsTeamConnection { return v.Teams }
+
+// GetTeamsTeamsTeamConnection includes the requested fields of the GraphQL type TeamConnection.
+type GetTeamsTeamsTeamConnection struct {
+	PageInfo GetTeamsTeamsTeamConnectionPageInfo    `json:"pageInfo"`
+	Nodes    []GetTeamsTeamsTeamConnectionNodesTeam `json:"nodes"`
+}
+
+// GetPageInfo returns GetTeamsTeamsTeamConnection.PageInfo, and is useful for accessing the field via an interface.
+func (v *GetTeamsTeamsTeamConnection) GetPageInfo() GetTeamsTeamsTeamConnectionPageInfo {
+	return v.PageInfo
+}
+
+// GetNodes returns GetTeamsTeamsTeamConnection.Nodes, and is useful for accessing the field via an interface.
+func (v *GetTeamsTeamsTeamConnection) GetNodes() []GetTeamsTeamsTeamConnectionNodesTeam {
+	return v.Nodes
+}
+
+// GetTeamsTeamsTeamConnectionNodesTeam includes the requested fields of the GraphQL type Team.
+// The GraphQL type's documentation follows.
+//
+// An organizational unit that contains issues.
+type GetTeamsTeamsTeamConnectionNodesTeam struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The team's unique key. The key is used in URLs.
+	Key string `json:"key"`
+	// The team's name.
+	Name string `json:"name"`
+}
+
+// GetId returns GetTeamsTeamsTeamConnectionNodesTeam.Id, and is useful for accessing the field via an interface.
+func (v *GetTeamsTeamsTeamConnectionNodesTeam) GetId() string { return v.Id }
+
+// GetKey returns GetTeamsTeamsTeamConnectionNodesTeam.Key, and is useful for accessing the field via an interface.
+func (v *GetTeamsTeamsTeamConnectionNodesTeam) GetKey() string { return v.Key }
+
+// GetName returns GetTeamsTeamsTeamConnectionNodesTeam.Name, and is useful for accessing the field via an interface.
+func (v *GetTeamsTeamsTeamConnectionNodesTeam) GetName() string { return v.Name }
+
+// GetTeamsTeamsTeamConnectionPageInfo includes the requested fields of the GraphQL type PageInfo.
+type GetTeamsTeamsTeamConnectionPageInfo struct {
+	// Indicates if there are more results when paginating forward.
+	HasNextPage bool `json:"hasNextPage"`
+	// Cursor representing the last result in the paginated results.
+	EndCursor string `json:"endCursor"`
+}
+
+// GetHasNextPage returns GetTeamsTeamsTeamConnectionPageInfo.HasNextPage, and is useful for accessing the field via an interface.
+func (v *GetTeamsTeamsTeamConnectionPageInfo) GetHasNextPage() bool { return v.HasNextPage }
+
+// GetEndCursor returns GetTeamsTeamsTeamConnectionPageInfo.EndCursor, and is useful for accessing the field via an interface.
+func (v *GetTeamsTeamsTeamConnectionPageInfo) GetEndCursor() string { return v.EndCursor }
+
+// GetViewerResponse is returned by GetViewer on success.
+type GetViewerResponse struct {
+	// The currently authenticated user.
+	Viewer GetViewerViewerUser `json:"viewer"`
+}
+
+// GetViewer returns GetViewerResponse.Viewer, and is useful for accessing the field via an interface.
+func (v *GetViewerResponse) GetViewer() GetViewerViewerUser { return v.Viewer }
+
+// GetViewerViewerUser includes the requested fields of the GraphQL type User.
+// The GraphQL type's documentation follows.
+//
+// A user that has access to the the resources of an organization.
+type GetViewerViewerUser struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The user's full name.
+	Name string `json:"name"`
+	// The user's display (nick) name. Unique within each organization.
+	DisplayName string `json:"displayName"`
+	// The user's email address.
+	Email string `json:"email"`
+}
+
+// GetId returns GetViewerViewerUser.Id, and is useful for accessing the field via an interface.
+func (v *GetViewerViewerUser) GetId() string { return v.Id }
+
+// GetName returns GetViewerViewerUser.Name, and is useful for accessing the field via an interface.
+func (v *GetViewerViewerUser) GetName() string { return v.Name }
+
+// GetDisplayName returns GetViewerViewerUser.DisplayName, and is useful for accessing the field via an interface.
+func (v *GetViewerViewerUser) GetDisplayName() string { return v.DisplayName }
+
+// GetEmail returns GetViewerViewerUser.Email, and is useful for accessing the field via an interface.
+func (v *GetViewerViewerUser) GetEmail() string { return v.Email }
+
+// GetWorkflowStatesResponse is returned by GetWorkflowStates on success.
+type GetWorkflowStatesResponse struct {
+	// All issue workflow states.
+	WorkflowStates GetWorkflowStatesWorkflowStatesWorkflowStateConnection `json:"workflowStates"`
+}
+
+// GetWorkflowStates returns GetWorkflowStatesResponse.WorkflowStates, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesResponse) GetWorkflowStates() GetWorkflowStatesWorkflowStatesWorkflowStateConnection {
+	return v.WorkflowStates
+}
+
+// GetWorkflowStatesWorkflowStatesWorkflowStateConnection includes the requested fields of the GraphQL type WorkflowStateConnection.
+type GetWorkflowStatesWorkflowStatesWorkflowStateConnection struct {
+	PageInfo GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo             `json:"pageInfo"`
+	Nodes    []GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState `json:"nodes"`
+}
+
+// GetPageInfo returns GetWorkflowStatesWorkflowStatesWorkflowStateConnection.PageInfo, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnection) GetPageInfo() GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo {
+	return v.PageInfo
+}
+
+// GetNodes returns GetWorkflowStatesWorkflowStatesWorkflowStateConnection.Nodes, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnection) GetNodes() []GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState {
+	return v.Nodes
+}
+
+// GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState includes the requested fields of the GraphQL type WorkflowState.
+// The GraphQL type's documentation follows.
+//
+// A state in a team workflow.
+type GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The state's name.
+	Name string `json:"name"`
+	// The type of the state. One of "triage", "backlog", "unstarted", "started", "completed", "canceled".
+	Type string `json:"type"`
+	// The state's UI color as a HEX string.
+	Color string `json:"color"`
+	// The position of the state in the team flow.
+	Position float64 `json:"position"`
+	// The team to which this state belongs to.
+	Team GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowStateTeam `json:"team"`
+}
+
+// GetId returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState.Id, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState) GetId() string {
+	return v.Id
+}
+
+// GetName returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState.Name, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState) GetName() string {
+	return v.Name
+}
+
+// GetType returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState.Type, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState) GetType() string {
+	return v.Type
+}
+
+// GetColor returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState.Color, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState) GetColor() string {
+	return v.Color
+}
+
+// GetPosition returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState.Position, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState) GetPosition() float64 {
+	return v.Position
+}
+
+// GetTeam returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState.Team, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState) GetTeam() GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowStateTeam {
+	return v.Team
+}
+
+// GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowStateTeam includes the requested fields of the GraphQL type Team.
+// The GraphQL type's documentation follows.
+//
+// An organizational unit that contains issues.
+type GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowStateTeam struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+}
+
+// GetId returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowStateTeam.Id, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowStateTeam) GetId() string {
+	return v.Id
+}
+
+// GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo includes the requested fields of the GraphQL type PageInfo.
+type GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo struct {
+	// Indicates if there are more results when paginating forward.
+	HasNextPage bool `json:"hasNextPage"`
+	// Cursor representing the last result in the paginated results.
+	EndCursor string `json:"endCursor"`
+}
+
+// GetHasNextPage returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo.HasNextPage, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo) GetHasNextPage() bool {
+	return v.HasNextPage
+}
+
+// GetEndCursor returns GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo.EndCursor, and is useful for accessing the field via an interface.
+func (v *GetWorkflowStatesWorkflowStatesWorkflowStateConnectionPageInfo) GetEndCursor() string {
+	return v.EndCursor
+}
+
+// MarkNotificationReadNotificationUpdateNotificationPayload includes the requested fields of the GraphQL type NotificationPayload.
+type MarkNotificationReadNotificationUpdateNotificationPayload struct {
+	// Whether the operation was successful.
+	Success bool `json:"success"`
+}
+
+// GetSuccess returns MarkNotificationReadNotificationUpdateNotificationPayload.Success, and is useful for accessing the field via an interface.
+func (v *MarkNotificationReadNotificationUpdateNotificationPayload) GetSuccess() bool {
+	return v.Success
+}
+
+// MarkNotificationReadResponse is returned by MarkNotificationRead on success.
+type MarkNotificationReadResponse struct {
+	// Updates a notification.
+	NotificationUpdate MarkNotificationReadNotificationUpdateNotificationPayload `json:"notificationUpdate"`
+}
+
+// GetNotificationUpdate returns MarkNotificationReadResponse.NotificationUpdate, and is useful for accessing the field via an interface.
+func (v *MarkNotificationReadResponse) GetNotificationUpdate() MarkNotificationReadNotificationUpdateNotificationPayload {
+	return v.NotificationUpdate
+}
+
+// UpdateIssueStateIssueUpdateIssuePayload includes the requested fields of the GraphQL type IssuePayload.
+type UpdateIssueStateIssueUpdateIssuePayload struct {
+	// Whether the operation was successful.
+	Success bool `json:"success"`
+	// The issue that was created or updated.
+	Issue UpdateIssueStateIssueUpdateIssuePayloadIssue `json:"issue"`
+}
+
+// GetSuccess returns UpdateIssueStateIssueUpdateIssuePayload.Success, and is useful for accessing the field via an interface.
+func (v *UpdateIssueStateIssueUpdateIssuePayload) GetSuccess() bool { return v.Success }
+
+// GetIssue returns UpdateIssueStateIssueUpdateIssuePayload.Issue, and is useful for accessing the field via an interface.
+func (v *UpdateIssueStateIssueUpdateIssuePayload) GetIssue() UpdateIssueStateIssueUpdateIssuePayloadIssue {
+	return v.Issue
+}
+
+// UpdateIssueStateIssueUpdateIssuePayloadIssue includes the requested fields of the GraphQL type Issue.
+// The GraphQL type's documentation follows.
+//
+// An issue.
+type UpdateIssueStateIssueUpdateIssuePayloadIssue struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The workflow state that the issue is associated with.
+	State UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState `json:"state"`
+}
+
+// GetId returns UpdateIssueStateIssueUpdateIssuePayloadIssue.Id, and is useful for accessing the field via an interface.
+func (v *UpdateIssueStateIssueUpdateIssuePayloadIssue) GetId() string { return v.Id }
+
+// GetState returns UpdateIssueStateIssueUpdateIssuePayloadIssue.State, and is useful for accessing the field via an interface.
+func (v *UpdateIssueStateIssueUpdateIssuePayloadIssue) GetState() UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState {
+	return v.State
+}
+
+// UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState includes the requested fields of the GraphQL type WorkflowState.
+// The GraphQL type's documentation follows.
+//
+// A state in a team workflow.
+type UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState struct {
+	// The unique identifier of the entity.
+	Id string `json:"id"`
+	// The type of the state. One of "triage", "backlog", "unstarted", "started", "completed", "canceled".
+	Type string `json:"type"`
+}
+
+// GetId returns UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState.Id, and is useful for accessing the field via an interface.
+func (v *UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState) GetId() string { return v.Id }
+
+// GetType returns UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState.Type, and is useful for accessing the field via an interface.
+func (v *UpdateIssueStateIssueUpdateIssuePayloadIssueStateWorkflowState) GetType() string {
+	return v.Type
+}
+
+// UpdateIssueStateResponse is returned by UpdateIssueState on success.
+type UpdateIssueStateResponse struct {
+	// Updates an issue.
+	IssueUpdate UpdateIssueStateIssueUpdateIssuePayload `json:"issueUpdate"`
+}
+
+// GetIssueUpdate returns UpdateIssueStateResponse.IssueUpdate, and is useful for accessing the field via an interface.
+func (v *UpdateIssueStateResponse) GetIssueUpdate() UpdateIssueStateIssueUpdateIssuePayload {
+	return v.IssueUpdate
+}
+
+// __ArchiveNotificationInput is used internally by genqlient
+type __ArchiveNotificationInput struct {
+	Id string `json:"id"`
+}
+
+// GetId returns __ArchiveNotificationInput.Id, and is useful for accessing the field via an interface.
+func (v *__ArchiveNotificationInput) GetId() string { return v.Id }
+
+// __CreateIssueInput is used internally by genqlient
+type __CreateIssueInput struct {
+	TeamId      string `json:"teamId"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ProjectId   string `json:"projectId,omitempty"`
+	AssigneeId  string `json:"assigneeId,omitempty"`
+}
+
+// GetTeamId returns __CreateIssueInput.TeamId, and is useful for accessing the field via an interface.
+func (v *__CreateIssueInput) GetTeamId() string { return v.TeamId }
+
+// GetTitle returns __CreateIssueInput.Title, and is useful for accessing the field via an interface.
+func (v *__CreateIssueInput) GetTitle() string { return v.Title }
+
+// GetDescription returns __CreateIssueInput.Description, and is useful for accessing the field via an interface.
+func (v *__CreateIssueInput) GetDescription() string { return v.Description }
+
+// GetProjectId returns __CreateIssueInput.ProjectId, and is useful for accessing the field via an interface.
+func (v *__CreateIssueInput) GetProjectId() string { return v.ProjectId }
+
+// GetAssigneeId returns __CreateIssueInput.AssigneeId, and is useful for accessing the field via an interface.
+func (v *__CreateIssueInput) GetAssigneeId() string { return v.AssigneeId }
+
+// __FindProjectsInput is used internally by genqlient
+type __FindProjectsInput struct {
+	Name string `json:"name"`
+}
+
+// GetName returns __FindProjectsInput.Name, and is useful for accessing the field via an interface.
+func (v *__FindProjectsInput) GetName() string { return v.Name }
+
+// __GetAssignedIssuesInput is used internally by genqlient
+type __GetAssignedIssuesInput struct {
+	First  int                    `json:"first"`
+	After  string                 `json:"after,omitempty"`
+	Filter map[string]interface{} `json:"filter"`
+}
+
+// GetFirst returns __GetAssignedIssuesInput.First, and is useful for accessing the field via an interface.
+func (v *__GetAssignedIssuesInput) GetFirst() int { return v.First }
+
+// GetAfter returns __GetAssignedIssuesInput.After, and is useful for accessing the field via an interface.
+func (v *__GetAssignedIssuesInput) GetAfter() string { return v.After }
+
+// GetFilter returns __GetAssignedIssuesInput.Filter, and is useful for accessing the field via an interface.
+func (v *__GetAssignedIssuesInput) GetFilter() map[string]interface{} { return v.Filter }
+
+// __GetNotificationsInput is used internally by genqlient
+type __GetNotificationsInput struct {
+	First int `json:"first"`
+}
+
+// GetFirst returns __GetNotificationsInput.First, and is useful for accessing the field via an interface.
+func (v *__GetNotificationsInput) GetFirst() int { return v.First }
+
+// __GetTeamsInput is used internally by genqlient
+type __GetTeamsInput struct {
+	First int    `json:"first"`
+	After string `json:"after,omitempty"`
+}
+
+// GetFirst returns __GetTeamsInput.First, and is useful for accessing the field via an interface.
+func (v *__GetTeamsInput) GetFirst() int { return v.First }
+
+// GetAfter returns __GetTeamsInput.After, and is useful for accessing the field via an interface.
+func (v *__GetTeamsInput) GetAfter() string { return v.After }
+
+// __GetWorkflowStatesInput is used internally by genqlient
+type __GetWorkflowStatesInput struct {
+	First int    `json:"first"`
+	After string `json:"after,omitempty"`
+}
+
+// GetFirst returns __GetWorkflowStatesInput.First, and is useful for accessing the field via an interface.
+func (v *__GetWorkflowStatesInput) GetFirst() int { return v.First }
+
+// GetAfter returns __GetWorkflowStatesInput.After, and is useful for accessing the field via an interface.
+func (v *__GetWorkflowStatesInput) GetAfter() string { return v.After }
+
+// __MarkNotificationReadInput is used internally by genqlient
+type __MarkNotificationReadInput struct {
+	Id     string `json:"id"`
+	ReadAt string `json:"readAt"`
+}
+
+// GetId returns __MarkNotificationReadInput.Id, and is useful for accessing the field via an interface.
+func (v *__MarkNotificationReadInput) GetId() string { return v.Id }
+
+// GetReadAt returns __MarkNotificationReadInput.ReadAt, and is useful for accessing the field via an interface.
+func (v *__MarkNotificationReadInput) GetReadAt() string { return v.ReadAt }
+
+// __UpdateIssueStateInput is used internally by genqlient
+type __UpdateIssueStateInput struct {
+	Id      string `json:"id"`
+	StateId string `json:"stateId"`
+}
+
+// GetId returns __UpdateIssueStateInput.Id, and is useful for accessing the field via an interface.
+func (v *__UpdateIssueStateInput) GetId() string { return v.Id }
+
+// GetStateId returns __UpdateIssueStateInput.StateId, and is useful for accessing the field via an interface.
+func (v *__UpdateIssueStateInput) GetStateId() string { return v.StateId }
+
+// The mutation executed by ArchiveNotification.
+const ArchiveNotification_Operation = `
+mutation ArchiveNotification ($id: String!) {
+	notificationArchive(id: $id) {
+		success
+	}
+}
+`
+
+// Archives an inbox notification.
+func ArchiveNotification(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	id string,
+) (data_ *ArchiveNotificationResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "ArchiveNotification",
+		Query:  ArchiveNotification_Operation,
+		Variables: &__ArchiveNotificationInput{
+			Id: id,
+		},
+	}
+
+	data_ = &ArchiveNotificationResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The mutation executed by CreateIssue.
+const CreateIssue_Operation = `
+mutation CreateIssue ($teamId: String!, $title: String!, $description: String, $projectId: String, $assigneeId: String) {
+	issueCreate(input: {teamId:$teamId,title:$title,description:$description,projectId:$projectId,assigneeId:$assigneeId}) {
+		success
+		issue {
+			id
+			identifier
+			title
+			url
+		}
+	}
+}
+`
+
+// Creates an issue. Optional fields that are left empty are not sent.
+func CreateIssue(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	teamId string,
+	title string,
+	description string,
+	projectId string,
+	assigneeId string,
+) (data_ *CreateIssueResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "CreateIssue",
+		Query:  CreateIssue_Operation,
+		Variables: &__CreateIssueInput{
+			TeamId:      teamId,
+			Title:       title,
+			Description: description,
+			ProjectId:   projectId,
+			AssigneeId:  assigneeId,
+		},
+	}
+
+	data_ = &CreateIssueResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by FindProjects.
+const FindProjects_Operation = `
+query FindProjects ($name: String!) {
+	projects(filter: {name:{eqIgnoreCase:$name}}) {
+		nodes {
+			id
+			name
+		}
+	}
+}
+`
+
+// Looks up projects by name (case-insensitive).
+func FindProjects(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	name string,
+) (data_ *FindProjectsResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "FindProjects",
+		Query:  FindProjects_Operation,
+		Variables: &__FindProjectsInput{
+			Name: name,
+		},
+	}
+
+	data_ = &FindProjectsResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetAssignedIssues.
+const GetAssignedIssues_Operation = `
+query GetAssignedIssues ($first: Int, $after: String, $filter: IssueFilter) {
+	viewer {
+		assignedIssues(first: $first, after: $after, filter: $filter) {
+			pageInfo {
+				hasNextPage
+				endCursor
+			}
+			nodes {
+				id
+				identifier
+				title
+				url
+				dueDate
+				createdAt
+				updatedAt
+				priority
+				priorityLabel
+				estimate
+				sortOrder
+				project {
+					id
+					name
+					targetDate
+				}
+				state {
+					id
+					name
+					type
+					color
+					position
+				}
+				team {
+					id
+					key
+					name
+				}
+				cycle {
+					id
+					number
+					name
+					startsAt
+				}
+				labels {
+					nodes {
+						id
+						name
+						color
+					}
+				}
+				assignee {
+					id
+					name
+					displayName
+				}
+			}
+		}
+	}
+}
+`
+
+// file: internal/linear/schema/operations.graphql
+// This query fetches the ID, identifier (like ENG-123), and title
+// for all issues assigned to the currently authenticated user (viewer).
+// Results are paginated; pass the previous page's endCursor as $after.
+// $filter narrows them down, e.g. to active issues or to those updated since
+// the last sync.
+func GetAssignedIssues(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	first int,
+	after string,
+	filter map[string]interface{},
+) (data_ *GetAssignedIssuesResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetAssignedIssues",
+		Query:  GetAssignedIssues_Operation,
+		Variables: &__GetAssignedIssuesInput{
+			First:  first,
+			After:  after,
+			Filter: filter,
+		},
+	}
+
+	data_ = &GetAssignedIssuesResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetNotifications.
+const GetNotifications_Operation = `
+query GetNotifications ($first: Int) {
+	notificationsUnreadCount
+	notifications(first: $first) {
+		nodes {
+			__typename
+			id
+			type
+			title
+			subtitle
+			url
+			readAt
+			snoozedUntilAt
+			createdAt
+		}
+	}
+}
+`
+
+// Fetches the most recent inbox notifications and the unread count.
+func GetNotifications(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	first int,
+) (data_ *GetNotificationsResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetNotifications",
+		Query:  GetNotifications_Operation,
+		Variables: &__GetNotificationsInput{
+			First: first,
+		},
+	}
+
+	data_ = &GetNotificationsResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetTeams.
+const GetTeams_Operation = `
+query GetTeams ($first: Int, $after: String) {
+	teams(first: $first, after: $after) {
+		pageInfo {
+			hasNextPage
+			endCursor
+		}
+		nodes {
+			id
+			key
+			name
+		}
+	}
+}
+`
+
+// Lists the teams visible to the viewer, so new issues can be filed in one.
+func GetTeams(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	first int,
+	after string,
+) (data_ *GetTeamsResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetTeams",
+		Query:  GetTeams_Operation,
+		Variables: &__GetTeamsInput{
+			First: first,
+			After: after,
+		},
+	}
+
+	data_ = &GetTeamsResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetViewer.
+const GetViewer_Operation = `
+query GetViewer {
+	viewer {
+		id
+		name
+		displayName
+		email
+	}
+}
+`
+
+// Returns the authenticated user, used as the default assignee for new issues
+// and to check credentials.
+func GetViewer(
+	ctx_ context.Context,
+	client_ graphql.Client,
+) (data_ *GetViewerResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetViewer",
+		Query:  GetViewer_Operation,
+	}
+
+	data_ = &GetViewerResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetWorkflowStates.
+const GetWorkflowStates_Operation = `
+query GetWorkflowStates ($first: Int, $after: String) {
+	workflowStates(first: $first, after: $after) {
+		pageInfo {
+			hasNextPage
+			endCursor
+		}
+		nodes {
+			id
+			name
+			type
+			color
+			position
+			team {
+				id
+			}
+		}
+	}
+}
+`
+
+// Lists the workflow states of every team the viewer can see, so issues can be
+// moved to another state from the menu.
+func GetWorkflowStates(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	first int,
+	after string,
+) (data_ *GetWorkflowStatesResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetWorkflowStates",
+		Query:  GetWorkflowStates_Operation,
+		Variables: &__GetWorkflowStatesInput{
+			First: first,
+			After: after,
+		},
+	}
+
+	data_ = &GetWorkflowStatesResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The mutation executed by MarkNotificationRead.
+const MarkNotificationRead_Operation = `
+mutation MarkNotificationRead ($id: String!, $readAt: DateTime!) {
+	notificationUpdate(id: $id, input: {readAt:$readAt}) {
+		success
+	}
+}
+`
+
+// Marks an inbox notification as read.
+func MarkNotificationRead(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	id string,
+	readAt string,
+) (data_ *MarkNotificationReadResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "MarkNotificationRead",
+		Query:  MarkNotificationRead_Operation,
+		Variables: &__MarkNotificationReadInput{
+			Id:     id,
+			ReadAt: readAt,
+		},
+	}
+
+	data_ = &MarkNotificationReadResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The mutation executed by UpdateIssueState.
+const UpdateIssueState_Operation = `
+mutation UpdateIssueState ($id: String!, $stateId: String!) {
+	issueUpdate(id: $id, input: {stateId:$stateId}) {
+		success
+		issue {
+			id
+			state {
+				id
+				type
+			}
+		}
+	}
+}
+`
+
+// Moves an issue to another workflow state.
+func UpdateIssueState(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	id string,
+	stateId string,
+) (data_ *UpdateIssueStateResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "UpdateIssueState",
+		Query:  UpdateIssueState_Operation,
+		Variables: &__UpdateIssueStateInput{
+			Id:      id,
+			StateId: stateId,
+		},
+	}
+
+	data_ = &UpdateIssueStateResponse{}
 	resp_ := &graphql.Response{Data: data_}
 
 	err_ = client_.MakeRequest(
@@ -0,0 +1,121 @@
+package linear
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// FetchUpdatedIssues retrieves the assigned issues matching filter that were
+// updated after since. Unlike FetchMatchingIssues, it ignores the state types
+// of filter and includes issues that have been completed or canceled, so
+// Merge can drop them. Issues that were unassigned, deleted or no longer
+// match otherwise don't show up at all; only a full fetch catches those.
+func (c *Client) FetchUpdatedIssues(ctx context.Context, since time.Time, filter IssueFilter) ([]Issue, error) {
+	variable := filter.attributes()
+	variable["updatedAt"] = map[string]interface{}{"gt": since.UTC().Format(time.RFC3339Nano)}
+	return c.fetchIssues(ctx, variable)
+}
+
+// IssueMatches reports whether Linear finds the assigned issue with the given
+// ID among those matching filter. It settles what can't be told from the
+// issue itself, such as whether its cycle is the current one.
+func (c *Client) IssueMatches(ctx context.Context, id string, filter IssueFilter) (bool, error) {
+	variable := filter.variable()
+	variable["id"] = map[string]interface{}{"eq": id}
+	issues, err := c.fetchIssues(ctx, variable)
+	if err != nil {
+		return false, err
+	}
+	return len(issues) > 0, nil
+}
+
+// Active reports whether issue is neither completed nor canceled.
+func Active(issue Issue) bool {
+	return issue.State.Type != "completed" && issue.State.Type != "canceled"
+}
+
+// Changes lists the IDs of issues that were added, changed or removed by a
+// sync.
+type Changes struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+// Empty reports whether nothing changed.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// Diff returns the changes from old to new, as found by a full fetch.
+func Diff(old, new []Issue) Changes {
+	before := make(map[string]Issue, len(old))
+	for _, issue := range old {
+		before[issue.Id] = issue
+	}
+	var changes Changes
+	for _, issue := range new {
+		prev, ok := before[issue.Id]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, issue.Id)
+		case !reflect.DeepEqual(prev, issue):
+			changes.Changed = append(changes.Changed, issue.Id)
+		}
+		delete(before, issue.Id)
+	}
+	for _, issue := range old {
+		if _, ok := before[issue.Id]; ok {
+			changes.Removed = append(changes.Removed, issue.Id)
+		}
+	}
+	return changes
+}
+
+// Merge applies the issues returned by FetchUpdatedIssues to issues. Updated
+// issues replace their previous version in place, new ones are appended and
+// those no longer active are removed.
+func Merge(issues, updated []Issue) ([]Issue, Changes) {
+	byID := make(map[string]Issue, len(updated))
+	for _, issue := range updated {
+		byID[issue.Id] = issue
+	}
+
+	var changes Changes
+	merged := make([]Issue, 0, len(issues)+len(updated))
+	for _, issue := range issues {
+		next, ok := byID[issue.Id]
+		delete(byID, issue.Id)
+		switch {
+		case !ok:
+			merged = append(merged, issue)
+		case !Active(next):
+			changes.Removed = append(changes.Removed, issue.Id)
+		default:
+			merged = append(merged, next)
+			if !reflect.DeepEqual(next, issue) {
+				changes.Changed = append(changes.Changed, issue.Id)
+			}
+		}
+	}
+	for _, issue := range updated {
+		if _, ok := byID[issue.Id]; ok && Active(issue) {
+			merged = append(merged, issue)
+			changes.Added = append(changes.Added, issue.Id)
+		}
+	}
+	return merged, changes
+}
+
+// Watermark returns the latest update time of issues, or since if it is
+// later. Passing it to the next FetchUpdatedIssues picks up where issues left
+// off, using Linear's clock rather than the local one.
+func Watermark(since time.Time, issues []Issue) time.Time {
+	for _, issue := range issues {
+		if updated, err := time.Parse(time.RFC3339, issue.UpdatedAt); err == nil && updated.After(since) {
+			since = updated
+		}
+	}
+	return since
+}
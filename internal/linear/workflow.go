@@ -0,0 +1,84 @@
+package linear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/pzurek/lil/internal/linear/schema"
+)
+
+// WorkflowState is a state in a team's workflow, as returned by the
+// GetWorkflowStates query.
+type WorkflowState = schema.GetWorkflowStatesWorkflowStatesWorkflowStateConnectionNodesWorkflowState
+
+// stateTypeOrder is the order Linear shows workflow state types in.
+var stateTypeOrder = map[string]int{
+	"triage":    0,
+	"backlog":   1,
+	"unstarted": 2,
+	"started":   3,
+	"completed": 4,
+	"canceled":  5,
+}
+
+// FetchWorkflowStates retrieves the workflow states of every team visible to
+// the viewer, keyed by team ID. Each team's states are in workflow order.
+func (c *Client) FetchWorkflowStates(ctx context.Context) (map[string][]WorkflowState, error) {
+	states := make(map[string][]WorkflowState)
+	after := ""
+
+	for page := 1; ; page++ {
+		resp, err := schema.GetWorkflowStates(ctx, c.gql, c.pageSize, after)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute GetWorkflowStates query (page %d): %w", page, classify(err))
+		}
+
+		if resp == nil {
+			return nil, errors.New("received nil response from GetWorkflowStates query")
+		}
+
+		connection := resp.WorkflowStates
+		for _, state := range connection.Nodes {
+			states[state.Team.Id] = append(states[state.Team.Id], state)
+		}
+
+		if !connection.PageInfo.HasNextPage || page >= c.maxPages {
+			break
+		}
+		if connection.PageInfo.EndCursor == "" || connection.PageInfo.EndCursor == after {
+			return nil, fmt.Errorf("GetWorkflowStates page %d reported more results without advancing the cursor", page)
+		}
+		after = connection.PageInfo.EndCursor
+	}
+
+	for _, teamStates := range states {
+		SortWorkflowStates(teamStates)
+	}
+	return states, nil
+}
+
+// SortWorkflowStates orders states the way Linear does: by type (triage,
+// backlog, unstarted, started, completed, canceled), then by position.
+func SortWorkflowStates(states []WorkflowState) {
+	sort.SliceStable(states, func(i, j int) bool {
+		ti, tj := stateTypeOrder[states[i].Type], stateTypeOrder[states[j].Type]
+		if ti != tj {
+			return ti < tj
+		}
+		return states[i].Position < states[j].Position
+	})
+}
+
+// UpdateIssueState moves an issue to another workflow state.
+func (c *Client) UpdateIssueState(ctx context.Context, issueID, stateID string) error {
+	resp, err := schema.UpdateIssueState(ctx, c.gql, issueID, stateID)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateIssueState mutation: %w", classify(err))
+	}
+	if resp == nil || !resp.IssueUpdate.Success {
+		return fmt.Errorf("linear did not update the state of issue %s", issueID)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package menu
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// Define a far future time for sorting items without dates
+var distantFuture = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// priorityGlyphs mark issues by priority in their titles: urgent, then
+// signal bars for high, medium and low.
+var priorityGlyphs = []string{"", "‼", "▇", "▅", "▂"}
+
+// IssueTitle is the menu title for an issue, e.g. "ENG-123: Fix the thing",
+// led by a priority glyph and followed by its labels, e.g.
+// "‼ ENG-123: Fix the thing [Bug]".
+func IssueTitle(issue linear.Issue) string {
+	title := issue.Identifier + ": " + issue.Title
+	if p := int(issue.Priority); p > 0 && p < len(priorityGlyphs) {
+		title = priorityGlyphs[p] + " " + title
+	}
+	for _, label := range issue.Labels.Nodes {
+		title += " [" + label.Name + "]"
+	}
+	return title
+}
+
+// Tooltip lists the issue's project, team, cycle, due date, priority,
+// estimate, labels, assignee and status, one per line.
+func Tooltip(issue linear.Issue) string {
+	tooltipLines := []string{}
+	if issue.Project.Id != "" {
+		tooltipLines = append(tooltipLines, "Project: "+issue.Project.Name)
+	}
+	if issue.Team.Name != "" {
+		tooltipLines = append(tooltipLines, fmt.Sprintf("Team: %s (%s)", issue.Team.Name, issue.Team.Key))
+	}
+	if issue.Cycle.Id != "" {
+		tooltipLines = append(tooltipLines, "Cycle: "+cycleName(issue))
+	}
+	if issue.DueDate != "" {
+		dueDate := parseLinearDate(issue.DueDate)
+		if !dueDate.Equal(distantFuture) {
+			tooltipLines = append(tooltipLines, "Due: "+dueDate.Format("Jan 2, 2006"))
+		}
+	}
+	if issue.Priority > 0 && issue.PriorityLabel != "" {
+		tooltipLines = append(tooltipLines, "Priority: "+issue.PriorityLabel)
+	}
+	if issue.Estimate > 0 {
+		points := "points"
+		if issue.Estimate == 1 {
+			points = "point"
+		}
+		tooltipLines = append(tooltipLines, fmt.Sprintf("Estimate: %s %s", strconv.FormatFloat(issue.Estimate, 'f', -1, 64), points))
+	}
+	if len(issue.Labels.Nodes) > 0 {
+		var names []string
+		for _, label := range issue.Labels.Nodes {
+			names = append(names, label.Name)
+		}
+		tooltipLines = append(tooltipLines, "Labels: "+strings.Join(names, ", "))
+	}
+	if issue.Assignee.Id != "" {
+		assigneeName := issue.Assignee.Name
+		if issue.Assignee.DisplayName != "" {
+			assigneeName = issue.Assignee.DisplayName
+		}
+		tooltipLines = append(tooltipLines, "Assignee: "+assigneeName)
+	}
+	if issue.State.Id != "" {
+		tooltipLines = append(tooltipLines, "Status: "+StateName(issue))
+	}
+	return strings.Join(tooltipLines, "\n")
+}
+
+// StateName is the name of the issue's workflow state, e.g. "In Progress",
+// or its type for issues cached before names were fetched.
+func StateName(issue linear.Issue) string {
+	if issue.State.Name != "" {
+		return issue.State.Name
+	}
+	return issue.State.Type
+}
+
+// stateIcons are the freedesktop icons standing in for state colors on hosts
+// that only show themed icons, keyed by state type.
+var stateIcons = map[string]string{
+	"triage":    "dialog-question",
+	"backlog":   "media-playback-stop",
+	"unstarted": "media-playback-pause",
+	"started":   "media-playback-start",
+	"completed": "emblem-default",
+	"canceled":  "process-stop",
+}
+
+// StateIndicator is the indicator for a workflow state of the given type and
+// color. Colors Linear doesn't send as hex are dropped.
+func StateIndicator(stateType, color string) Indicator {
+	color, _ = NormalizeColor(color)
+	return Indicator{Color: color, IconName: stateIcons[stateType]}
+}
+
+// NormalizeColor turns a hex color such as "#F2C94C", "f2c94c" or "#fc4" into
+// lowercase "#rrggbb" form. It reports false if color isn't one.
+func NormalizeColor(color string) (string, bool) {
+	hex := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(color), "#"))
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return "", false
+	}
+	return "#" + hex, true
+}
+
+// RGB returns the components of the indicator's color between 0 and 1. It
+// reports false when the indicator has no color.
+func (i Indicator) RGB() (r, g, b float64, ok bool) {
+	hex, ok := NormalizeColor(i.Color)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	value, _ := strconv.ParseUint(hex[1:], 16, 32)
+	return float64(value>>16) / 255, float64(value>>8&0xff) / 255, float64(value&0xff) / 255, true
+}
+
+// cycleName is the custom name of the issue's cycle, or "Cycle 12".
+func cycleName(issue linear.Issue) string {
+	if issue.Cycle.Name != "" {
+		return issue.Cycle.Name
+	}
+	return fmt.Sprintf("Cycle %d", int(issue.Cycle.Number))
+}
+
+// DueLabel is a short due date description such as "due Jan 2, 2006", or
+// empty when the issue has no due date.
+func DueLabel(issue linear.Issue) string {
+	dueDate := parseLinearDate(issue.DueDate)
+	if dueDate.Equal(distantFuture) {
+		return ""
+	}
+	return "due " + dueDate.Format("Jan 2, 2006")
+}
+
+// parseLinearDate parses Linear's date format.
+// Returns distantFuture if parsing fails or input is empty.
+func parseLinearDate(dateStr string) time.Time {
+	if dateStr == "" {
+		return distantFuture
+	}
+	t, err := time.Parse(time.RFC3339, dateStr)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			log.Printf("Warning: Could not parse date '%s': %v", dateStr, err)
+			return distantFuture
+		}
+	}
+	return t
+}
@@ -0,0 +1,329 @@
+package menu
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// Grouping modes.
+const (
+	ByProject  = "project"
+	ByTeam     = "team"
+	ByState    = "state"
+	ByPriority = "priority"
+	ByCycle    = "cycle"
+	ByLabel    = "label"
+	ByDueDate  = "due"
+	Flat       = "flat"
+)
+
+// Groupings lists the grouping modes in the order the menu offers them, with
+// their titles.
+var Groupings = []struct{ Mode, Title string }{
+	{ByProject, "Project"},
+	{ByTeam, "Team"},
+	{ByState, "Status"},
+	{ByPriority, "Priority"},
+	{ByCycle, "Cycle"},
+	{ByLabel, "Label"},
+	{ByDueDate, "Due Date"},
+	{Flat, "None"},
+}
+
+// GroupBy groups issues by mode, one of the grouping modes. now is used by
+// ByDueDate. Unknown modes group by project.
+func GroupBy(mode string, issues []linear.Issue, now time.Time) []Group {
+	switch mode {
+	case ByTeam:
+		return GroupByTeam(issues)
+	case ByState:
+		return GroupByState(issues)
+	case ByPriority:
+		return GroupByPriority(issues)
+	case ByCycle:
+		return GroupByCycle(issues)
+	case ByLabel:
+		return GroupByLabel(issues)
+	case ByDueDate:
+		return GroupByDueDate(issues, now)
+	case Flat:
+		return GroupFlat(issues)
+	}
+	return GroupByProject(issues)
+}
+
+// Group is a titled set of issues. An empty Name means the group has no
+// header (e.g. issues without a project).
+type Group struct {
+	Name   string
+	Issues []linear.Issue
+
+	earliestDate time.Time
+	id           string
+}
+
+// GroupByProject groups issues by project. Projects are ordered by their
+// target date, or by their earliest issue date when they have none, and
+// issues without a project come last. Issues within a project are ordered by
+// due date, falling back to creation date.
+func GroupByProject(issues []linear.Issue) []Group {
+	// Group by project
+	projectsMap := make(map[string]*Group)
+	noProjectKey := "__no_project__" // Internal key that won't be displayed
+
+	for _, issue := range issues {
+		projectKey := noProjectKey
+		projectTargetDate := distantFuture
+		if issue.Project.Id != "" {
+			projectKey = issue.Project.Id
+			projectTargetDate = parseLinearDate(issue.Project.TargetDate)
+		}
+
+		group, exists := projectsMap[projectKey]
+		if !exists {
+			group = &Group{earliestDate: distantFuture, id: projectKey}
+			if projectKey != noProjectKey {
+				group.Name = issue.Project.Name
+			}
+			projectsMap[projectKey] = group
+		}
+
+		group.Issues = append(group.Issues, issue)
+
+		candidate := projectTargetDate
+		if candidate.Equal(distantFuture) {
+			candidate = effectiveDate(issue)
+		}
+		if candidate.Before(group.earliestDate) {
+			group.earliestDate = candidate
+		}
+	}
+
+	// Convert map to slice for sorting
+	groups := make([]Group, 0, len(projectsMap))
+	var noProject *Group
+	for key, group := range projectsMap {
+		sortIssues(group.Issues)
+		if key == noProjectKey {
+			noProject = group
+			continue
+		}
+		groups = append(groups, *group)
+	}
+
+	// Sort projects by earliest date, then by name and project ID so the
+	// order is the same on every refresh
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].earliestDate.Equal(groups[j].earliestDate) {
+			return groups[i].earliestDate.Before(groups[j].earliestDate)
+		}
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].id < groups[j].id
+	})
+
+	// No-project group always last
+	if noProject != nil {
+		groups = append(groups, *noProject)
+	}
+	return groups
+}
+
+// GroupFlat puts every issue into a single group without a header, ordered
+// by due date, falling back to creation date.
+func GroupFlat(issues []linear.Issue) []Group {
+	if len(issues) == 0 {
+		return nil
+	}
+	sorted := append([]linear.Issue(nil), issues...)
+	sortIssues(sorted)
+	return []Group{{Issues: sorted}}
+}
+
+// GroupByTeam groups issues by team, ordered by name.
+func GroupByTeam(issues []linear.Issue) []Group {
+	return groupInto(issues, func(issue linear.Issue) []bucket {
+		name := issue.Team.Name
+		if name == "" {
+			name = issue.Team.Key
+		}
+		return []bucket{{id: issue.Team.Id, name: name}}
+	})
+}
+
+// stateOrder ranks workflow state types the way Linear lists them.
+var stateOrder = map[string]int{"triage": 0, "backlog": 1, "unstarted": 2, "started": 3}
+
+// GroupByState groups issues by workflow state name, ordered by state type
+// from triage to started, then by the state's position in its team's
+// workflow. Teams' states with the same name share a group.
+func GroupByState(issues []linear.Issue) []Group {
+	return groupInto(issues, func(issue linear.Issue) []bucket {
+		rank, ok := stateOrder[issue.State.Type]
+		if !ok {
+			rank = len(stateOrder)
+		}
+		// Issues cached before state names were fetched only have the type
+		name := issue.State.Name
+		if name == "" {
+			name = capitalize(issue.State.Type)
+		}
+		return []bucket{{id: strings.ToLower(name), name: name, rank: float64(rank), position: issue.State.Position}}
+	})
+}
+
+// priorityNames are the headers of GroupByPriority, by Linear priority.
+var priorityNames = []string{"No Priority", "Urgent", "High", "Medium", "Low"}
+
+// GroupByPriority groups issues by priority, most urgent first. Issues
+// without a priority come last.
+func GroupByPriority(issues []linear.Issue) []Group {
+	return groupInto(issues, func(issue linear.Issue) []bucket {
+		priority := int(issue.Priority)
+		if priority <= 0 || priority >= len(priorityNames) {
+			return []bucket{none}
+		}
+		return []bucket{{id: priorityNames[priority], name: priorityNames[priority], rank: float64(priority)}}
+	})
+}
+
+// GroupByCycle groups issues by cycle, ordered by start date. Issues outside
+// any cycle come last.
+func GroupByCycle(issues []linear.Issue) []Group {
+	return groupInto(issues, func(issue linear.Issue) []bucket {
+		cycle := issue.Cycle
+		if cycle.Id == "" {
+			return []bucket{none}
+		}
+		return []bucket{{id: cycle.Id, name: cycleName(issue), rank: float64(parseLinearDate(cycle.StartsAt).Unix())}}
+	})
+}
+
+// GroupByLabel groups issues by label name, ordered by name. Labels with the
+// same name, like a workspace label and a team's, share a group. Issues with
+// several labels are listed under each of them, and those without any come
+// last.
+func GroupByLabel(issues []linear.Issue) []Group {
+	return groupInto(issues, func(issue linear.Issue) []bucket {
+		if len(issue.Labels.Nodes) == 0 {
+			return []bucket{none}
+		}
+		var buckets []bucket
+		for _, label := range issue.Labels.Nodes {
+			buckets = append(buckets, bucket{id: strings.ToLower(label.Name), name: label.Name})
+		}
+		return buckets
+	})
+}
+
+// Due date buckets of GroupByDueDate, in order.
+var dueBuckets = []string{"Overdue", "Today", "This Week", "Later"}
+
+// GroupByDueDate groups issues into Overdue, Today, This Week (the next
+// seven days) and Later, relative to now. Issues without a due date come
+// last.
+func GroupByDueDate(issues []linear.Issue, now time.Time) []Group {
+	// Due dates are calendar days, compare them with the local date
+	today := calendarDay(now)
+	return groupInto(issues, func(issue linear.Issue) []bucket {
+		due := parseLinearDate(issue.DueDate)
+		var i int
+		switch {
+		case due.Equal(distantFuture):
+			return []bucket{none}
+		case due.Before(today):
+			i = 0
+		case due.Equal(today):
+			i = 1
+		case due.Before(today.AddDate(0, 0, 8)):
+			i = 2
+		default:
+			i = 3
+		}
+		return []bucket{{id: dueBuckets[i], name: dueBuckets[i], rank: float64(i)}}
+	})
+}
+
+// bucket is a group an issue belongs to. Buckets are ordered by rank, then
+// by position, name and ID.
+type bucket struct {
+	id       string
+	name     string
+	rank     float64
+	position float64
+}
+
+// none holds the issues that lack what they are grouped by. It has no header
+// and comes last, like the issues without a project.
+var none = bucket{id: "__none__", rank: math.Inf(1)}
+
+// groupInto groups issues into the buckets returned for each of them. Issues
+// within a group are ordered by due date, falling back to creation date.
+func groupInto(issues []linear.Issue, buckets func(linear.Issue) []bucket) []Group {
+	byID := map[string]int{}
+	var keys []bucket
+	var groups []Group
+	for _, issue := range issues {
+		for _, b := range buckets(issue) {
+			i, ok := byID[b.id]
+			if !ok {
+				i = len(groups)
+				byID[b.id] = i
+				keys = append(keys, b)
+				groups = append(groups, Group{Name: b.name})
+			}
+			groups[i].Issues = append(groups[i].Issues, issue)
+		}
+	}
+
+	order := make([]int, len(groups))
+	for i := range order {
+		order[i] = i
+		sortIssues(groups[i].Issues)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := keys[order[i]], keys[order[j]]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.position != b.position {
+			return a.position < b.position
+		}
+		if an, bn := strings.ToLower(a.name), strings.ToLower(b.name); an != bn {
+			return an < bn
+		}
+		return a.id < b.id
+	})
+	sorted := make([]Group, 0, len(groups))
+	for _, i := range order {
+		sorted = append(sorted, groups[i])
+	}
+	return sorted
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// sortIssues orders issues by due date, falling back to creation date.
+func sortIssues(issues []linear.Issue) {
+	SortIssues(SortByDueDate, issues)
+}
+
+// effectiveDate is the issue's due date, or its creation date when it has none.
+func effectiveDate(issue linear.Issue) time.Time {
+	date := parseLinearDate(issue.DueDate)
+	if date.Equal(distantFuture) {
+		date = parseLinearDate(issue.CreatedAt)
+	}
+	return date
+}
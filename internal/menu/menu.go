@@ -0,0 +1,494 @@
+// Package menu turns Linear issues into a platform-neutral menu description
+// that the tray backends render.
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// maxInboxItems caps how many notifications the Inbox section lists by
+// default.
+const maxInboxItems = 10
+
+// ActionKind identifies what a renderer should do when an item is clicked.
+type ActionKind int
+
+const (
+	// ActionNone marks informational items that do nothing when clicked.
+	ActionNone ActionKind = iota
+	// ActionOpenURL opens Action.URL in the default browser.
+	ActionOpenURL
+	// ActionRefresh triggers an immediate refresh.
+	ActionRefresh
+	// ActionQuit exits the application.
+	ActionQuit
+	// ActionSetState moves Action.IssueID to the workflow state Action.StateID.
+	ActionSetState
+	// ActionCreateIssue prompts for a title and creates an issue in the team
+	// Action.TeamID.
+	ActionCreateIssue
+	// ActionOpenNotification opens Action.URL and marks the notification
+	// Action.NotificationID as read.
+	ActionOpenNotification
+	// ActionMarkRead marks the notification Action.NotificationID as read.
+	ActionMarkRead
+	// ActionArchive archives the notification Action.NotificationID.
+	ActionArchive
+	// ActionSignIn asks for a new API key, or runs the OAuth sign-in, for
+	// Action.Workspace.
+	ActionSignIn
+	// ActionOpenLogs opens the log file.
+	ActionOpenLogs
+	// ActionSetGrouping groups the issues by Action.Grouping.
+	ActionSetGrouping
+)
+
+// Action describes what happens when an item is clicked.
+type Action struct {
+	Kind    ActionKind
+	URL     string
+	IssueID string
+	StateID string
+	TeamID  string
+
+	NotificationID string
+	// Grouping is one of the grouping modes, e.g. ByProject.
+	Grouping string
+	// Workspace names the Linear workspace the action applies to.
+	Workspace string
+}
+
+// Item is a single clickable or informational menu entry.
+type Item struct {
+	Title   string
+	Tooltip string
+	// KeyEquivalent is an optional keyboard shortcut (e.g. "r").
+	KeyEquivalent string
+	Enabled       bool
+	// Checked marks the current choice among sibling items.
+	Checked bool
+	// Indicator, when set, is a colored dot drawn next to the title.
+	Indicator Indicator
+	// Urgency asks renderers to highlight the title: red when Overdue and
+	// orange when DueToday.
+	Urgency Urgency
+	Action  Action
+	// Submenu, when set, is shown when hovering over the item.
+	Submenu []Section
+}
+
+// Indicator marks an item with the color of its workflow state. The zero
+// value draws nothing.
+type Indicator struct {
+	// Color is a #rrggbb hex color.
+	Color string
+	// IconName is a freedesktop icon name for the state type, for hosts that
+	// only show themed icons.
+	IconName string
+}
+
+// Section is a group of items. Renderers draw a separator between sections
+// and, when Header is set, a disabled header item at the top.
+type Section struct {
+	Header string
+	Items  []Item
+}
+
+// Menu is the full contents of the tray menu.
+type Menu struct {
+	Sections []Section
+	// Badge, when set, is shown next to the tray icon, e.g. the number of
+	// overdue issues.
+	Badge string
+}
+
+// Data is everything the menu is built from.
+type Data struct {
+	Issues []linear.Issue
+	// States holds each team's workflow states, keyed by team ID. Issues of
+	// teams with known states get a submenu for moving them between states.
+	States map[string][]linear.WorkflowState
+	// Teams are offered by the New Issue item. It is left out when empty.
+	Teams []linear.Team
+	// Inbox, when set, adds an Inbox section above the issues.
+	Inbox   *linear.Inbox
+	Options Options
+}
+
+// Options tune how the menu is built. The zero value is the default menu.
+type Options struct {
+	// Grouping is how issues are split into sections, one of the grouping
+	// modes. Empty means ByProject.
+	Grouping string
+	// Sorting orders the issues within each group, one of the sort
+	// strategies. Empty means SortByDueDate.
+	Sorting      string
+	HideTooltips bool
+	HideInbox    bool
+	// InboxLimit caps the notifications listed in the Inbox section. Zero
+	// means 10.
+	InboxLimit int
+	// HighlightDue sets the Urgency of issue items.
+	HighlightDue bool
+	// DueSoonDays is how many days after today issues count as due soon.
+	DueSoonDays int
+	// Badge is one of the badge kinds, saying which issues the menu's badge
+	// counts. Empty means BadgeNone.
+	Badge string
+}
+
+// Loading is shown until the first fetch or cache load completes.
+func Loading() Menu {
+	return withFooter(Section{Items: []Item{disabled("Loading...")}})
+}
+
+// errorTitle describes why issues could not be fetched, in terms of OAuth
+// sign-ins or API keys as the workspace uses.
+func errorTitle(err error, oauth bool) string {
+	var limitErr *linear.RateLimitError
+	var gqlErr *linear.GraphQLError
+	switch {
+	case errors.Is(err, linear.ErrMissingAPIKey) && oauth:
+		return "Not signed in to Linear"
+	case errors.Is(err, linear.ErrMissingAPIKey):
+		return "No Linear API key"
+	case errors.Is(err, linear.ErrUnauthorized) && oauth:
+		return "Linear sign-in expired"
+	case errors.Is(err, linear.ErrUnauthorized):
+		return "Linear rejected the API key"
+	case errors.As(err, &limitErr):
+		return "Rate limited until " + limitErr.Until.Local().Format("15:04")
+	case errors.Is(err, linear.ErrOffline):
+		return "Linear is unreachable"
+	case errors.Is(err, linear.ErrSchemaMismatch):
+		return "Unexpected response from Linear"
+	case errors.As(err, &gqlErr):
+		return "Linear returned an error: " + gqlErr.Message
+	}
+	return "Error fetching issues"
+}
+
+// remedies are the items offered to fix err. Rate limits pass by
+// themselves, so they get none.
+func remedies(err error, oauth bool) []Item {
+	signIn := Item{Title: "Set API Key…", Enabled: true, Action: Action{Kind: ActionSignIn}}
+	switch {
+	case oauth && errors.Is(err, linear.ErrUnauthorized):
+		signIn.Title = "Sign In Again…"
+	case oauth:
+		signIn.Title = "Sign In…"
+	case errors.Is(err, linear.ErrUnauthorized):
+		signIn.Title = "Update API Key…"
+	}
+	retry := Item{Title: "Retry", Enabled: true, Action: Action{Kind: ActionRefresh}}
+	openLogs := Item{Title: "Open Logs", Enabled: true, Action: Action{Kind: ActionOpenLogs}}
+
+	var limitErr *linear.RateLimitError
+	switch {
+	case errors.Is(err, linear.ErrMissingAPIKey):
+		return []Item{signIn}
+	case errors.Is(err, linear.ErrUnauthorized):
+		return []Item{signIn, retry}
+	case errors.As(err, &limitErr):
+		return nil
+	case errors.Is(err, linear.ErrOffline):
+		return []Item{retry}
+	case errors.Is(err, linear.ErrSchemaMismatch):
+		return []Item{openLogs}
+	}
+	return []Item{retry, openLogs}
+}
+
+// errorSection describes the workspace's error and how to fix it. When
+// issues from an earlier fetch are still shown, it says how old they are.
+func errorSection(w Workspace) Section {
+	title := errorTitle(w.Err, w.OAuth)
+	if !w.UpdatedAt.IsZero() {
+		title = "⚠ " + title
+	}
+	section := Section{Items: []Item{disabled(title)}}
+	if !w.UpdatedAt.IsZero() {
+		section.Items = append(section.Items, disabled("Showing issues from "+since(w.UpdatedAt)))
+	}
+	section.Items = append(section.Items, remedies(w.Err, w.OAuth)...)
+	return section
+}
+
+// since formats t as a time of day, with the date unless it is today.
+func since(t time.Time) string {
+	t, now := t.Local(), time.Now()
+	if t.YearDay() == now.YearDay() && t.Year() == now.Year() {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan 2, 15:04")
+}
+
+// failedContent returns the sections of a workspace whose last fetch
+// failed: the error, followed by the last good issues if there are any.
+func failedContent(w Workspace) []Section {
+	sections := []Section{errorSection(w)}
+	if !w.UpdatedAt.IsZero() {
+		sections = append(sections, content(w.Data)...)
+	}
+	return sections
+}
+
+// Build creates the menu for a list of issues: one section per group, by
+// project unless Options.Grouping says otherwise, after the Inbox and
+// followed by the New Issue item and the standard footer. Its badge counts
+// the issues Options.Badge asks for.
+func Build(data Data) Menu {
+	m := withGrouping(withFooter(content(data)...), data.Options.Grouping)
+	m.Badge = badge([]Workspace{{Data: data}}, time.Now())
+	return m
+}
+
+// Workspace is the menu data of one Linear workspace.
+type Workspace struct {
+	Name string
+	Data Data
+	// Loading is set until the workspace's issues have been fetched or
+	// loaded from the cache.
+	Loading bool
+	// Err is set when the workspace's issues could not be fetched.
+	Err error
+	// UpdatedAt is when Data was fetched. When it is set along with Err,
+	// Data is the last good fetch and is shown below the error.
+	UpdatedAt time.Time
+	// OAuth is set when the workspace signs in with OAuth rather than an
+	// API key.
+	OAuth bool
+}
+
+// BuildWorkspaces creates the menu for one or more workspaces. A single
+// workspace gets the same menu as Build. With several, each workspace is a
+// top-level item whose submenu holds its inbox and issues. Every action is
+// tagged with the workspace it belongs to.
+func BuildWorkspaces(workspaces []Workspace) Menu {
+	if len(workspaces) == 1 {
+		w := workspaces[0]
+		var m Menu
+		switch {
+		case w.Loading:
+			m = Loading()
+		case w.Err != nil:
+			m = withGrouping(withFooter(failedContent(w)...), w.Data.Options.Grouping)
+			m.Badge = badge(workspaces, time.Now())
+		default:
+			m = Build(w.Data)
+		}
+		m.Sections = tagWorkspace(m.Sections, w.Name)
+		return m
+	}
+
+	section := Section{}
+	for _, w := range workspaces {
+		item := Item{Title: w.Name, Enabled: true}
+		switch {
+		case w.Loading:
+			item.Submenu = []Section{{Items: []Item{disabled("Loading...")}}}
+		case w.Err != nil:
+			item.Title += " (error)"
+			item.Submenu = tagWorkspace(failedContent(w), w.Name)
+		default:
+			if n := len(w.Data.Issues); n > 0 {
+				item.Title = fmt.Sprintf("%s (%d)", w.Name, n)
+			}
+			item.Submenu = tagWorkspace(content(w.Data), w.Name)
+		}
+		section.Items = append(section.Items, item)
+	}
+	// Every workspace is grouped the same way
+	m := withGrouping(withFooter(section), workspaces[0].Data.Options.Grouping)
+	m.Badge = badge(workspaces, time.Now())
+	return m
+}
+
+// tagWorkspace sets the workspace of every action in sections and their
+// submenus.
+func tagWorkspace(sections []Section, workspace string) []Section {
+	for i := range sections {
+		for j := range sections[i].Items {
+			item := &sections[i].Items[j]
+			if item.Action.Kind != ActionNone {
+				item.Action.Workspace = workspace
+			}
+			item.Submenu = tagWorkspace(item.Submenu, workspace)
+		}
+	}
+	return sections
+}
+
+// content returns the sections for a workspace's inbox and issues, without
+// the footer.
+func content(data Data) []Section {
+	opts := data.Options
+	var sections []Section
+	if data.Inbox != nil && len(data.Inbox.Notifications) > 0 && !opts.HideInbox {
+		sections = append(sections, inboxSection(*data.Inbox, opts.InboxLimit))
+	}
+	if len(data.Issues) == 0 {
+		sections = append(sections, Section{Items: []Item{disabled("No active assigned issues")}})
+	}
+	now := time.Now()
+	for _, group := range GroupBy(opts.Grouping, data.Issues, now) {
+		SortIssues(opts.Sorting, group.Issues)
+		section := Section{Header: group.Name}
+		for _, issue := range group.Issues {
+			item := IssueItem(issue, data.States[issue.Team.Id])
+			if opts.HideTooltips {
+				item.Tooltip = ""
+			}
+			if opts.HighlightDue {
+				item.Urgency = UrgencyOf(issue, now, opts.DueSoonDays)
+			}
+			section.Items = append(section.Items, item)
+		}
+		sections = append(sections, section)
+	}
+	if len(data.Teams) > 0 {
+		sections = append(sections, Section{Items: []Item{NewIssueItem(data.Teams)}})
+	}
+	return sections
+}
+
+// NewIssueItem creates the New Issue item. With a single team it creates the
+// issue there directly; otherwise it has a submenu for picking the team.
+func NewIssueItem(teams []linear.Team) Item {
+	item := Item{Title: "New Issue…", Enabled: true}
+	if len(teams) == 1 {
+		item.KeyEquivalent = "n"
+		item.Action = Action{Kind: ActionCreateIssue, TeamID: teams[0].Id}
+		return item
+	}
+
+	section := Section{Header: "Team"}
+	for _, team := range teams {
+		section.Items = append(section.Items, Item{
+			Title:   team.Name,
+			Tooltip: team.Key,
+			Enabled: true,
+			Action:  Action{Kind: ActionCreateIssue, TeamID: team.Id},
+		})
+	}
+	item.Submenu = []Section{section}
+	return item
+}
+
+// IssueItem creates the item for a single issue. Clicking it opens the issue
+// unless states are given, in which case it gets a submenu for opening it or
+// moving it to another workflow state.
+func IssueItem(issue linear.Issue, states []linear.WorkflowState) Item {
+	open := Action{Kind: ActionOpenURL, URL: issue.Url}
+	item := Item{
+		Title:     IssueTitle(issue),
+		Tooltip:   Tooltip(issue),
+		Enabled:   true,
+		Indicator: StateIndicator(issue.State.Type, issue.State.Color),
+		Action:    open,
+	}
+	if len(states) == 0 {
+		return item
+	}
+
+	moveTo := Section{Header: "Move to"}
+	for _, state := range states {
+		moveTo.Items = append(moveTo.Items, Item{
+			Title:     state.Name,
+			Enabled:   state.Id != issue.State.Id,
+			Checked:   state.Id == issue.State.Id,
+			Indicator: StateIndicator(state.Type, state.Color),
+			Action:    Action{Kind: ActionSetState, IssueID: issue.Id, StateID: state.Id},
+		})
+	}
+	item.Submenu = []Section{
+		{Items: []Item{{Title: "Open in Linear", Enabled: true, Action: open}}},
+		moveTo,
+	}
+	return item
+}
+
+// inboxSection lists the most recent limit notifications under a header with
+// the unread count. Unread notifications are marked with a dot.
+func inboxSection(inbox linear.Inbox, limit int) Section {
+	if limit <= 0 {
+		limit = maxInboxItems
+	}
+	section := Section{Header: "Inbox"}
+	if inbox.UnreadCount > 0 {
+		section.Header = fmt.Sprintf("Inbox (%d unread)", inbox.UnreadCount)
+	}
+
+	for i, notification := range inbox.Notifications {
+		if i == limit {
+			section.Items = append(section.Items, disabled(fmt.Sprintf("%d more in Linear", len(inbox.Notifications)-i)))
+			break
+		}
+		section.Items = append(section.Items, NotificationItem(notification))
+	}
+	return section
+}
+
+// NotificationItem creates the item for a notification, with a submenu for
+// opening, marking as read and archiving it.
+func NotificationItem(notification linear.Notification) Item {
+	unread := notification.ReadAt == ""
+	title := notification.Title
+	if unread {
+		title = "● " + title
+	}
+	return Item{
+		Title:   title,
+		Tooltip: notification.Subtitle,
+		Enabled: true,
+		Action:  Action{Kind: ActionOpenNotification, URL: notification.Url, NotificationID: notification.Id},
+		Submenu: []Section{{Items: []Item{
+			{Title: "Open in Linear", Enabled: true, Action: Action{Kind: ActionOpenNotification, URL: notification.Url, NotificationID: notification.Id}},
+			{Title: "Mark as Read", Enabled: unread, Action: Action{Kind: ActionMarkRead, NotificationID: notification.Id}},
+			{Title: "Archive", Enabled: true, Action: Action{Kind: ActionArchive, NotificationID: notification.Id}},
+		}}},
+	}
+}
+
+// withFooter appends the Refresh Now and Quit section shared by every menu.
+func withFooter(sections ...Section) Menu {
+	footer := Section{Items: []Item{
+		{Title: "Refresh Now", KeyEquivalent: "r", Enabled: true, Action: Action{Kind: ActionRefresh}},
+		{Title: "Quit Lil", Enabled: true, Action: Action{Kind: ActionQuit}},
+	}}
+	return Menu{Sections: append(sections, footer)}
+}
+
+// withGrouping adds the Group By item, with current checked, to the footer
+// of m.
+func withGrouping(m Menu, current string) Menu {
+	footer := &m.Sections[len(m.Sections)-1]
+	footer.Items = append([]Item{GroupingItem(current)}, footer.Items...)
+	return m
+}
+
+// GroupingItem creates the Group By item, whose submenu picks the grouping
+// mode.
+func GroupingItem(current string) Item {
+	if current == "" {
+		current = ByProject
+	}
+	var section Section
+	for _, g := range Groupings {
+		section.Items = append(section.Items, Item{
+			Title:   g.Title,
+			Enabled: true,
+			Checked: g.Mode == current,
+			Action:  Action{Kind: ActionSetGrouping, Grouping: g.Mode},
+		})
+	}
+	return Item{Title: "Group By", Enabled: true, Submenu: []Section{section}}
+}
+
+func disabled(title string) Item {
+	return Item{Title: title}
+}
@@ -0,0 +1,807 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/linear/schema"
+)
+
+// Test the parseLinearDate function with various date formats
+func TestParseLinearDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  time.Time
+		isDistant bool // whether the result should be the distantFuture value
+	}{
+		{
+			name:      "Empty string",
+			input:     "",
+			isDistant: true,
+		},
+		{
+			name:      "RFC3339 format",
+			input:     "2023-04-15T14:30:45Z",
+			expected:  time.Date(2023, 4, 15, 14, 30, 45, 0, time.UTC),
+			isDistant: false,
+		},
+		{
+			name:      "YYYY-MM-DD format",
+			input:     "2023-04-15",
+			expected:  time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC),
+			isDistant: false,
+		},
+		{
+			name:      "Invalid format",
+			input:     "15/04/2023",
+			isDistant: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := parseLinearDate(tc.input)
+
+			if tc.isDistant {
+				if !result.Equal(distantFuture) {
+					t.Errorf("Expected distant future date, got %v", result)
+				}
+			} else {
+				if !result.Equal(tc.expected) {
+					t.Errorf("Expected %v, got %v", tc.expected, result)
+				}
+			}
+		})
+	}
+}
+
+// testIssue builds an issue with the fields the grouping logic looks at.
+func testIssue(identifier, projectID, projectName, targetDate, dueDate, createdAt string) linear.Issue {
+	return linear.Issue{
+		Id:         "id-" + identifier,
+		Identifier: identifier,
+		Title:      "Issue " + identifier,
+		Url:        "https://linear.app/test/issue/" + identifier,
+		DueDate:    dueDate,
+		CreatedAt:  createdAt,
+		Project: schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueProject{
+			Id:         projectID,
+			Name:       projectName,
+			TargetDate: targetDate,
+		},
+	}
+}
+
+// Test project sorting based on earliest dates
+func TestProjectSorting(t *testing.T) {
+	issues := []linear.Issue{
+		testIssue("NP-1", "", "", "", "2023-03-01", ""),
+		testIssue("A-1", "a", "Project A", "2023-05-01", "", ""),
+		testIssue("B-1", "b", "Project B", "2023-04-01", "", ""),
+		// No target date: ordered by the earliest issue date instead
+		testIssue("C-1", "c", "Project C", "", "2023-06-01", ""),
+		testIssue("C-2", "c", "Project C", "", "", "2023-04-15T10:00:00Z"),
+	}
+
+	groups := GroupByProject(issues)
+
+	// Verify the order
+	expected := []string{"Project B", "Project C", "Project A", ""}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d", len(expected), len(groups))
+	}
+	for i, group := range groups {
+		if group.Name != expected[i] {
+			t.Errorf("Expected %q at position %d, got %q", expected[i], i, group.Name)
+		}
+	}
+
+	// Issues within a project are ordered by due date, falling back to creation date
+	projectC := groups[1]
+	if projectC.Issues[0].Identifier != "C-2" || projectC.Issues[1].Identifier != "C-1" {
+		t.Errorf("Expected Project C issues [C-2 C-1], got [%s %s]", projectC.Issues[0].Identifier, projectC.Issues[1].Identifier)
+	}
+}
+
+// Projects with the same name and date are ordered by project ID
+func TestProjectSortingSameName(t *testing.T) {
+	for range 20 {
+		groups := GroupByProject([]linear.Issue{
+			testIssue("Z-1", "z", "Launch", "2023-05-01", "", ""),
+			testIssue("A-1", "a", "Launch", "2023-05-01", "", ""),
+			testIssue("M-1", "m", "Launch", "2023-05-01", "", ""),
+		})
+		var got []string
+		for _, group := range groups {
+			got = append(got, group.Issues[0].Identifier)
+		}
+		if strings.Join(got, ",") != "A-1,M-1,Z-1" {
+			t.Fatalf("Expected projects A-1,M-1,Z-1, got %s", strings.Join(got, ","))
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	issues := []linear.Issue{
+		testIssue("A-1", "a", "Project A", "2023-05-01", "", ""),
+		testIssue("NP-1", "", "", "", "2023-03-01", ""),
+	}
+
+	m := Build(Data{Issues: issues})
+
+	// Project A, no project, footer
+	if len(m.Sections) != 3 {
+		t.Fatalf("Expected 3 sections, got %d", len(m.Sections))
+	}
+	if m.Sections[0].Header != "Project A" {
+		t.Errorf("Expected first header to be Project A, got %q", m.Sections[0].Header)
+	}
+	if m.Sections[1].Header != "" {
+		t.Errorf("Expected no-project section to have no header, got %q", m.Sections[1].Header)
+	}
+
+	item := m.Sections[0].Items[0]
+	if item.Title != "A-1: Issue A-1" {
+		t.Errorf("Unexpected item title %q", item.Title)
+	}
+	if item.Action.Kind != ActionOpenURL || item.Action.URL != issues[0].Url {
+		t.Errorf("Expected item to open %s, got %+v", issues[0].Url, item.Action)
+	}
+
+	footer := m.Sections[2].Items
+	if len(footer) != 3 || footer[0].Title != "Group By" || footer[1].Action.Kind != ActionRefresh || footer[2].Action.Kind != ActionQuit {
+		t.Errorf("Unexpected footer %+v", footer)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	issue := func(id, team, state string, priority float64, due string) linear.Issue {
+		i := linear.Issue{Id: id, Identifier: id, DueDate: due, Priority: priority}
+		i.Team.Id, i.Team.Key = "team-"+team, team
+		i.State.Type = state
+		return i
+	}
+	issues := []linear.Issue{
+		issue("ENG-1", "ENG", "started", 3, "2024-03-01"),
+		issue("OPS-1", "OPS", "backlog", 1, "2024-03-20"),
+		issue("ENG-2", "ENG", "unstarted", 0, ""),
+		issue("DES-1", "DES", "started", 2, "2024-02-20"),
+		issue("ENG-3", "ENG", "triage", 1, "2024-03-04"),
+	}
+	issues[0].Cycle.Id, issues[0].Cycle.Number, issues[0].Cycle.StartsAt = "c12", 12, "2024-02-26T00:00:00Z"
+	issues[1].Cycle.Id, issues[1].Cycle.Name, issues[1].Cycle.StartsAt = "c3", "Ops sprint", "2024-02-19T00:00:00Z"
+	issues[2].Cycle = issues[0].Cycle
+	// Workflow states are ordered by position rather than name, and issues
+	// cached without state names are grouped by type
+	issues[0].State.Name, issues[0].State.Position = "Doing", 1
+	issues[1].State.Name = "Backlog"
+	issues[2].State.Name = "Todo"
+	issues[3].State.Name, issues[3].State.Position = "Code Review", 2
+	// A workspace label and a team label with the same name share a group
+	issues[0].Labels.Nodes = []linear.Label{{Id: "bug", Name: "Bug"}, {Id: "ui", Name: "UI"}}
+	issues[3].Labels.Nodes = []linear.Label{{Id: "des-ui", Name: "UI"}}
+	now := time.Date(2024, 3, 1, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		mode   string
+		expect string
+	}{
+		{mode: ByProject, expect: ": DES-1 ENG-1 ENG-3 OPS-1 ENG-2"},
+		{mode: ByTeam, expect: "DES: DES-1 | ENG: ENG-1 ENG-3 ENG-2 | OPS: OPS-1"},
+		{mode: ByState, expect: "Triage: ENG-3 | Backlog: OPS-1 | Todo: ENG-2 | Doing: ENG-1 | Code Review: DES-1"},
+		{mode: ByPriority, expect: "Urgent: ENG-3 OPS-1 | High: DES-1 | Medium: ENG-1 | : ENG-2"},
+		{mode: ByCycle, expect: "Ops sprint: OPS-1 | Cycle 12: ENG-1 ENG-2 | : DES-1 ENG-3"},
+		{mode: ByLabel, expect: "Bug: ENG-1 | UI: DES-1 ENG-1 | : ENG-3 OPS-1 ENG-2"},
+		{mode: ByDueDate, expect: "Overdue: DES-1 | Today: ENG-1 | This Week: ENG-3 | Later: OPS-1 | : ENG-2"},
+		{mode: Flat, expect: ": DES-1 ENG-1 ENG-3 OPS-1 ENG-2"},
+		{mode: "", expect: ": DES-1 ENG-1 ENG-3 OPS-1 ENG-2"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			var groups []string
+			for _, group := range GroupBy(tc.mode, issues, now) {
+				var ids []string
+				for _, issue := range group.Issues {
+					ids = append(ids, issue.Identifier)
+				}
+				groups = append(groups, group.Name+": "+strings.Join(ids, " "))
+			}
+			if got := strings.Join(groups, " | "); got != tc.expect {
+				t.Errorf("Expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestGroupByTeamWithSameName(t *testing.T) {
+	// Teams with the same name are ordered by ID, whichever issue comes first
+	issue := func(id, team string) linear.Issue {
+		i := linear.Issue{Id: id, Identifier: id}
+		i.Team.Id, i.Team.Name = team, "Platform"
+		return i
+	}
+	for _, issues := range [][]linear.Issue{
+		{issue("B-1", "team-b"), issue("A-1", "team-a")},
+		{issue("A-1", "team-a"), issue("B-1", "team-b")},
+	} {
+		groups := GroupByTeam(issues)
+		if len(groups) != 2 || groups[0].Issues[0].Identifier != "A-1" || groups[1].Issues[0].Identifier != "B-1" {
+			t.Errorf("Expected team-a's group before team-b's, got %+v", groups)
+		}
+	}
+}
+
+func TestSortIssues(t *testing.T) {
+	issue := func(identifier string, priority, sortOrder float64, due, updated string) linear.Issue {
+		return linear.Issue{Identifier: identifier, Priority: priority, SortOrder: sortOrder, DueDate: due, UpdatedAt: updated, CreatedAt: "2024-01-01T00:00:00Z"}
+	}
+	issues := []linear.Issue{
+		issue("ENG-10", 2, 3, "2024-03-01", "2024-03-01T09:00:00Z"),
+		issue("ENG-9", 0, -1, "", "2024-03-01T12:00:00Z"),
+		issue("OPS-2", 2, 3, "2024-03-01", "2024-03-01T09:00:00Z"),
+		issue("ENG-2", 1, 10.5, "2024-03-05", "2024-02-01T09:00:00Z"),
+		issue("DES-4", 4, 0, "2024-02-01", "2024-03-01T10:00:00Z"),
+	}
+
+	tests := []struct {
+		strategy string
+		expect   string
+	}{
+		{strategy: SortByDueDate, expect: "ENG-9 DES-4 ENG-10 OPS-2 ENG-2"},
+		{strategy: SortByPriority, expect: "ENG-2 ENG-10 OPS-2 DES-4 ENG-9"},
+		{strategy: SortManual, expect: "ENG-9 DES-4 ENG-10 OPS-2 ENG-2"},
+		{strategy: SortByUpdated, expect: "ENG-9 DES-4 ENG-10 OPS-2 ENG-2"},
+		{strategy: SortByIdentifier, expect: "DES-4 ENG-2 ENG-9 ENG-10 OPS-2"},
+		{strategy: "", expect: "ENG-9 DES-4 ENG-10 OPS-2 ENG-2"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.strategy, func(t *testing.T) {
+			// Every starting order gives the same result
+			for shift := range issues {
+				sorted := append(slices.Clone(issues[shift:]), issues[:shift]...)
+				SortIssues(tc.strategy, sorted)
+				var ids []string
+				for _, issue := range sorted {
+					ids = append(ids, issue.Identifier)
+				}
+				if got := strings.Join(ids, " "); got != tc.expect {
+					t.Errorf("Starting at %d: expected %s, got %s", shift, tc.expect, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupingItem(t *testing.T) {
+	item := GroupingItem(ByLabel)
+	var checked []string
+	for _, choice := range item.Submenu[0].Items {
+		if choice.Action.Kind != ActionSetGrouping {
+			t.Errorf("Expected %q to set the grouping, got %+v", choice.Title, choice.Action)
+		}
+		if choice.Checked {
+			checked = append(checked, choice.Action.Grouping)
+		}
+	}
+	if len(item.Submenu[0].Items) != len(Groupings) || fmt.Sprint(checked) != "[label]" {
+		t.Errorf("Expected every mode with only label checked, got %v", checked)
+	}
+	if got := Build(Data{}).Sections; !got[len(got)-1].Items[0].Submenu[0].Items[0].Checked {
+		t.Error("Expected project grouping to be checked by default")
+	}
+}
+
+func TestBuildEmptyAndError(t *testing.T) {
+	if got := Build(Data{}).Sections[0].Items[0].Title; got != "No active assigned issues" {
+		t.Errorf("Unexpected empty title %q", got)
+	}
+	if got := errorSection(Workspace{Err: errors.New("boom")}).Items[0]; got.Title != "Error fetching issues" || got.Enabled {
+		t.Errorf("Unexpected error item %+v", got)
+	}
+	until := time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local)
+	if got := errorSection(Workspace{Err: fmt.Errorf("fetch: %w", &linear.RateLimitError{Until: until})}).Items[0].Title; got != "Rate limited until 14:30" {
+		t.Errorf("Unexpected rate limit title %q", got)
+	}
+	if got := Loading().Sections[0].Items[0].Title; got != "Loading..." {
+		t.Errorf("Unexpected loading title %q", got)
+	}
+}
+
+func TestErrorStates(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		oauth    bool
+		title    string
+		remedies []ActionKind
+		signIn   string
+	}{
+		{name: "Missing key", err: fmt.Errorf("fetch: %w", linear.ErrMissingAPIKey), title: "No Linear API key", remedies: []ActionKind{ActionSignIn}, signIn: "Set API Key…"},
+		{name: "Unauthorized", err: fmt.Errorf("%w: bad key", linear.ErrUnauthorized), title: "Linear rejected the API key", remedies: []ActionKind{ActionSignIn, ActionRefresh}, signIn: "Update API Key…"},
+		{name: "Signed out", err: linear.ErrMissingAPIKey, oauth: true, title: "Not signed in to Linear", remedies: []ActionKind{ActionSignIn}, signIn: "Sign In…"},
+		{name: "Sign-in expired", err: linear.ErrUnauthorized, oauth: true, title: "Linear sign-in expired", remedies: []ActionKind{ActionSignIn, ActionRefresh}, signIn: "Sign In Again…"},
+		{name: "Offline", err: fmt.Errorf("%w: no route to host", linear.ErrOffline), title: "Linear is unreachable", remedies: []ActionKind{ActionRefresh}},
+		{name: "Rate limited", err: &linear.RateLimitError{Until: time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local)}, title: "Rate limited until 14:30"},
+		{name: "GraphQL", err: &linear.GraphQLError{Message: "Entity not found", Code: "INVALID_INPUT"}, title: "Linear returned an error: Entity not found", remedies: []ActionKind{ActionRefresh, ActionOpenLogs}},
+		{name: "Schema mismatch", err: fmt.Errorf("%w: Cannot query field", linear.ErrSchemaMismatch), title: "Unexpected response from Linear", remedies: []ActionKind{ActionOpenLogs}},
+		{name: "Other", err: errors.New("boom"), title: "Error fetching issues", remedies: []ActionKind{ActionRefresh, ActionOpenLogs}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			items := errorSection(Workspace{Err: tc.err, OAuth: tc.oauth}).Items
+			if items[0].Title != tc.title || items[0].Enabled {
+				t.Errorf("Expected disabled %q, got %+v", tc.title, items[0])
+			}
+			var kinds []ActionKind
+			for _, item := range items[1:] {
+				kinds = append(kinds, item.Action.Kind)
+			}
+			if !slices.Equal(kinds, tc.remedies) {
+				t.Errorf("Expected remedies %v, got %v", tc.remedies, kinds)
+			}
+			if tc.signIn != "" && items[1].Title != tc.signIn {
+				t.Errorf("Expected %q, got %q", tc.signIn, items[1].Title)
+			}
+		})
+	}
+
+	signIn := BuildWorkspaces([]Workspace{{Name: "acme", Err: linear.ErrMissingAPIKey, OAuth: true}}).Sections[0].Items[1]
+	if signIn.Title != "Sign In…" || signIn.Action.Workspace != "acme" {
+		t.Errorf("Expected an OAuth sign-in for acme, got %+v", signIn)
+	}
+}
+
+func TestBuildStale(t *testing.T) {
+	updated := time.Now().Add(-time.Minute)
+	w := Workspace{
+		Name:      "acme",
+		Data:      Data{Issues: []linear.Issue{{Id: "1", Identifier: "A-1", Title: "Issue A-1"}}},
+		Err:       fmt.Errorf("%w: dial tcp", linear.ErrOffline),
+		UpdatedAt: updated,
+	}
+
+	m := BuildWorkspaces([]Workspace{w})
+	banner := m.Sections[0].Items
+	if banner[0].Title != "⚠ Linear is unreachable" || banner[1].Title != "Showing issues from "+updated.Format("15:04") {
+		t.Errorf("Unexpected stale banner %+v", banner)
+	}
+	if len(m.Sections) != 3 || m.Sections[1].Items[0].Title != "A-1: Issue A-1" {
+		t.Errorf("Expected the last good issues below the banner, got %+v", m.Sections)
+	}
+
+	m = BuildWorkspaces([]Workspace{w, {Name: "other", Loading: true}})
+	item := m.Sections[0].Items[0]
+	if item.Title != "acme (error)" || len(item.Submenu) != 2 || item.Submenu[1].Items[0].Title != "A-1: Issue A-1" {
+		t.Errorf("Expected the stale issues in the workspace submenu, got %+v", item)
+	}
+
+	w.UpdatedAt = time.Time{}
+	if m = BuildWorkspaces([]Workspace{w}); len(m.Sections) != 2 || m.Sections[0].Items[0].Title != "Linear is unreachable" {
+		t.Errorf("Expected only the error without earlier issues, got %+v", m.Sections)
+	}
+}
+
+// Test the logic for building tooltip content
+func TestIssueTitle(t *testing.T) {
+	issue := linear.Issue{Identifier: "ENG-1", Title: "Fix the thing"}
+	tests := []struct {
+		name     string
+		priority float64
+		labels   []linear.Label
+		expected string
+	}{
+		{name: "Plain", expected: "ENG-1: Fix the thing"},
+		{name: "Urgent", priority: 1, expected: "‼ ENG-1: Fix the thing"},
+		{name: "Low with labels", priority: 4, labels: []linear.Label{{Name: "Bug"}, {Name: "UI"}}, expected: "▂ ENG-1: Fix the thing [Bug] [UI]"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			issue := issue
+			issue.Priority, issue.Labels.Nodes = tc.priority, tc.labels
+			if got := IssueTitle(issue); got != tc.expected {
+				t.Errorf("Expected title %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTooltipContent(t *testing.T) {
+	issue := linear.Issue{
+		Id:         "issue1",
+		Identifier: "ABC-123",
+		Title:      "Test Issue",
+		DueDate:    "2023-06-01",
+		State: schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueStateWorkflowState{
+			Id:   "state1",
+			Type: "started",
+		},
+		Project: schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueProject{
+			Id:   "proj1",
+			Name: "Test Project",
+		},
+		Assignee: schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssueAssigneeUser{
+			Id:   "user1",
+			Name: "John Doe",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*linear.Issue)
+		expected string
+	}{
+		{
+			name:     "All fields present",
+			modify:   func(*linear.Issue) {},
+			expected: "Project: Test Project\nDue: Jun 1, 2023\nAssignee: John Doe\nStatus: started",
+		},
+		{
+			name:     "No project",
+			modify:   func(i *linear.Issue) { i.Project.Id = "" },
+			expected: "Due: Jun 1, 2023\nAssignee: John Doe\nStatus: started",
+		},
+		{
+			name:     "Display name preferred",
+			modify:   func(i *linear.Issue) { i.Assignee.DisplayName = "jdoe" },
+			expected: "Project: Test Project\nDue: Jun 1, 2023\nAssignee: jdoe\nStatus: started",
+		},
+		{
+			name: "Details",
+			modify: func(i *linear.Issue) {
+				i.Team.Key, i.Team.Name = "ABC", "Alphabet"
+				i.Cycle.Id, i.Cycle.Number = "cycle1", 7
+				i.Priority, i.PriorityLabel = 2, "High"
+				i.Estimate = 0.5
+				i.Labels.Nodes = []linear.Label{{Name: "Bug"}, {Name: "UI"}}
+				i.State.Name = "In Review"
+			},
+			expected: "Project: Test Project\nTeam: Alphabet (ABC)\nCycle: Cycle 7\nDue: Jun 1, 2023\nPriority: High\nEstimate: 0.5 points\nLabels: Bug, UI\nAssignee: John Doe\nStatus: In Review",
+		},
+		{
+			name: "Named cycle",
+			modify: func(i *linear.Issue) {
+				i.Project.Id, i.DueDate, i.Assignee.Id, i.State.Id = "", "", "", ""
+				i.Cycle.Id, i.Cycle.Name = "cycle1", "Hardening"
+				i.Estimate = 1
+			},
+			expected: "Cycle: Hardening\nEstimate: 1 point",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			issue := issue
+			tc.modify(&issue)
+			if got := Tooltip(issue); got != tc.expected {
+				t.Errorf("Expected tooltip %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestIssueItemStates(t *testing.T) {
+	issue := testIssue("ENG-1", "", "", "", "", "")
+	issue.State.Id = "progress"
+	issue.State.Type = "started"
+	issue.State.Color = "#F2C94C"
+
+	if item := IssueItem(issue, nil); item.Submenu != nil || item.Action.Kind != ActionOpenURL {
+		t.Errorf("Expected a plain clickable item without states, got %+v", item)
+	}
+	if item := IssueItem(issue, nil); item.Indicator != (Indicator{Color: "#f2c94c", IconName: "media-playback-start"}) {
+		t.Errorf("Expected the issue's state indicator, got %+v", item.Indicator)
+	}
+
+	states := []linear.WorkflowState{
+		{Id: "todo", Name: "Todo", Type: "unstarted", Color: "#e2e2e2"},
+		{Id: "progress", Name: "In Progress", Type: "started", Color: "#f2c94c"},
+		{Id: "done", Name: "Done", Type: "completed", Color: "#5e6ad2"},
+	}
+	item := IssueItem(issue, states)
+	if len(item.Submenu) != 2 {
+		t.Fatalf("Expected open and move-to sections, got %d", len(item.Submenu))
+	}
+	if open := item.Submenu[0].Items[0]; open.Action.Kind != ActionOpenURL || open.Action.URL != issue.Url {
+		t.Errorf("Expected first submenu item to open the issue, got %+v", open)
+	}
+
+	moveTo := item.Submenu[1].Items
+	if len(moveTo) != 3 {
+		t.Fatalf("Expected 3 states, got %d", len(moveTo))
+	}
+	for i, state := range states {
+		current := state.Id == issue.State.Id
+		if moveTo[i].Title != state.Name || moveTo[i].Checked != current || moveTo[i].Enabled == current {
+			t.Errorf("Unexpected item for state %s: %+v", state.Id, moveTo[i])
+		}
+		if moveTo[i].Indicator.Color != state.Color {
+			t.Errorf("Expected state %s to be marked %s, got %+v", state.Id, state.Color, moveTo[i].Indicator)
+		}
+		if moveTo[i].Action != (Action{Kind: ActionSetState, IssueID: issue.Id, StateID: state.Id}) {
+			t.Errorf("Unexpected action for state %s: %+v", state.Id, moveTo[i].Action)
+		}
+	}
+}
+
+func TestStateIndicator(t *testing.T) {
+	testCases := []struct {
+		name      string
+		stateType string
+		color     string
+		expected  Indicator
+	}{
+		{"Hex color", "started", "#f2c94c", Indicator{Color: "#f2c94c", IconName: "media-playback-start"}},
+		{"Uppercase without hash", "unstarted", "E2E2E2", Indicator{Color: "#e2e2e2", IconName: "media-playback-pause"}},
+		{"Short hex", "triage", "#FC4", Indicator{Color: "#ffcc44", IconName: "dialog-question"}},
+		{"Invalid color", "backlog", "#bcbcbz", Indicator{IconName: "media-playback-stop"}},
+		{"Named color", "canceled", "red", Indicator{IconName: "process-stop"}},
+		{"Unknown type", "", "", Indicator{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := StateIndicator(tc.stateType, tc.color); got != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestIndicatorRGB(t *testing.T) {
+	r, g, b, ok := Indicator{Color: "#ff8000"}.RGB()
+	if !ok || r != 1 || g != float64(0x80)/255 || b != 0 {
+		t.Errorf("Expected (1, %v, 0), got (%v, %v, %v, %v)", float64(0x80)/255, r, g, b, ok)
+	}
+	if _, _, _, ok := (Indicator{IconName: "process-stop"}).RGB(); ok {
+		t.Error("Expected no color for an indicator without one")
+	}
+}
+
+func TestNewIssueItem(t *testing.T) {
+	if m := Build(Data{}); len(m.Sections) != 2 {
+		t.Errorf("Expected no New Issue item without teams, got %d sections", len(m.Sections))
+	}
+
+	single := []linear.Team{{Id: "eng", Key: "ENG", Name: "Engineering"}}
+	m := Build(Data{Teams: single})
+	item := m.Sections[len(m.Sections)-2].Items[0]
+	if item.Title != "New Issue…" || item.Action.Kind != ActionCreateIssue || item.Action.TeamID != "eng" {
+		t.Errorf("Expected New Issue to create in eng directly, got %+v", item)
+	}
+	if len(item.Submenu) != 0 {
+		t.Errorf("Expected no team submenu for a single team, got %+v", item.Submenu)
+	}
+
+	item = NewIssueItem(append(single, linear.Team{Id: "ops", Key: "OPS", Name: "Operations"}))
+	if item.Action.Kind != ActionNone || len(item.Submenu) != 1 {
+		t.Fatalf("Expected a team submenu, got %+v", item)
+	}
+	teams := item.Submenu[0].Items
+	if len(teams) != 2 || teams[1].Title != "Operations" || teams[1].Action.Kind != ActionCreateIssue || teams[1].Action.TeamID != "ops" {
+		t.Errorf("Unexpected team items %+v", teams)
+	}
+}
+
+func TestInboxSection(t *testing.T) {
+	inbox := &linear.Inbox{UnreadCount: 1, Notifications: []linear.Notification{
+		{Id: "n1", Title: "ENG-1 Ship it", Subtitle: "Ada commented", Url: "https://linear.app/acme/issue/ENG-1"},
+		{Id: "n2", Title: "Rocket", ReadAt: "2024-01-01T00:00:00Z"},
+	}}
+
+	m := Build(Data{Inbox: inbox})
+	section := m.Sections[0]
+	if section.Header != "Inbox (1 unread)" {
+		t.Errorf("Unexpected inbox header %q", section.Header)
+	}
+	if len(section.Items) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(section.Items))
+	}
+
+	unread, read := section.Items[0], section.Items[1]
+	if unread.Title != "● ENG-1 Ship it" || read.Title != "Rocket" {
+		t.Errorf("Expected only unread notifications to be marked, got %q and %q", unread.Title, read.Title)
+	}
+	if unread.Tooltip != "Ada commented" {
+		t.Errorf("Expected the subtitle as tooltip, got %q", unread.Tooltip)
+	}
+	actions := unread.Submenu[0].Items
+	if actions[0].Action.Kind != ActionOpenNotification || actions[0].Action.URL != "https://linear.app/acme/issue/ENG-1" || actions[0].Action.NotificationID != "n1" {
+		t.Errorf("Unexpected open action %+v", actions[0].Action)
+	}
+	if !actions[1].Enabled || actions[1].Action.Kind != ActionMarkRead {
+		t.Errorf("Expected unread notification to offer Mark as Read, got %+v", actions[1])
+	}
+	if read.Submenu[0].Items[1].Enabled {
+		t.Error("Expected Mark as Read to be disabled for a read notification")
+	}
+	if actions[2].Action.Kind != ActionArchive || actions[2].Action.NotificationID != "n1" {
+		t.Errorf("Unexpected archive action %+v", actions[2].Action)
+	}
+
+	if got := Build(Data{Inbox: &linear.Inbox{}}).Sections[0].Header; got != "" {
+		t.Errorf("Expected an empty inbox to be left out, got section %q", got)
+	}
+
+	for i := 0; i < maxInboxItems+2; i++ {
+		inbox.Notifications = append(inbox.Notifications, linear.Notification{Id: "more"})
+	}
+	section = inboxSection(*inbox, 0)
+	if len(section.Items) != maxInboxItems+1 || section.Items[maxInboxItems].Title != "4 more in Linear" {
+		t.Errorf("Expected the inbox to be capped at %d items, got %d ending with %q", maxInboxItems, len(section.Items), section.Items[len(section.Items)-1].Title)
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	issues := []linear.Issue{
+		testIssue("A-1", "a", "Project A", "2023-05-01", "", ""),
+		testIssue("B-1", "b", "Project B", "2023-04-01", "", ""),
+		testIssue("NP-1", "", "", "", "2023-03-01", ""),
+	}
+	inbox := &linear.Inbox{Notifications: []linear.Notification{{Id: "n1"}, {Id: "n2"}, {Id: "n3"}}}
+
+	m := Build(Data{Issues: issues, Inbox: inbox, Options: Options{Grouping: Flat, HideTooltips: true, InboxLimit: 2}})
+	// Inbox, issues, footer
+	if len(m.Sections) != 3 {
+		t.Fatalf("Expected 3 sections, got %d", len(m.Sections))
+	}
+	if got := len(m.Sections[0].Items); got != 3 {
+		t.Errorf("Expected 2 notifications and a more item, got %d items", got)
+	}
+	flat := m.Sections[1]
+	if flat.Header != "" || len(flat.Items) != 3 {
+		t.Fatalf("Expected one headerless section with every issue, got %+v", flat)
+	}
+	if flat.Items[0].Title != "NP-1: Issue NP-1" {
+		t.Errorf("Expected flat issues ordered by date, got %q first", flat.Items[0].Title)
+	}
+	for _, item := range flat.Items {
+		if item.Tooltip != "" {
+			t.Errorf("Expected no tooltip, got %q", item.Tooltip)
+		}
+	}
+	m = Build(Data{Issues: issues, Options: Options{Grouping: Flat, Sorting: SortByIdentifier}})
+	if got := m.Sections[0].Items[0].Title; got != "A-1: Issue A-1" {
+		t.Errorf("Expected flat issues ordered by identifier, got %q first", got)
+	}
+
+	m = Build(Data{Issues: issues, Inbox: inbox, Options: Options{HideInbox: true}})
+	if m.Sections[0].Header != "Project B" {
+		t.Errorf("Expected the inbox to be hidden, got section %q first", m.Sections[0].Header)
+	}
+}
+
+func TestUrgencyOf(t *testing.T) {
+	now := time.Date(2023, 6, 14, 18, 0, 0, 0, time.Local)
+	testCases := []struct {
+		name     string
+		dueDate  string
+		soonDays int
+		expected Urgency
+	}{
+		{"No due date", "", 7, NotDue},
+		{"Yesterday", "2023-06-13", 7, Overdue},
+		{"Today", "2023-06-14", 7, DueToday},
+		{"Tomorrow", "2023-06-15", 7, DueSoon},
+		{"Last day of due soon", "2023-06-21", 7, DueSoon},
+		{"After due soon", "2023-06-22", 7, NotDue},
+		{"Tomorrow without due soon", "2023-06-15", 0, NotDue},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issue := testIssue("ENG-1", "", "", "", tc.dueDate, "")
+			if got := UrgencyOf(issue, now, tc.soonDays); got != tc.expected {
+				t.Errorf("Expected urgency %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCountDue(t *testing.T) {
+	now := time.Date(2023, 6, 14, 9, 0, 0, 0, time.Local)
+	issues := []linear.Issue{
+		testIssue("A-1", "", "", "", "2023-06-01", ""),
+		testIssue("A-2", "", "", "", "2023-06-13", ""),
+		testIssue("A-3", "", "", "", "2023-06-14", ""),
+		testIssue("A-4", "", "", "", "2023-06-16", ""),
+		testIssue("A-5", "", "", "", "2023-07-01", ""),
+		testIssue("A-6", "", "", "", "", ""),
+	}
+
+	counts := CountDue(issues, now, 7)
+	if counts != (DueCounts{Overdue: 2, Today: 1, Soon: 1}) {
+		t.Fatalf("Unexpected counts %+v", counts)
+	}
+	for kind, expected := range map[string]int{BadgeNone: 0, "": 0, BadgeOverdue: 2, BadgeToday: 3, BadgeSoon: 4} {
+		if got := counts.Badge(kind); got != expected {
+			t.Errorf("Expected badge %q to count %d, got %d", kind, expected, got)
+		}
+	}
+}
+
+func TestBuildHighlightsDue(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	issues := []linear.Issue{
+		testIssue("A-1", "", "", "", yesterday, ""),
+		testIssue("A-2", "", "", "", today, ""),
+		testIssue("A-3", "", "", "", "", ""),
+	}
+
+	m := Build(Data{Issues: issues})
+	if m.Badge != "" || m.Sections[0].Items[0].Urgency != NotDue {
+		t.Errorf("Expected no badge or highlighting by default, got %q and %+v", m.Badge, m.Sections[0].Items[0])
+	}
+
+	opts := Options{HighlightDue: true, DueSoonDays: 7, Badge: BadgeToday}
+	m = Build(Data{Issues: issues, Options: opts})
+	if m.Badge != "2" {
+		t.Errorf("Expected a badge counting 2 issues, got %q", m.Badge)
+	}
+	for i, expected := range []Urgency{Overdue, DueToday, NotDue} {
+		if got := m.Sections[0].Items[i].Urgency; got != expected {
+			t.Errorf("Expected item %d to have urgency %d, got %d", i, expected, got)
+		}
+	}
+
+	// Stale issues still count, those of failed or loading workspaces don't
+	m = BuildWorkspaces([]Workspace{
+		{Name: "acme", Data: Data{Issues: issues, Options: opts}},
+		{Name: "globex", Data: Data{Issues: issues, Options: opts}, Err: errors.New("offline"), UpdatedAt: time.Now()},
+		{Name: "initech", Data: Data{Issues: issues, Options: opts}, Err: errors.New("unauthorized")},
+		{Name: "umbrella", Loading: true},
+	})
+	if m.Badge != "4" {
+		t.Errorf("Expected a badge counting 4 issues, got %q", m.Badge)
+	}
+}
+
+func TestBuildWorkspaces(t *testing.T) {
+	issues := []linear.Issue{testIssue("A-1", "a", "Project A", "2023-05-01", "", "")}
+
+	single := BuildWorkspaces([]Workspace{{Name: "acme", Data: Data{Issues: issues}}})
+	if len(single.Sections) != 2 || single.Sections[0].Header != "Project A" {
+		t.Fatalf("Expected a single workspace to get the plain menu, got %+v", single.Sections)
+	}
+	if got := single.Sections[0].Items[0].Action.Workspace; got != "acme" {
+		t.Errorf("Expected actions to be tagged with the workspace, got %q", got)
+	}
+	if got := BuildWorkspaces([]Workspace{{Name: "acme", Loading: true}}).Sections[0].Items[0].Title; got != "Loading..." {
+		t.Errorf("Expected the loading menu, got %q", got)
+	}
+
+	m := BuildWorkspaces([]Workspace{
+		{Name: "acme", Data: Data{Issues: issues}},
+		{Name: "globex", Err: errors.New("unauthorized")},
+		{Name: "initech", Loading: true},
+	})
+	// Workspaces, footer
+	if len(m.Sections) != 2 {
+		t.Fatalf("Expected 2 sections, got %d", len(m.Sections))
+	}
+	items := m.Sections[0].Items
+	titles := []string{}
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+	if got := strings.Join(titles, "|"); got != "acme (1)|globex (error)|initech" {
+		t.Errorf("Unexpected workspace items %s", got)
+	}
+
+	issue := items[0].Submenu[0].Items[0]
+	if issue.Title != "A-1: Issue A-1" || issue.Action.Workspace != "acme" {
+		t.Errorf("Expected the workspace's issues in its submenu, got %+v", issue)
+	}
+	if got := items[1].Submenu[0].Items[0].Title; got != "Error fetching issues" {
+		t.Errorf("Unexpected error submenu %q", got)
+	}
+	if footer := m.Sections[1].Items[1]; footer.Action.Kind != ActionRefresh || footer.Action.Workspace != "" {
+		t.Errorf("Expected a shared, untagged footer, got %+v", footer)
+	}
+}
@@ -0,0 +1,107 @@
+package menu
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// Sort strategies for the issues within a group.
+const (
+	// SortByDueDate orders issues by due date, falling back to creation
+	// date.
+	SortByDueDate = "due"
+	// SortByPriority orders issues from urgent to low, then by due date.
+	// Issues without a priority come last.
+	SortByPriority = "priority"
+	// SortManual keeps the order issues were dragged into in Linear.
+	SortManual = "manual"
+	// SortByUpdated puts the most recently updated issues first.
+	SortByUpdated = "updated"
+	// SortByIdentifier orders issues by team key and number.
+	SortByIdentifier = "identifier"
+)
+
+// SortStrategies lists the sort strategies.
+var SortStrategies = []string{SortByDueDate, SortByPriority, SortManual, SortByUpdated, SortByIdentifier}
+
+// sorts compares issues for each sort strategy. A result of zero leaves the
+// order to the identifier.
+var sorts = map[string]func(a, b linear.Issue) int{
+	SortByDueDate: compareDates,
+	SortByPriority: func(a, b linear.Issue) int {
+		if c := comparePriorities(a.Priority, b.Priority); c != 0 {
+			return c
+		}
+		return compareDates(a, b)
+	},
+	SortManual: func(a, b linear.Issue) int {
+		return compareFloats(a.SortOrder, b.SortOrder)
+	},
+	SortByUpdated: func(a, b linear.Issue) int {
+		return parseLinearDate(b.UpdatedAt).Compare(parseLinearDate(a.UpdatedAt))
+	},
+	SortByIdentifier: func(a, b linear.Issue) int { return 0 },
+}
+
+// SortIssues orders issues in place by strategy, one of the sort strategies.
+// Ties are broken by identifier, so equal issues keep their places across
+// refreshes. Unknown strategies sort by due date.
+func SortIssues(strategy string, issues []linear.Issue) {
+	compare, ok := sorts[strategy]
+	if !ok {
+		compare = compareDates
+	}
+	slices.SortStableFunc(issues, func(a, b linear.Issue) int {
+		if c := compare(a, b); c != 0 {
+			return c
+		}
+		return compareIdentifiers(a.Identifier, b.Identifier)
+	})
+}
+
+// compareDates compares the due dates of issues, falling back to their
+// creation dates.
+func compareDates(a, b linear.Issue) int {
+	return effectiveDate(a).Compare(effectiveDate(b))
+}
+
+// comparePriorities compares Linear priorities, which run from 1 (urgent) to
+// 4 (low) with 0 meaning none.
+func comparePriorities(a, b float64) int {
+	if a == 0 {
+		a = 5
+	}
+	if b == 0 {
+		b = 5
+	}
+	return compareFloats(a, b)
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifiers orders identifiers like ENG-9 and ENG-10 by team key,
+// then by number.
+func compareIdentifiers(a, b string) int {
+	keyA, numA, _ := strings.Cut(a, "-")
+	keyB, numB, _ := strings.Cut(b, "-")
+	if c := strings.Compare(keyA, keyB); c != 0 {
+		return c
+	}
+	nA, errA := strconv.Atoi(numA)
+	nB, errB := strconv.Atoi(numB)
+	if errA != nil || errB != nil {
+		return strings.Compare(numA, numB)
+	}
+	return nA - nB
+}
@@ -0,0 +1,117 @@
+package menu
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// Urgency is how close an issue is to its due date.
+type Urgency int
+
+// Urgencies, from least to most urgent.
+const (
+	// NotDue issues have no due date, or one further away than due soon.
+	NotDue Urgency = iota
+	DueSoon
+	DueToday
+	Overdue
+)
+
+// Badge kinds, naming the least urgent issues the badge counts.
+const (
+	BadgeNone    = "none"
+	BadgeOverdue = "overdue"
+	BadgeToday   = "today"
+	BadgeSoon    = "soon"
+)
+
+// Badges lists the badge kinds.
+var Badges = []string{BadgeNone, BadgeOverdue, BadgeToday, BadgeSoon}
+
+// badgeUrgencies maps the badge kinds to the urgency they count from.
+var badgeUrgencies = map[string]Urgency{
+	BadgeOverdue: Overdue,
+	BadgeToday:   DueToday,
+	BadgeSoon:    DueSoon,
+}
+
+// calendarDay is the local date of t, in the UTC form parseLinearDate gives
+// due dates.
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// UrgencyOf says how close issue is to its due date on the day of now.
+// Issues due at most soonDays days after today are DueSoon.
+func UrgencyOf(issue linear.Issue, now time.Time, soonDays int) Urgency {
+	due := parseLinearDate(issue.DueDate)
+	today := calendarDay(now)
+	switch {
+	case due.Equal(distantFuture):
+		return NotDue
+	case due.Before(today):
+		return Overdue
+	case due.Equal(today):
+		return DueToday
+	case !due.After(today.AddDate(0, 0, soonDays)):
+		return DueSoon
+	}
+	return NotDue
+}
+
+// DueCounts are the numbers of issues that are overdue, due today and due
+// soon.
+type DueCounts struct {
+	Overdue int
+	Today   int
+	Soon    int
+}
+
+// CountDue counts issues by urgency, as UrgencyOf sees them.
+func CountDue(issues []linear.Issue, now time.Time, soonDays int) DueCounts {
+	var counts DueCounts
+	for _, issue := range issues {
+		switch UrgencyOf(issue, now, soonDays) {
+		case Overdue:
+			counts.Overdue++
+		case DueToday:
+			counts.Today++
+		case DueSoon:
+			counts.Soon++
+		}
+	}
+	return counts
+}
+
+// Badge is the number of issues a badge of kind counts: those at least as
+// urgent as it names. Unknown kinds count nothing.
+func (c DueCounts) Badge(kind string) int {
+	switch badgeUrgencies[kind] {
+	case Overdue:
+		return c.Overdue
+	case DueToday:
+		return c.Overdue + c.Today
+	case DueSoon:
+		return c.Overdue + c.Today + c.Soon
+	}
+	return 0
+}
+
+// badge is the text of the tray badge for the issues shown of workspaces, or
+// empty when there is nothing to count.
+func badge(workspaces []Workspace, now time.Time) string {
+	total := 0
+	for _, w := range workspaces {
+		if w.Loading || (w.Err != nil && w.UpdatedAt.IsZero()) {
+			continue
+		}
+		opts := w.Data.Options
+		total += CountDue(w.Data.Issues, now, opts.DueSoonDays).Badge(opts.Badge)
+	}
+	if total == 0 {
+		return ""
+	}
+	return strconv.Itoa(total)
+}
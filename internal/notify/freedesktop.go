@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Desktop Notifications D-Bus names, see
+// https://specifications.freedesktop.org/notification-spec/latest/
+const (
+	notificationsName  = "org.freedesktop.Notifications"
+	notificationsPath  = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notificationsIface = "org.freedesktop.Notifications"
+)
+
+// Freedesktop shows notifications through the org.freedesktop.Notifications
+// service of Linux desktops.
+type Freedesktop struct {
+	// conn returns the session bus connection to use.
+	conn func() (*dbus.Conn, error)
+	// AppName is shown as the sender of the notifications.
+	AppName string
+}
+
+// NewFreedesktop returns a notifier using the notification service on the
+// session bus.
+func NewFreedesktop() *Freedesktop {
+	return &Freedesktop{conn: dbus.SessionBus, AppName: "Lil"}
+}
+
+func (f *Freedesktop) Notify(n Notification) error {
+	conn, err := f.conn()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	hints := map[string]dbus.Variant{}
+	call := conn.Object(notificationsName, notificationsPath).Call(notificationsIface+".Notify", 0,
+		f.AppName, uint32(0), "", n.Title, n.Body, []string{}, hints, int32(-1))
+	if call.Err != nil {
+		return fmt.Errorf("failed to show notification: %w", call.Err)
+	}
+	return nil
+}
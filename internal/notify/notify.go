@@ -0,0 +1,184 @@
+// Package notify tells the user about new and changed issues with desktop
+// notifications.
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// MaxNotifications is how many notifications one refresh may show before
+// they are folded into a single summary.
+const MaxNotifications = 3
+
+// Kind is what happened to an issue.
+type Kind int
+
+const (
+	// Assigned is an issue newly assigned to the user.
+	Assigned Kind = iota
+	// DueDateChanged is an issue whose due date was set, moved or removed.
+	DueDateChanged
+	// StateChanged is an issue moved to another workflow state.
+	StateChanged
+	// PriorityRaised is an issue whose priority went up.
+	PriorityRaised
+	// Summary stands in for more than MaxNotifications changes.
+	Summary
+)
+
+// Notification is a single desktop notification.
+type Notification struct {
+	Kind Kind
+	// IssueID and Identifier name the issue the notification is about. They
+	// are empty for summaries.
+	IssueID    string
+	Identifier string
+	Title      string
+	Body       string
+	// URL opens the issue.
+	URL string
+}
+
+// Notifier shows desktop notifications.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// Nop is a Notifier that shows nothing, for systems without a backend.
+type Nop struct{}
+
+func (Nop) Notify(Notification) error { return nil }
+
+// Recorder is a Notifier that records notifications instead of showing
+// them, for tests.
+type Recorder struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (r *Recorder) Notify(n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+// Notifications returns what was recorded so far.
+func (r *Recorder) Notifications() []Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Notification(nil), r.notifications...)
+}
+
+// Options select which changes are notified about.
+type Options struct {
+	Assigned bool
+	DueDate  bool
+	State    bool
+	Priority bool
+}
+
+// All notifies about every kind of change.
+var All = Options{Assigned: true, DueDate: true, State: true, Priority: true}
+
+// Changes returns the notifications for going from the old to the new list
+// of assigned issues. Issues that left the list aren't notified about; the
+// user usually moved them out themselves.
+func Changes(old, new []linear.Issue, opts Options) []Notification {
+	before := make(map[string]linear.Issue, len(old))
+	for _, issue := range old {
+		before[issue.Id] = issue
+	}
+
+	var notifications []Notification
+	add := func(kind Kind, issue linear.Issue, title string) {
+		notifications = append(notifications, Notification{Kind: kind, IssueID: issue.Id, Identifier: issue.Identifier, Title: title, Body: issue.Title, URL: issue.Url})
+	}
+	for _, issue := range new {
+		prev, ok := before[issue.Id]
+		if !ok {
+			if opts.Assigned {
+				add(Assigned, issue, issue.Identifier+" was assigned to you")
+			}
+			continue
+		}
+		if opts.State && prev.State.Id != issue.State.Id {
+			add(StateChanged, issue, fmt.Sprintf("%s moved to %s", issue.Identifier, stateName(issue)))
+		}
+		if opts.DueDate && prev.DueDate != issue.DueDate {
+			if issue.DueDate == "" {
+				add(DueDateChanged, issue, issue.Identifier+" no longer has a due date")
+			} else {
+				add(DueDateChanged, issue, fmt.Sprintf("%s is now due %s", issue.Identifier, dueDate(issue.DueDate)))
+			}
+		}
+		if opts.Priority && raised(prev.Priority, issue.Priority) {
+			add(PriorityRaised, issue, fmt.Sprintf("%s raised to %s priority", issue.Identifier, priorityName(issue.Priority)))
+		}
+	}
+	return notifications
+}
+
+// Coalesce folds more than MaxNotifications notifications into a summary
+// naming the issues involved.
+func Coalesce(notifications []Notification, workspace string) []Notification {
+	if len(notifications) <= MaxNotifications {
+		return notifications
+	}
+	var identifiers []string
+	seen := map[string]bool{}
+	for _, n := range notifications {
+		if !seen[n.Identifier] {
+			seen[n.Identifier] = true
+			identifiers = append(identifiers, n.Identifier)
+		}
+	}
+	title := fmt.Sprintf("%d updates to your issues", len(notifications))
+	if workspace != "" {
+		title += " in " + workspace
+	}
+	return []Notification{{Kind: Summary, Title: title, Body: strings.Join(identifiers, ", ")}}
+}
+
+// raised reports whether priority went up. Linear's priorities run from 1
+// (urgent) to 4 (low), with 0 meaning none.
+func raised(from, to float64) bool {
+	return to > 0 && (from == 0 || to < from)
+}
+
+func priorityName(priority float64) string {
+	switch priority {
+	case 1:
+		return "urgent"
+	case 2:
+		return "high"
+	case 3:
+		return "medium"
+	case 4:
+		return "low"
+	}
+	return "no"
+}
+
+// stateName is how the workflow state of issue is described: its name, or
+// its type for issues cached before names were fetched.
+func stateName(issue linear.Issue) string {
+	if issue.State.Name != "" {
+		return issue.State.Name
+	}
+	return issue.State.Type
+}
+
+// dueDate formats one of Linear's dates, e.g. "Jan 2, 2006".
+func dueDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("Jan 2, 2006")
+}
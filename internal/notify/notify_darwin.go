@@ -0,0 +1,95 @@
+//go:build darwin
+
+package notify
+
+// #cgo LDFLAGS: -framework UserNotifications
+import "C"
+
+import (
+	"log"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/progrium/darwinkit/macos/foundation"
+	"github.com/progrium/darwinkit/objc"
+)
+
+// System returns the notifier of the desktop lil runs on.
+func System() Notifier {
+	return &UserNotifications{}
+}
+
+// authorizationOptions asks to show alerts and play sounds
+// (UNAuthorizationOptionSound | UNAuthorizationOptionAlert).
+const authorizationOptions uint = 1<<1 | 1<<2
+
+// UserNotifications shows notifications through the UserNotifications
+// framework, asking for permission before the first one. The notification
+// center only exists for apps run from a bundle, so a bare binary falls back
+// to AppleScript's display notification.
+type UserNotifications struct {
+	once sync.Once
+	// center is the UNUserNotificationCenter, or nil outside a bundle.
+	center objc.Object
+	// sent numbers the notification requests, which need unique
+	// identifiers.
+	sent atomic.Uint64
+}
+
+// notificationScript shows a notification. The title and text are passed as
+// arguments to avoid quoting issues.
+const notificationScript = `on run argv
+	display notification (item 2 of argv) with title (item 1 of argv)
+end run`
+
+func (u *UserNotifications) Notify(n Notification) error {
+	u.once.Do(u.authorize)
+	if u.center.IsNil() {
+		return exec.Command("osascript", "-e", notificationScript, n.Title, n.Body).Run()
+	}
+
+	objc.WithAutoreleasePool(func() {
+		content := objc.Call[objc.Object](objc.GetClass("UNMutableNotificationContent"), objc.Sel("new"))
+		defer content.Release()
+		objc.Call[objc.Void](content, objc.Sel("setTitle:"), n.Title)
+		objc.Call[objc.Void](content, objc.Sel("setBody:"), n.Body)
+
+		id := "lil-" + strconv.FormatUint(u.sent.Add(1), 10)
+		// A nil trigger shows the notification right away
+		request := objc.Call[objc.Object](objc.GetClass("UNNotificationRequest"), objc.Sel("requestWithIdentifier:content:trigger:"), id, content, objc.Object{})
+		objc.Call[objc.Void](u.center, objc.Sel("addNotificationRequest:withCompletionHandler:"), request, func(err foundation.Error) {
+			if !err.IsNil() {
+				log.Printf("Failed to show notification %q: %s", n.Title, err.LocalizedDescription())
+			}
+		})
+	})
+	return nil
+}
+
+// authorize looks up the notification center and asks the user to allow
+// notifications. macOS only asks once and remembers the answer, which can be
+// changed in System Settings.
+func (u *UserNotifications) authorize() {
+	// currentNotificationCenter raises an exception outside a bundle
+	if foundation.Bundle_MainBundle().BundleIdentifier() == "" {
+		return
+	}
+	class := objc.GetClass("UNUserNotificationCenter")
+	if class.Ptr() == nil {
+		return
+	}
+	u.center = objc.Call[objc.Object](class, objc.Sel("currentNotificationCenter"))
+	if u.center.IsNil() {
+		return
+	}
+	objc.Call[objc.Void](u.center, objc.Sel("requestAuthorizationWithOptions:completionHandler:"), authorizationOptions, func(granted bool, err foundation.Error) {
+		switch {
+		case !err.IsNil():
+			log.Printf("Failed to ask for permission to show notifications: %s", err.LocalizedDescription())
+		case !granted:
+			log.Printf("Notifications are turned off for Lil in System Settings")
+		}
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package notify
+
+import "runtime"
+
+// System returns the notifier of the desktop lil runs on.
+func System() Notifier {
+	if runtime.GOOS == "linux" {
+		return NewFreedesktop()
+	}
+	return Nop{}
+}
@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/pzurek/lil/internal/dbustest"
+	"github.com/pzurek/lil/internal/linear"
+)
+
+func testIssue(id, stateID, dueDate string, priority float64) linear.Issue {
+	issue := linear.Issue{Id: id, Identifier: "ENG-" + id, Title: "Issue " + id, DueDate: dueDate, Priority: priority}
+	issue.State.Id = stateID
+	issue.State.Type = "started"
+	return issue
+}
+
+func TestChanges(t *testing.T) {
+	named := func(issue linear.Issue, state string) linear.Issue {
+		issue.State.Name = state
+		return issue
+	}
+	old := []linear.Issue{
+		testIssue("1", "todo", "", 3),
+		testIssue("2", "todo", "2024-03-01", 3),
+		testIssue("3", "todo", "2024-03-01", 0),
+	}
+
+	tests := []struct {
+		name   string
+		new    []linear.Issue
+		opts   Options
+		expect []string
+	}{
+		{name: "Unchanged", new: old, opts: All},
+		{name: "Assigned", new: append(old[:3:3], testIssue("4", "todo", "", 0)), opts: All, expect: []string{"ENG-4 was assigned to you"}},
+		{name: "Unassigned", new: old[:2], opts: All},
+		{name: "State", new: []linear.Issue{testIssue("1", "doing", "", 3)}, opts: All, expect: []string{"ENG-1 moved to started"}},
+		{name: "Named state", new: []linear.Issue{named(testIssue("1", "doing", "", 3), "In Progress")}, opts: All, expect: []string{"ENG-1 moved to In Progress"}},
+		{name: "Due date set", new: []linear.Issue{testIssue("1", "todo", "2024-03-05", 3)}, opts: All, expect: []string{"ENG-1 is now due Mar 5, 2024"}},
+		{name: "Due date removed", new: []linear.Issue{testIssue("2", "todo", "", 3)}, opts: All, expect: []string{"ENG-2 no longer has a due date"}},
+		{name: "Priority raised", new: []linear.Issue{testIssue("1", "todo", "", 1)}, opts: All, expect: []string{"ENG-1 raised to urgent priority"}},
+		{name: "Priority set", new: []linear.Issue{testIssue("3", "todo", "2024-03-01", 4)}, opts: All, expect: []string{"ENG-3 raised to low priority"}},
+		{name: "Priority lowered", new: []linear.Issue{testIssue("1", "todo", "", 4)}, opts: All},
+		{name: "Priority removed", new: []linear.Issue{testIssue("1", "todo", "", 0)}, opts: All},
+		{
+			name:   "Several changes",
+			new:    []linear.Issue{testIssue("2", "doing", "2024-03-02", 2)},
+			opts:   All,
+			expect: []string{"ENG-2 moved to started", "ENG-2 is now due Mar 2, 2024", "ENG-2 raised to high priority"},
+		},
+		{
+			name:   "Only some kinds",
+			new:    []linear.Issue{testIssue("2", "doing", "2024-03-02", 2), testIssue("4", "todo", "", 0)},
+			opts:   Options{DueDate: true},
+			expect: []string{"ENG-2 is now due Mar 2, 2024"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var titles []string
+			for _, n := range Changes(old, tc.new, tc.opts) {
+				titles = append(titles, n.Title)
+				if n.Body != "Issue "+n.IssueID {
+					t.Errorf("Expected the issue title as body, got %q", n.Body)
+				}
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(tc.expect) {
+				t.Errorf("Expected %q, got %q", tc.expect, titles)
+			}
+		})
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	old := []linear.Issue{testIssue("1", "todo", "", 0)}
+	new := []linear.Issue{testIssue("1", "doing", "2024-03-01", 1), testIssue("2", "todo", "", 0), testIssue("3", "todo", "", 0)}
+	notifications := Changes(old, new, All)
+
+	if got := Coalesce(notifications[:MaxNotifications], ""); len(got) != MaxNotifications {
+		t.Errorf("Expected %d notifications to be shown as they are, got %+v", MaxNotifications, got)
+	}
+	got := Coalesce(notifications, "acme")
+	if len(got) != 1 || got[0].Kind != Summary || got[0].Title != "5 updates to your issues in acme" || got[0].Body != "ENG-1, ENG-2, ENG-3" {
+		t.Errorf("Expected a summary, got %+v", got)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var notifier Notifier = &Recorder{}
+	notifier.Notify(Notification{Title: "first"})
+	notifier.Notify(Notification{Title: "second"})
+	if got := notifier.(*Recorder).Notifications(); len(got) != 2 || got[1].Title != "second" {
+		t.Errorf("Expected both notifications in order, got %+v", got)
+	}
+}
+
+// fakeNotifications records the notifications sent to it over D-Bus. Calls
+// arrive on the connection's goroutine, hence the mutex.
+type fakeNotifications struct {
+	mu       sync.Mutex
+	received [][]interface{}
+}
+
+func (f *fakeNotifications) Notify(appName string, replacesID uint32, icon, summary, body string, actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.received = append(f.received, []interface{}{appName, summary, body, timeout})
+	return uint32(len(f.received)), nil
+}
+
+// calls returns the notifications received so far.
+func (f *fakeNotifications) calls() [][]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.received)
+}
+
+func TestFreedesktop(t *testing.T) {
+	address := dbustest.StartBus(t)
+	conn := dbustest.Connect(t, address)
+	service := &fakeNotifications{}
+	if err := conn.Export(service, notificationsPath, notificationsIface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := conn.RequestName(notificationsName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("Failed to own %s: %v", notificationsName, err)
+	}
+
+	notifier := &Freedesktop{conn: func() (*dbus.Conn, error) { return dbustest.Connect(t, address), nil }, AppName: "Lil"}
+	if err := notifier.Notify(Notification{Title: "ENG-1 was assigned to you", Body: "Fix the thing"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]interface{}{{"Lil", "ENG-1 was assigned to you", "Fix the thing", int32(-1)}}
+	if got := service.calls(); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	conn.ReleaseName(notificationsName)
+	if err := notifier.Notify(Notification{Title: "ENG-2"}); err == nil {
+		t.Error("Expected an error without a notification service")
+	}
+}
@@ -0,0 +1,259 @@
+// Package oauth signs in to Linear with the OAuth 2.0 authorization code flow
+// and PKCE, and keeps the resulting tokens fresh.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pzurek/lil/internal/config"
+)
+
+// Linear's OAuth endpoints.
+const (
+	DefaultAuthURL  = "https://linear.app/oauth/authorize"
+	DefaultTokenURL = "https://api.linear.app/oauth/token"
+)
+
+// DefaultScope is requested unless the config names other scopes.
+const DefaultScope = "read,write"
+
+// expiryDelta refreshes tokens this long before they expire, so a request
+// doesn't race the expiry.
+const expiryDelta = time.Minute
+
+// Config describes the OAuth application and the server it authorizes with.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	Scope        string
+	// RedirectPort is the loopback port of the redirect listener; zero picks
+	// a free one.
+	RedirectPort int
+	AuthURL      string
+	TokenURL     string
+	// HTTPClient sends token requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewConfig returns the Config of the OAuth application in the config file,
+// talking to Linear.
+func NewConfig(c config.OAuth) Config {
+	scope := c.Scope
+	if scope == "" {
+		scope = DefaultScope
+	}
+	return Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Scope:        scope,
+		RedirectPort: c.RedirectPort,
+		AuthURL:      DefaultAuthURL,
+		TokenURL:     DefaultTokenURL,
+	}
+}
+
+// Token is an access token and what is needed to renew it.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Expired reports whether the token expires within expiryDelta of now.
+// Tokens without an expiry never expire.
+func (t Token) Expired(now time.Time) bool {
+	return !t.Expiry.IsZero() && now.Add(expiryDelta).After(t.Expiry)
+}
+
+// callback is what the redirect listener received.
+type callback struct {
+	code string
+	err  error
+}
+
+// Login runs the authorization code flow: it starts a listener on the
+// loopback interface, calls open with the authorization URL for the user to
+// visit in a browser, waits for the browser to be redirected back with a
+// code and exchanges the code for a token.
+func (c Config) Login(ctx context.Context, open func(url string)) (Token, error) {
+	verifier, err := randomString(32)
+	if err != nil {
+		return Token{}, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return Token{}, err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", c.RedirectPort))
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to start the redirect listener: %w", err)
+	}
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	callbacks := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		result := parseCallback(r.URL.Query(), state)
+		if errors.Is(result.err, errUnexpectedState) {
+			// Not the browser we sent; keep waiting for it
+			http.Error(w, "Signing in to Linear failed: "+result.err.Error(), http.StatusBadRequest)
+			return
+		}
+		if result.err != nil {
+			http.Error(w, "Signing in to Linear failed: "+result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Signed in to Linear. You can close this window and return to lil.")
+		}
+		select {
+		case callbacks <- result:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {c.Scope},
+		"state":                 {state},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	open(c.AuthURL + "?" + query.Encode())
+
+	select {
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	case result := <-callbacks:
+		if result.err != nil {
+			return Token{}, result.err
+		}
+		return c.exchange(ctx, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {result.code},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {verifier},
+		})
+	}
+}
+
+// errUnexpectedState is reported for callbacks that don't carry the state
+// of the login, such as requests by other local processes.
+var errUnexpectedState = errors.New("authorization response has an unexpected state")
+
+// parseCallback checks the authorization response the browser was
+// redirected with.
+func parseCallback(query url.Values, state string) callback {
+	switch {
+	case query.Get("state") != state:
+		return callback{err: errUnexpectedState}
+	case query.Get("error") != "":
+		return callback{err: fmt.Errorf("authorization denied: %s", strings.TrimSpace(query.Get("error")+" "+query.Get("error_description")))}
+	case query.Get("code") == "":
+		return callback{err: errors.New("authorization response has no code")}
+	}
+	return callback{code: query.Get("code")}
+}
+
+// Refresh exchanges a refresh token for a new token. Servers that don't
+// rotate refresh tokens keep the old one valid, so it is carried over.
+func (c Config) Refresh(ctx context.Context, refreshToken string) (Token, error) {
+	token, err := c.exchange(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// exchange posts a token request and decodes the response.
+func (c Config) exchange(ctx context.Context, form url.Values) (Token, error) {
+	form.Set("client_id", c.ClientID)
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var payload struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil && resp.StatusCode == http.StatusOK {
+		return Token{}, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || payload.Error != "" {
+		if payload.Error != "" {
+			return Token{}, fmt.Errorf("token request rejected: %s", strings.TrimSpace(payload.Error+" "+payload.ErrorDescription))
+		}
+		return Token{}, fmt.Errorf("token request failed with status %s", resp.Status)
+	}
+	if payload.AccessToken == "" {
+		return Token{}, errors.New("token response has no access token")
+	}
+
+	token := Token{AccessToken: payload.AccessToken, RefreshToken: payload.RefreshToken}
+	if payload.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(payload.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// randomString returns n random bytes, base64url encoded.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// challenge derives the S256 code challenge from a PKCE verifier.
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,295 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pzurek/lil/internal/credentials"
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// fakeAuthServer is a minimal OAuth authorization server. Its authorize
+// endpoint approves every request by redirecting back with a code, and its
+// token endpoint checks the PKCE verifier against the challenge.
+type fakeAuthServer struct {
+	t  *testing.T
+	mu sync.Mutex
+	// challenges maps issued codes to their PKCE challenge and redirect URI.
+	challenges map[string][2]string
+	issued     int
+	refreshes  int
+	expiresIn  int
+	deny       bool
+}
+
+func newFakeAuthServer(t *testing.T) (*fakeAuthServer, *httptest.Server) {
+	f := &fakeAuthServer{t: t, challenges: map[string][2]string{}, expiresIn: 3600}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", f.authorize)
+	mux.HandleFunc("/token", f.token)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeAuthServer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != "lil-test" || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "read,write" {
+		f.t.Errorf("Unexpected authorization request: %v", q)
+	}
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	params := url.Values{"state": {q.Get("state")}}
+	if f.deny {
+		params.Set("error", "access_denied")
+	} else {
+		f.mu.Lock()
+		code := fmt.Sprintf("code-%d", len(f.challenges))
+		f.challenges[code] = [2]string{q.Get("code_challenge"), q.Get("redirect_uri")}
+		f.mu.Unlock()
+		params.Set("code", code)
+	}
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (f *fakeAuthServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		f.t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fail := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error":%q}`, code)
+	}
+	if r.PostForm.Get("client_id") != "lil-test" {
+		fail("invalid_client")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		issued, ok := f.challenges[r.PostForm.Get("code")]
+		delete(f.challenges, r.PostForm.Get("code"))
+		if !ok || challenge(r.PostForm.Get("code_verifier")) != issued[0] || r.PostForm.Get("redirect_uri") != issued[1] {
+			fail("invalid_grant")
+			return
+		}
+	case "refresh_token":
+		if r.PostForm.Get("refresh_token") != fmt.Sprintf("refresh-%d", f.issued) {
+			fail("invalid_grant")
+			return
+		}
+		f.refreshes++
+	default:
+		fail("unsupported_grant_type")
+		return
+	}
+
+	f.issued++
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token":  fmt.Sprintf("access-%d", f.issued),
+		"refresh_token": fmt.Sprintf("refresh-%d", f.issued),
+		"token_type":    "Bearer",
+		"expires_in":    f.expiresIn,
+	})
+}
+
+// browse follows the authorization URL like a browser would, through the
+// redirect back to the loopback listener.
+func browse(t *testing.T) func(string) {
+	return func(authURL string) {
+		go func() {
+			resp, err := http.Get(authURL)
+			if err != nil {
+				t.Errorf("Failed to follow the authorization URL: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+}
+
+func testConfig(server *httptest.Server) Config {
+	return Config{
+		ClientID: "lil-test",
+		Scope:    DefaultScope,
+		AuthURL:  server.URL + "/authorize",
+		TokenURL: server.URL + "/token",
+	}
+}
+
+// memoryStore is a credentials.Store backed by a map.
+type memoryStore map[string]string
+
+func (m memoryStore) Name() string { return "memory" }
+
+func (m memoryStore) Get(account string) (string, error) {
+	secret, ok := m[account]
+	if !ok {
+		return "", credentials.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (m memoryStore) Set(account, secret string) error {
+	m[account] = secret
+	return nil
+}
+
+func (m memoryStore) Delete(account string) error {
+	delete(m, account)
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	_, server := newFakeAuthServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := testConfig(server).Login(ctx, browse(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+		t.Errorf("Expected the first token, got %+v", token)
+	}
+	if until := time.Until(token.Expiry); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("Expected the token to expire in an hour, got %s", until)
+	}
+}
+
+func TestLoginIgnoresForgedCallbacks(t *testing.T) {
+	_, server := newFakeAuthServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	open := func(authURL string) {
+		parsed, _ := url.Parse(authURL)
+		redirect, _ := url.Parse(parsed.Query().Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"state": {"forged"}, "code": {"stolen"}}.Encode()
+		resp, err := http.Get(redirect.String())
+		if err != nil {
+			t.Fatalf("Failed to send the forged callback: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected the forged callback to be rejected, got %s", resp.Status)
+		}
+		browse(t)(authURL)
+	}
+	token, err := testConfig(server).Login(ctx, open)
+	if err != nil {
+		t.Fatalf("Expected the login to go on after a forged callback, got %v", err)
+	}
+	if token.AccessToken != "access-1" {
+		t.Errorf("Expected the first token, got %+v", token)
+	}
+}
+
+func TestLoginDenied(t *testing.T) {
+	fake, server := newFakeAuthServer(t)
+	fake.deny = true
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := testConfig(server).Login(ctx, browse(t)); err == nil || err.Error() != "authorization denied: access_denied" {
+		t.Errorf("Expected the denial to be reported, got %v", err)
+	}
+}
+
+func TestParseCallback(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{name: "Code", query: "state=s&code=abc", code: "abc"},
+		{name: "Wrong state", query: "state=forged&code=abc"},
+		{name: "Missing code", query: "state=s"},
+		{name: "Error", query: "state=s&error=access_denied"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tc.query)
+			result := parseCallback(query, "s")
+			if result.code != tc.code || (tc.code == "") != (result.err != nil) {
+				t.Errorf("Expected code %q, got %+v", tc.code, result)
+			}
+		})
+	}
+}
+
+func TestSource(t *testing.T) {
+	fake, server := newFakeAuthServer(t)
+	store := memoryStore{}
+	source := NewSource(testConfig(server), store, "acme")
+	now := time.Now()
+	source.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := source.Token(ctx); !errors.Is(err, linear.ErrMissingAPIKey) {
+		t.Fatalf("Expected ErrMissingAPIKey before signing in, got %v", err)
+	}
+
+	loginCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := source.Login(loginCtx, browse(t)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var stored Token
+	if err := json.Unmarshal([]byte(store["acme"]), &stored); err != nil || stored.RefreshToken != "refresh-1" {
+		t.Fatalf("Expected the token to be stored, got %q (%v)", store["acme"], err)
+	}
+	if token, err := source.Token(ctx); err != nil || token != "access-1" {
+		t.Errorf("Expected access-1, got %q (%v)", token, err)
+	}
+
+	// A rejected token is refreshed once, even if several requests saw it fail
+	for range 2 {
+		if token, err := source.Refresh(ctx, "access-1"); err != nil || token != "access-2" {
+			t.Errorf("Expected access-2, got %q (%v)", token, err)
+		}
+	}
+	if fake.refreshes != 1 {
+		t.Errorf("Expected 1 refresh, got %d", fake.refreshes)
+	}
+
+	// An expired token is refreshed before it is used
+	now = now.Add(2 * time.Hour)
+	if token, err := source.Token(ctx); err != nil || token != "access-3" {
+		t.Errorf("Expected access-3, got %q (%v)", token, err)
+	}
+	if err := json.Unmarshal([]byte(store["acme"]), &stored); err != nil || stored.AccessToken != "access-3" || stored.RefreshToken != "refresh-3" {
+		t.Errorf("Expected the refreshed token to be stored, got %q (%v)", store["acme"], err)
+	}
+
+	// A token refreshed by another process is picked up from the store
+	other := NewSource(testConfig(server), store, "acme")
+	if token, err := other.Refresh(ctx, "access-3"); err != nil || token != "access-4" {
+		t.Fatalf("Expected access-4, got %q (%v)", token, err)
+	}
+	if token, err := source.Refresh(ctx, "access-3"); err != nil || token != "access-4" {
+		t.Errorf("Expected the other process's token, got %q (%v)", token, err)
+	}
+	if fake.refreshes != 3 {
+		t.Errorf("Expected 3 refreshes, got %d", fake.refreshes)
+	}
+}
+
+func TestSourceRejectsAPIKey(t *testing.T) {
+	source := NewSource(Config{}, memoryStore{"acme": "lin_api_123"}, "acme")
+	if _, err := source.Token(context.Background()); !errors.Is(err, linear.ErrMissingAPIKey) {
+		t.Errorf("Expected a stored API key to be rejected, got %v", err)
+	}
+}
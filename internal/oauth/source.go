@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pzurek/lil/internal/credentials"
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// Source is a linear.TokenSource that keeps the token of an account in a
+// credential store, refreshing and saving it when it expires or is rejected.
+type Source struct {
+	config  Config
+	store   credentials.Store
+	account string
+	now     func() time.Time
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewSource returns a token source for the token of account in store.
+func NewSource(config Config, store credentials.Store, account string) *Source {
+	return &Source{config: config, store: store, account: account, now: time.Now}
+}
+
+// Login runs the browser flow and saves the new token.
+func (s *Source) Login(ctx context.Context, open func(url string)) error {
+	token, err := s.config.Login(ctx, open)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(token)
+}
+
+// Token returns a valid access token, refreshing the stored one if it has
+// expired.
+func (s *Source) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil || s.token.Expired(s.now()) {
+		// Another lil process may have refreshed the token in the meantime
+		if err := s.load(); err != nil {
+			return "", err
+		}
+	}
+	if s.token.Expired(s.now()) {
+		if err := s.refresh(ctx); err != nil {
+			return "", err
+		}
+	}
+	return s.token.AccessToken, nil
+}
+
+// Refresh returns a new access token after rejected was refused.
+func (s *Source) Refresh(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && s.token.AccessToken != rejected {
+		return s.token.AccessToken, nil // already refreshed by another request
+	}
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	if s.token.AccessToken != rejected {
+		return s.token.AccessToken, nil // refreshed by another process
+	}
+	if err := s.refresh(ctx); err != nil {
+		return "", err
+	}
+	return s.token.AccessToken, nil
+}
+
+// load reads the stored token.
+func (s *Source) load() error {
+	secret, err := s.store.Get(s.account)
+	if errors.Is(err, credentials.ErrNotFound) {
+		return fmt.Errorf("%w: not signed in to %s, run lil auth login", linear.ErrMissingAPIKey, s.account)
+	}
+	if err != nil {
+		return err
+	}
+	var token Token
+	if err := json.Unmarshal([]byte(secret), &token); err != nil || token.AccessToken == "" {
+		return fmt.Errorf("%w: the stored credentials of %s are not an OAuth token, run lil auth login", linear.ErrMissingAPIKey, s.account)
+	}
+	s.token = &token
+	return nil
+}
+
+// refresh renews the loaded token and saves it.
+func (s *Source) refresh(ctx context.Context) error {
+	if s.token.RefreshToken == "" {
+		return fmt.Errorf("%w: the access token of %s has expired, run lil auth login", linear.ErrMissingAPIKey, s.account)
+	}
+	token, err := s.config.Refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to refresh the access token of %s: %w", s.account, err)
+	}
+	return s.save(token)
+}
+
+// save stores token and makes it the current one.
+func (s *Source) save(token Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Set(s.account, string(data)); err != nil {
+		return fmt.Errorf("failed to store the access token: %w", err)
+	}
+	s.token = &token
+	return nil
+}
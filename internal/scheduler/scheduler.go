@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Default timings used when a Config leaves them unset.
+const (
+	DefaultInterval   = 5 * time.Minute
+	DefaultMinBackoff = 15 * time.Second
+	DefaultMaxBackoff = 15 * time.Minute
+	DefaultJitter     = 0.2
+)
+
+// FetchFunc performs a single refresh. A non-nil error counts as a failure
+// and makes the scheduler back off before trying again.
+type FetchFunc func(ctx context.Context) error
+
+// Config controls how often the scheduler runs and how it backs off.
+type Config struct {
+	// Interval is the delay between successful refreshes.
+	Interval time.Duration
+	// MinBackoff is the delay after the first consecutive failure. It doubles
+	// with every further failure up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Jitter randomizes backoff delays by up to this fraction in either
+	// direction (0.2 means ±20%) so that many clients don't retry in lockstep.
+	Jitter float64
+	// Clock is the time source. Defaults to the system clock.
+	Clock Clock
+	// Rand returns a value in [0, 1). Defaults to math/rand.
+	Rand func() float64
+	// NotBefore, if set, returns a time before which the next fetch should
+	// not run, e.g. until an exhausted rate limit budget resets. The zero
+	// time means no restriction.
+	NotBefore func() time.Time
+}
+
+// Scheduler runs a FetchFunc periodically, backing off exponentially after
+// failures, and can be asked to refresh immediately.
+type Scheduler struct {
+	fetch FetchFunc
+	cfg   Config
+	now   chan struct{}
+
+	mu       sync.Mutex
+	failures int
+	lastRun  time.Time
+	lastErr  error
+}
+
+// New creates a scheduler for fetch. Zero values in cfg are replaced by the
+// package defaults.
+func New(fetch FetchFunc, cfg Config) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = cfg.MinBackoff
+	}
+	if cfg.Jitter < 0 {
+		cfg.Jitter = 0
+	}
+	if cfg.Jitter > 1 {
+		cfg.Jitter = 1
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.Float64
+	}
+	return &Scheduler{
+		fetch: fetch,
+		cfg:   cfg,
+		now:   make(chan struct{}, 1),
+	}
+}
+
+// Run fetches immediately and then keeps fetching until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		delay := s.runOnce(ctx)
+
+		timer := s.cfg.Clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		case <-s.now:
+			timer.Stop()
+		}
+	}
+}
+
+// RefreshNow asks a running scheduler to fetch without waiting for the next
+// tick. Requests made while a refresh is already pending are coalesced.
+func (s *Scheduler) RefreshNow() {
+	select {
+	case s.now <- struct{}{}:
+	default:
+	}
+}
+
+// SetInterval changes the delay between successful refreshes. It takes effect
+// from the next refresh on.
+func (s *Scheduler) SetInterval(d time.Duration) {
+	if d <= 0 {
+		d = DefaultInterval
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg.Interval = d
+}
+
+// Failures returns the number of consecutive failed fetches.
+func (s *Scheduler) Failures() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures
+}
+
+// LastRun returns when the last fetch finished and the error it returned.
+func (s *Scheduler) LastRun() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun, s.lastErr
+}
+
+// runOnce performs a fetch, records the outcome and returns the delay until
+// the next one.
+func (s *Scheduler) runOnce(ctx context.Context) time.Duration {
+	err := s.fetch(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = s.cfg.Clock.Now()
+	s.lastErr = err
+	if err == nil {
+		s.failures = 0
+		return s.hold(s.cfg.Interval)
+	}
+	s.failures++
+	delay := s.hold(s.backoff(s.failures))
+	log.Printf("Refresh failed (%d in a row), retrying in %s: %v", s.failures, delay.Round(time.Second), err)
+	return delay
+}
+
+// hold stretches delay to last until NotBefore.
+func (s *Scheduler) hold(delay time.Duration) time.Duration {
+	if s.cfg.NotBefore == nil {
+		return delay
+	}
+	if until := s.cfg.NotBefore(); !until.IsZero() {
+		if wait := until.Sub(s.cfg.Clock.Now()); wait > delay {
+			return wait
+		}
+	}
+	return delay
+}
+
+// backoff returns the jittered delay after n consecutive failures.
+func (s *Scheduler) backoff(n int) time.Duration {
+	d := s.cfg.MinBackoff
+	for i := 1; i < n && d < s.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.cfg.MaxBackoff {
+		d = s.cfg.MaxBackoff
+	}
+	if s.cfg.Jitter > 0 {
+		// Scale by a factor in [1-Jitter, 1+Jitter).
+		factor := 1 + s.cfg.Jitter*(2*s.cfg.Rand()-1)
+		d = time.Duration(float64(d) * factor)
+	}
+	return d
+}
@@ -0,0 +1,247 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced Clock. Every timer it creates is reported
+// on the timers channel so tests can wait for the scheduler to go idle.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers chan *fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	d        time.Duration
+	deadline time.Time
+	c        chan time.Time
+	stopped  bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		timers: make(chan *fakeTimer, 16),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	t := &fakeTimer{clock: c, d: d, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.mu.Unlock()
+	c.timers <- t
+	return t
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+// fire advances the clock to the timer's deadline and delivers the tick.
+func (t *fakeTimer) fire() {
+	t.clock.mu.Lock()
+	t.clock.now = t.deadline
+	t.stopped = true
+	t.clock.mu.Unlock()
+	t.c <- t.deadline
+}
+
+func (c *fakeClock) nextTimer(t *testing.T) *fakeTimer {
+	t.Helper()
+	select {
+	case timer := <-c.timers:
+		return timer
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduler did not arm a timer")
+		return nil
+	}
+}
+
+func TestSchedulerBackoff(t *testing.T) {
+	clock := newFakeClock()
+	errFetch := errors.New("boom")
+	results := []error{nil, errFetch, errFetch, errFetch, errFetch, nil}
+	var calls int
+
+	s := New(func(ctx context.Context) error {
+		err := results[calls]
+		calls++
+		return err
+	}, Config{
+		Interval:   time.Minute,
+		MinBackoff: 10 * time.Second,
+		MaxBackoff: 30 * time.Second,
+		Jitter:     0.5,
+		Clock:      clock,
+		Rand:       func() float64 { return 0.5 }, // no jitter offset
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	expected := []time.Duration{
+		time.Minute,      // success
+		10 * time.Second, // 1st failure
+		20 * time.Second, // 2nd failure
+		30 * time.Second, // capped
+		30 * time.Second, // still capped
+		time.Minute,      // recovered
+	}
+	for i, want := range expected {
+		timer := clock.nextTimer(t)
+		if timer.d != want {
+			t.Errorf("Delay after fetch %d: expected %s, got %s", i+1, want, timer.d)
+		}
+		if i < len(expected)-1 {
+			timer.fire()
+		}
+	}
+
+	if s.Failures() != 0 {
+		t.Errorf("Expected failures to reset after success, got %d", s.Failures())
+	}
+
+	cancel()
+	<-done
+}
+
+func TestSchedulerRefreshNow(t *testing.T) {
+	clock := newFakeClock()
+	fetched := make(chan struct{}, 4)
+
+	s := New(func(ctx context.Context) error {
+		fetched <- struct{}{}
+		return nil
+	}, Config{Interval: time.Hour, Clock: clock})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	<-fetched
+	first := clock.nextTimer(t)
+
+	s.RefreshNow()
+	<-fetched
+	clock.nextTimer(t)
+
+	if !first.stopped {
+		t.Error("Expected pending timer to be stopped by RefreshNow")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	for _, r := range []float64{0, 0.25, 0.5, 0.75, 0.999} {
+		s := New(nil, Config{
+			MinBackoff: 10 * time.Second,
+			MaxBackoff: time.Minute,
+			Jitter:     0.2,
+			Rand:       func() float64 { return r },
+		})
+		d := s.backoff(1)
+		if d < 8*time.Second || d >= 12*time.Second {
+			t.Errorf("Rand %.3f: backoff %s outside [8s, 12s)", r, d)
+		}
+	}
+}
+
+func TestSchedulerSetInterval(t *testing.T) {
+	clock := newFakeClock()
+	s := New(func(ctx context.Context) error { return nil }, Config{Interval: time.Hour, Clock: clock})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	first := clock.nextTimer(t)
+	if first.d != time.Hour {
+		t.Errorf("Expected initial delay of 1h, got %s", first.d)
+	}
+
+	s.SetInterval(2 * time.Minute)
+	first.fire()
+	if next := clock.nextTimer(t); next.d != 2*time.Minute {
+		t.Errorf("Expected delay of 2m after SetInterval, got %s", next.d)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestSchedulerNotBefore(t *testing.T) {
+	clock := newFakeClock()
+	var notBefore time.Time
+	fail := false
+	s := New(func(ctx context.Context) error {
+		if fail {
+			return errors.New("rate limited")
+		}
+		return nil
+	}, Config{
+		Interval:   5 * time.Minute,
+		MinBackoff: 15 * time.Second,
+		Jitter:     -1,
+		Clock:      clock,
+		NotBefore:  func() time.Time { return notBefore },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	timer := clock.nextTimer(t)
+	if timer.d != 5*time.Minute {
+		t.Errorf("Expected the interval without a hold, got %s", timer.d)
+	}
+
+	notBefore = timer.deadline.Add(20 * time.Minute)
+	timer.fire()
+	timer = clock.nextTimer(t)
+	if timer.d != 20*time.Minute {
+		t.Errorf("Expected the delay to last until the hold ends, got %s", timer.d)
+	}
+
+	fail = true
+	notBefore = timer.deadline.Add(time.Minute)
+	timer.fire()
+	if timer = clock.nextTimer(t); timer.d != time.Minute {
+		t.Errorf("Expected the backoff to last until the hold ends, got %s", timer.d)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,278 @@
+package sni
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/pzurek/lil/internal/menu"
+)
+
+const (
+	menuInterface = "com.canonical.dbusmenu"
+	menuPath      = dbus.ObjectPath("/MenuBar")
+)
+
+// layout is the (ia{sv}av) structure returned by GetLayout.
+type layout struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+	Children   []dbus.Variant
+}
+
+// itemProperties is an (ia{sv}) entry returned by GetGroupProperties.
+type itemProperties struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+}
+
+// menuEvent is an (isvu) entry passed to EventGroup.
+type menuEvent struct {
+	ID        int32
+	EventID   string
+	Data      dbus.Variant
+	Timestamp uint32
+}
+
+// node is a flattened menu entry with a stable dbusmenu ID.
+type node struct {
+	id         int32
+	properties map[string]dbus.Variant
+	children   []*node
+	item       *menu.Item // nil for the root, headers and separators
+}
+
+// dbusMenu implements the com.canonical.dbusmenu interface for a menu.Menu.
+type dbusMenu struct {
+	conn    *dbus.Conn
+	onClick func(menu.Item)
+
+	mu       sync.Mutex
+	revision uint32
+	root     *node
+	nodes    map[int32]*node
+}
+
+func newDBusMenu(conn *dbus.Conn, onClick func(menu.Item)) *dbusMenu {
+	m := &dbusMenu{conn: conn, onClick: onClick}
+	m.root, m.nodes = buildNodes(menu.Menu{})
+	return m
+}
+
+// set replaces the menu contents and tells hosts to re-read the layout.
+func (m *dbusMenu) set(model menu.Menu) {
+	m.mu.Lock()
+	m.root, m.nodes = buildNodes(model)
+	m.revision++
+	revision := m.revision
+	m.mu.Unlock()
+
+	if err := m.conn.Emit(menuPath, menuInterface+".LayoutUpdated", revision, int32(0)); err != nil {
+		logf("Failed to emit LayoutUpdated: %v", err)
+	}
+}
+
+// buildNodes flattens a menu model into dbusmenu nodes: sections become runs
+// of items separated by separator nodes, with headers as disabled items.
+func buildNodes(model menu.Menu) (*node, map[int32]*node) {
+	root := &node{id: 0, properties: map[string]dbus.Variant{
+		"children-display": dbus.MakeVariant("submenu"),
+	}}
+	nodes := map[int32]*node{0: root}
+	nextID := int32(1)
+
+	var addSections func(parent *node, sections []menu.Section)
+	add := func(parent, n *node) {
+		n.id = nextID
+		nextID++
+		nodes[n.id] = n
+		parent.children = append(parent.children, n)
+	}
+	addSections = func(parent *node, sections []menu.Section) {
+		for i, section := range sections {
+			if i > 0 {
+				add(parent, &node{properties: map[string]dbus.Variant{
+					"type": dbus.MakeVariant("separator"),
+				}})
+			}
+			if section.Header != "" {
+				add(parent, &node{properties: map[string]dbus.Variant{
+					"label":   dbus.MakeVariant(escapeLabel(section.Header)),
+					"enabled": dbus.MakeVariant(false),
+				}})
+			}
+			for j := range section.Items {
+				item := section.Items[j]
+				n := &node{properties: itemPropertiesFor(item), item: &item}
+				add(parent, n)
+				if len(item.Submenu) > 0 {
+					addSections(n, item.Submenu)
+				}
+			}
+		}
+	}
+
+	addSections(root, model.Sections)
+	return root, nodes
+}
+
+// itemPropertiesFor maps a menu item onto dbusmenu item properties.
+func itemPropertiesFor(item menu.Item) map[string]dbus.Variant {
+	props := map[string]dbus.Variant{
+		"label":   dbus.MakeVariant(escapeLabel(item.Title)),
+		"enabled": dbus.MakeVariant(item.Enabled),
+	}
+	if item.Tooltip != "" {
+		// dbusmenu has no tooltips; the accessible description is the closest
+		// thing hosts expose.
+		props["accessible-desc"] = dbus.MakeVariant(item.Tooltip)
+	}
+	if item.KeyEquivalent != "" {
+		props["shortcut"] = dbus.MakeVariant([][]string{{"Control", item.KeyEquivalent}})
+	}
+	if item.Indicator.IconName != "" {
+		// Hosts only draw themed icons here, so the state type stands in for
+		// its color.
+		props["icon-name"] = dbus.MakeVariant(item.Indicator.IconName)
+	}
+	// Labels can't be colored either, so urgent issues get an icon instead
+	switch item.Urgency {
+	case menu.Overdue:
+		props["icon-name"] = dbus.MakeVariant("task-past-due")
+	case menu.DueToday:
+		props["icon-name"] = dbus.MakeVariant("task-due")
+	}
+	if item.Checked {
+		props["toggle-type"] = dbus.MakeVariant("checkmark")
+		props["toggle-state"] = dbus.MakeVariant(int32(1))
+	}
+	if len(item.Submenu) > 0 {
+		props["children-display"] = dbus.MakeVariant("submenu")
+	}
+	return props
+}
+
+// escapeLabel doubles underscores, which dbusmenu treats as mnemonic markers.
+func escapeLabel(label string) string {
+	out := make([]rune, 0, len(label))
+	for _, r := range label {
+		if r == '_' {
+			out = append(out, '_')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// toLayout converts a node and up to depth levels of children (-1 for all).
+func (n *node) toLayout(depth int32, propertyNames []string) layout {
+	l := layout{
+		ID:         n.id,
+		Properties: filterProperties(n.properties, propertyNames),
+		Children:   []dbus.Variant{},
+	}
+	if depth == 0 {
+		return l
+	}
+	for _, child := range n.children {
+		l.Children = append(l.Children, dbus.MakeVariant(child.toLayout(depth-1, propertyNames)))
+	}
+	return l
+}
+
+func filterProperties(props map[string]dbus.Variant, names []string) map[string]dbus.Variant {
+	if len(names) == 0 {
+		return props
+	}
+	filtered := make(map[string]dbus.Variant, len(names))
+	for _, name := range names {
+		if v, ok := props[name]; ok {
+			filtered[name] = v
+		}
+	}
+	return filtered
+}
+
+// GetLayout implements com.canonical.dbusmenu.GetLayout.
+func (m *dbusMenu) GetLayout(parentID int32, recursionDepth int32, propertyNames []string) (uint32, layout, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, ok := m.nodes[parentID]
+	if !ok {
+		return 0, layout{}, dbus.MakeFailedError(errUnknownItem(parentID))
+	}
+	return m.revision, n.toLayout(recursionDepth, propertyNames), nil
+}
+
+// GetGroupProperties implements com.canonical.dbusmenu.GetGroupProperties.
+func (m *dbusMenu) GetGroupProperties(ids []int32, propertyNames []string) ([]itemProperties, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []itemProperties{}
+	if len(ids) == 0 {
+		for id := range m.nodes {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		if n, ok := m.nodes[id]; ok {
+			result = append(result, itemProperties{ID: id, Properties: filterProperties(n.properties, propertyNames)})
+		}
+	}
+	return result, nil
+}
+
+// GetProperty implements com.canonical.dbusmenu.GetProperty.
+func (m *dbusMenu) GetProperty(id int32, name string) (dbus.Variant, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, ok := m.nodes[id]
+	if !ok {
+		return dbus.Variant{}, dbus.MakeFailedError(errUnknownItem(id))
+	}
+	v, ok := n.properties[name]
+	if !ok {
+		return dbus.Variant{}, dbus.MakeFailedError(errUnknownProperty(name))
+	}
+	return v, nil
+}
+
+// Event implements com.canonical.dbusmenu.Event.
+func (m *dbusMenu) Event(id int32, eventID string, data dbus.Variant, timestamp uint32) *dbus.Error {
+	if eventID != "clicked" {
+		return nil
+	}
+	m.mu.Lock()
+	n, ok := m.nodes[id]
+	m.mu.Unlock()
+	if !ok {
+		return dbus.MakeFailedError(errUnknownItem(id))
+	}
+	if n.item != nil && n.item.Enabled && m.onClick != nil {
+		// Don't block the bus while the action runs.
+		go m.onClick(*n.item)
+	}
+	return nil
+}
+
+// EventGroup implements com.canonical.dbusmenu.EventGroup.
+func (m *dbusMenu) EventGroup(events []menuEvent) ([]int32, *dbus.Error) {
+	idErrors := []int32{}
+	for _, e := range events {
+		if err := m.Event(e.ID, e.EventID, e.Data, e.Timestamp); err != nil {
+			idErrors = append(idErrors, e.ID)
+		}
+	}
+	return idErrors, nil
+}
+
+// AboutToShow implements com.canonical.dbusmenu.AboutToShow. The layout is
+// always current, so no update is ever needed.
+func (m *dbusMenu) AboutToShow(id int32) (bool, *dbus.Error) {
+	return false, nil
+}
+
+// AboutToShowGroup implements com.canonical.dbusmenu.AboutToShowGroup.
+func (m *dbusMenu) AboutToShowGroup(ids []int32) ([]int32, []int32, *dbus.Error) {
+	return []int32{}, []int32{}, nil
+}
@@ -0,0 +1,298 @@
+// Package sni shows a menu.Menu in the Linux system tray using the
+// StatusNotifierItem and com.canonical.dbusmenu D-Bus protocols.
+package sni
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+
+	"github.com/pzurek/lil/internal/menu"
+)
+
+const (
+	itemInterface    = "org.kde.StatusNotifierItem"
+	itemPath         = dbus.ObjectPath("/StatusNotifierItem")
+	watcherName      = "org.kde.StatusNotifierWatcher"
+	watcherPath      = dbus.ObjectPath("/StatusNotifierWatcher")
+	watcherInterface = "org.kde.StatusNotifierWatcher"
+)
+
+// instances makes bus names unique when several trays share a process.
+var instances int32
+
+// Options configures a Tray.
+type Options struct {
+	// ID is the application identifier reported to the host (e.g. "lil").
+	ID string
+	// Title is the human readable application name.
+	Title string
+	// Icon is shown in the tray. It is sent to the host as a pixmap.
+	Icon image.Image
+	// OnClick is called, on its own goroutine, when an enabled item is clicked.
+	OnClick func(menu.Item)
+}
+
+// Tray is a StatusNotifierItem exported on a D-Bus connection.
+type Tray struct {
+	conn  *dbus.Conn
+	name  string
+	props *prop.Properties
+	menu  *dbusMenu
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// pixmap is the (iiay) ARGB32 icon structure used by IconPixmap.
+type pixmap struct {
+	Width  int32
+	Height int32
+	Data   []byte
+}
+
+// toolTip is the (sa(iiay)ss) structure used by the ToolTip property.
+type toolTip struct {
+	IconName    string
+	IconPixmap  []pixmap
+	Title       string
+	Description string
+}
+
+// item implements the org.kde.StatusNotifierItem methods. The menu is the
+// only interaction, so activation requests are ignored.
+type item struct{}
+
+func (item) Activate(x, y int32) *dbus.Error          { return nil }
+func (item) SecondaryActivate(x, y int32) *dbus.Error { return nil }
+func (item) ContextMenu(x, y int32) *dbus.Error       { return nil }
+func (item) Scroll(delta int32, orientation string) *dbus.Error {
+	return nil
+}
+
+// New exports a StatusNotifierItem and its menu on conn and registers it with
+// the StatusNotifierWatcher. If no watcher is running yet, the tray registers
+// as soon as one appears.
+func New(conn *dbus.Conn, opts Options) (*Tray, error) {
+	t := &Tray{
+		conn: conn,
+		name: fmt.Sprintf("org.kde.StatusNotifierItem-%d-%d", os.Getpid(), atomic.AddInt32(&instances, 1)),
+		menu: newDBusMenu(conn, opts.OnClick),
+		done: make(chan struct{}),
+	}
+
+	var icon []pixmap
+	if opts.Icon != nil {
+		icon = []pixmap{toPixmap(opts.Icon)}
+	}
+
+	props, err := prop.Export(conn, itemPath, prop.Map{
+		itemInterface: {
+			"Category":            {Value: "ApplicationStatus", Emit: prop.EmitTrue},
+			"Id":                  {Value: opts.ID, Emit: prop.EmitTrue},
+			"Title":               {Value: opts.Title, Emit: prop.EmitTrue},
+			"Status":              {Value: "Active", Emit: prop.EmitTrue},
+			"WindowId":            {Value: int32(0), Emit: prop.EmitTrue},
+			"IconName":            {Value: "", Emit: prop.EmitTrue},
+			"IconPixmap":          {Value: icon, Emit: prop.EmitTrue},
+			"OverlayIconName":     {Value: "", Emit: prop.EmitTrue},
+			"OverlayIconPixmap":   {Value: []pixmap{}, Emit: prop.EmitTrue},
+			"AttentionIconName":   {Value: "", Emit: prop.EmitTrue},
+			"AttentionIconPixmap": {Value: []pixmap{}, Emit: prop.EmitTrue},
+			"ToolTip":             {Value: toolTip{Title: opts.Title, IconPixmap: []pixmap{}}, Emit: prop.EmitTrue},
+			"ItemIsMenu":          {Value: true, Emit: prop.EmitTrue},
+			"Menu":                {Value: menuPath, Emit: prop.EmitTrue},
+			// Ayatana's extension for text next to the icon, shown by
+			// GNOME's AppIndicator extension among others.
+			"XAyatanaLabel":      {Value: "", Emit: prop.EmitTrue},
+			"XAyatanaLabelGuide": {Value: "", Emit: prop.EmitTrue},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export StatusNotifierItem properties: %w", err)
+	}
+	t.props = props
+
+	menuProps, err := prop.Export(conn, menuPath, prop.Map{
+		menuInterface: {
+			"Version":       {Value: uint32(3), Emit: prop.EmitTrue},
+			"TextDirection": {Value: "ltr", Emit: prop.EmitTrue},
+			"Status":        {Value: "normal", Emit: prop.EmitTrue},
+			"IconThemePath": {Value: []string{}, Emit: prop.EmitTrue},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export dbusmenu properties: %w", err)
+	}
+
+	if err := conn.Export(item{}, itemPath, itemInterface); err != nil {
+		return nil, fmt.Errorf("failed to export StatusNotifierItem: %w", err)
+	}
+	if err := conn.Export(t.menu, menuPath, menuInterface); err != nil {
+		return nil, fmt.Errorf("failed to export dbusmenu: %w", err)
+	}
+	if err := exportIntrospection(conn, itemPath, itemInterface, item{}, props); err != nil {
+		return nil, err
+	}
+	if err := exportIntrospection(conn, menuPath, menuInterface, t.menu, menuProps); err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(t.name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request bus name %s: %w", t.name, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("bus name %s is already taken", t.name)
+	}
+
+	if err := t.watchForWatcher(); err != nil {
+		return nil, err
+	}
+	if err := t.register(); err != nil {
+		logf("StatusNotifierWatcher not available yet, waiting for it: %v", err)
+	}
+	return t, nil
+}
+
+// Name returns the unique bus name the item is published under.
+func (t *Tray) Name() string {
+	return t.name
+}
+
+// SetMenu replaces the tray menu and shows its badge next to the icon.
+func (t *Tray) SetMenu(model menu.Menu) {
+	t.menu.set(model)
+	t.setLabel(model.Badge)
+}
+
+// setLabel updates the text shown next to the icon by hosts that support
+// it.
+func (t *Tray) setLabel(label string) {
+	if current, _ := t.props.GetMust(itemInterface, "XAyatanaLabel").(string); current == label {
+		return
+	}
+	t.props.SetMust(itemInterface, "XAyatanaLabel", label)
+	if err := t.conn.Emit(itemPath, itemInterface+".XAyatanaNewLabel", label, ""); err != nil {
+		logf("Failed to emit XAyatanaNewLabel: %v", err)
+	}
+}
+
+// Close unregisters the item from the bus. It does not close the connection.
+func (t *Tray) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	close(t.done)
+	_, err := t.conn.ReleaseName(t.name)
+	return err
+}
+
+// register announces the item to the StatusNotifierWatcher.
+func (t *Tray) register() error {
+	obj := t.conn.Object(watcherName, watcherPath)
+	return obj.Call(watcherInterface+".RegisterStatusNotifierItem", 0, t.name).Err
+}
+
+// watchForWatcher re-registers whenever a StatusNotifierWatcher (re)appears,
+// e.g. when the panel restarts.
+func (t *Tray) watchForWatcher() error {
+	if err := t.conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, watcherName),
+	); err != nil {
+		return fmt.Errorf("failed to watch for StatusNotifierWatcher: %w", err)
+	}
+
+	signals := make(chan *dbus.Signal, 8)
+	t.conn.Signal(signals)
+	go func() {
+		defer t.conn.RemoveSignal(signals)
+		for {
+			select {
+			case <-t.done:
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if sig.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(sig.Body) < 3 {
+					continue
+				}
+				if name, _ := sig.Body[0].(string); name != watcherName {
+					continue
+				}
+				if owner, _ := sig.Body[2].(string); owner == "" {
+					continue
+				}
+				if err := t.register(); err != nil {
+					logf("Failed to register with StatusNotifierWatcher: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// exportIntrospection publishes introspection data for an exported object so
+// hosts that introspect before calling can find its methods and properties.
+func exportIntrospection(conn *dbus.Conn, path dbus.ObjectPath, iface string, obj interface{}, props *prop.Properties) error {
+	node := &introspect.Node{
+		Name: string(path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       iface,
+				Methods:    introspect.Methods(obj),
+				Properties: props.Introspection(iface),
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("failed to export introspection for %s: %w", path, err)
+	}
+	return nil
+}
+
+// toPixmap converts an image to the ARGB32 network-byte-order pixmap format
+// StatusNotifierItem hosts expect.
+func toPixmap(img image.Image) pixmap {
+	bounds := img.Bounds()
+	data := make([]byte, 0, bounds.Dx()*bounds.Dy()*4)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// RGBA returns premultiplied values; hosts expect straight alpha.
+			if a > 0 {
+				r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+			}
+			data = append(data, byte(a>>8), byte(r>>8), byte(g>>8), byte(b>>8))
+		}
+	}
+	return pixmap{Width: int32(bounds.Dx()), Height: int32(bounds.Dy()), Data: data}
+}
+
+func logf(format string, args ...interface{}) {
+	log.Printf("sni: "+format, args...)
+}
+
+type errUnknownItem int32
+
+func (e errUnknownItem) Error() string { return fmt.Sprintf("unknown menu item %d", int32(e)) }
+
+type errUnknownProperty string
+
+func (e errUnknownProperty) Error() string { return fmt.Sprintf("unknown property %q", string(e)) }
@@ -0,0 +1,246 @@
+package sni
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/pzurek/lil/internal/dbustest"
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/menu"
+)
+
+// fakeWatcher stands in for the panel's StatusNotifierWatcher.
+type fakeWatcher struct {
+	registered chan string
+}
+
+func (w *fakeWatcher) RegisterStatusNotifierItem(service string) *dbus.Error {
+	w.registered <- service
+	return nil
+}
+
+func startWatcher(t *testing.T, conn *dbus.Conn) *fakeWatcher {
+	t.Helper()
+	w := &fakeWatcher{registered: make(chan string, 4)}
+	if err := conn.Export(w, watcherPath, watcherInterface); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.RequestName(watcherName, dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func expectRegistration(t *testing.T, w *fakeWatcher, name string) {
+	t.Helper()
+	select {
+	case got := <-w.registered:
+		if got != name {
+			t.Errorf("Expected %s to register, got %s", name, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Tray never registered with the watcher")
+	}
+}
+
+// layoutNode is a decoded GetLayout entry.
+type layoutNode struct {
+	id       int32
+	props    map[string]dbus.Variant
+	children []layoutNode
+}
+
+func decodeLayout(t *testing.T, v []interface{}) layoutNode {
+	t.Helper()
+	n := layoutNode{id: v[0].(int32), props: v[1].(map[string]dbus.Variant)}
+	for _, child := range v[2].([]dbus.Variant) {
+		n.children = append(n.children, decodeLayout(t, child.Value().([]interface{})))
+	}
+	return n
+}
+
+func (n layoutNode) label() string {
+	s, _ := n.props["label"].Value().(string)
+	return s
+}
+
+func testMenu() menu.Menu {
+	issue := linear.Issue{Id: "1", Identifier: "ENG_1", Title: "Ship it", Url: "https://linear.app/test/issue/ENG_1"}
+	issue.Project.Id = "p1"
+	issue.Project.Name = "Launch"
+	return menu.Build(menu.Data{Issues: []linear.Issue{issue}})
+}
+
+func TestTrayExportsMenu(t *testing.T) {
+	address := dbustest.StartBus(t)
+	host := dbustest.Connect(t, address)
+	watcher := startWatcher(t, host)
+
+	clicks := make(chan menu.Item, 1)
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff})
+	tray, err := New(dbustest.Connect(t, address), Options{
+		ID:      "lil",
+		Title:   "Lil",
+		Icon:    img,
+		OnClick: func(item menu.Item) { clicks <- item },
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer tray.Close()
+	expectRegistration(t, watcher, tray.Name())
+
+	item := host.Object(tray.Name(), itemPath)
+	for name, want := range map[string]interface{}{
+		"Id":         "lil",
+		"ItemIsMenu": true,
+		"Menu":       menuPath,
+	} {
+		v, err := item.GetProperty(itemInterface + "." + name)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", name, err)
+		}
+		if v.Value() != want {
+			t.Errorf("Expected %s to be %v, got %v", name, want, v.Value())
+		}
+	}
+
+	var icons []pixmap
+	v, err := item.GetProperty(itemInterface + ".IconPixmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbus.Store([]interface{}{v.Value()}, &icons); err != nil {
+		t.Fatal(err)
+	}
+	if len(icons) != 1 || icons[0].Width != 2 || string(icons[0].Data[:4]) != "\xff\x11\x22\x33" {
+		t.Errorf("Unexpected icon pixmap %+v", icons)
+	}
+
+	// Hosts re-read the layout when told it changed
+	if err := host.AddMatchSignal(dbus.WithMatchInterface(menuInterface), dbus.WithMatchMember("LayoutUpdated")); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 4)
+	host.Signal(signals)
+
+	tray.SetMenu(testMenu())
+
+	select {
+	case sig := <-signals:
+		if sig.Name != menuInterface+".LayoutUpdated" {
+			t.Errorf("Unexpected signal %s", sig.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No LayoutUpdated signal after SetMenu")
+	}
+
+	var revision uint32
+	var raw []interface{}
+	call := host.Object(tray.Name(), menuPath).Call(menuInterface+".GetLayout", 0, int32(0), int32(-1), []string{})
+	if err := call.Store(&revision, &raw); err != nil {
+		t.Fatalf("GetLayout failed: %v", err)
+	}
+	root := decodeLayout(t, raw)
+
+	// Project header, issue, separator, Group By, Refresh Now, Quit
+	labels := []string{}
+	for _, child := range root.children {
+		if kind, _ := child.props["type"].Value().(string); kind == "separator" {
+			labels = append(labels, "---")
+			continue
+		}
+		labels = append(labels, child.label())
+	}
+	expected := []string{"Launch", "ENG__1: Ship it", "---", "Group By", "Refresh Now", "Quit Lil"}
+	if strings.Join(labels, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Expected layout %v, got %v", expected, labels)
+	}
+	if enabled := root.children[0].props["enabled"].Value(); enabled != false {
+		t.Errorf("Expected project header to be disabled")
+	}
+
+	issueID := root.children[1].id
+	call = host.Object(tray.Name(), menuPath).Call(menuInterface+".Event", 0, issueID, "clicked", dbus.MakeVariant(""), uint32(0))
+	if call.Err != nil {
+		t.Fatalf("Event failed: %v", call.Err)
+	}
+	select {
+	case clicked := <-clicks:
+		if clicked.Action.Kind != menu.ActionOpenURL || clicked.Action.URL != "https://linear.app/test/issue/ENG_1" {
+			t.Errorf("Unexpected clicked item %+v", clicked)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Click was not delivered")
+	}
+
+	badged := testMenu()
+	badged.Badge = "3"
+	tray.SetMenu(badged)
+	v, err = item.GetProperty(itemInterface + ".XAyatanaLabel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Value() != "3" {
+		t.Errorf("Expected the badge as the label, got %v", v.Value())
+	}
+}
+
+func TestTrayRegistersWhenWatcherAppears(t *testing.T) {
+	address := dbustest.StartBus(t)
+
+	tray, err := New(dbustest.Connect(t, address), Options{ID: "lil", Title: "Lil"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer tray.Close()
+
+	watcher := startWatcher(t, dbustest.Connect(t, address))
+	expectRegistration(t, watcher, tray.Name())
+}
+
+func TestBuildNodesSubmenu(t *testing.T) {
+	model := menu.Menu{Sections: []menu.Section{{Items: []menu.Item{{
+		Title:     "ENG-1: Ship it",
+		Enabled:   true,
+		Indicator: menu.Indicator{Color: "#f2c94c", IconName: "media-playback-start"},
+		Submenu: []menu.Section{
+			{Items: []menu.Item{{Title: "Open in Linear", Enabled: true}}},
+			{Header: "Move to", Items: []menu.Item{{Title: "Done", Checked: true}}},
+		},
+	}}}}}
+
+	root, nodes := buildNodes(model)
+	if len(root.children) != 1 {
+		t.Fatalf("Expected 1 top-level item, got %d", len(root.children))
+	}
+	parent := root.children[0]
+	if parent.properties["children-display"].Value() != "submenu" {
+		t.Error("Expected the parent item to be marked as a submenu")
+	}
+	if icon := parent.properties["icon-name"].Value(); icon != "media-playback-start" {
+		t.Errorf("Expected the state icon, got %v", icon)
+	}
+	urgent := itemPropertiesFor(menu.Item{Title: "ENG-2: Late", Indicator: menu.Indicator{IconName: "media-playback-start"}, Urgency: menu.Overdue})
+	if icon := urgent["icon-name"]; icon.Value() != "task-past-due" {
+		t.Errorf("Expected overdue items to get the past due icon, got %v", icon.Value())
+	}
+
+	// Open in Linear, separator, Move to header, Done
+	if len(parent.children) != 4 {
+		t.Fatalf("Expected 4 submenu entries, got %d", len(parent.children))
+	}
+	done := parent.children[3]
+	if done.properties["toggle-state"].Value() != int32(1) {
+		t.Error("Expected the checked item to have toggle-state 1")
+	}
+	if nodes[done.id] != done {
+		t.Error("Expected submenu items to be addressable by ID")
+	}
+}
@@ -0,0 +1,229 @@
+// Package webhook receives Linear webhook deliveries, so issue changes show
+// up without waiting for the next refresh.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body, keyed with the
+// webhook's signing secret.
+const SignatureHeader = "Linear-Signature"
+
+// MaxAge is how far a delivery's timestamp may be from the local clock.
+// Older deliveries are rejected as possible replays.
+const MaxAge = time.Minute
+
+// maxBodySize bounds the payloads read from the network.
+const maxBodySize = 1 << 20
+
+// Event actions.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+)
+
+// Event is an Issue event delivered by Linear.
+type Event struct {
+	// Action is ActionCreate, ActionUpdate or ActionRemove.
+	Action string
+	Issue  linear.Issue
+	// AssigneeID is the user the issue is assigned to, if any.
+	AssigneeID string
+	// Timestamp is when Linear sent the delivery.
+	Timestamp time.Time
+}
+
+// payload is the body of a webhook delivery. Data holds the entity, whose
+// fields mostly match those of the GraphQL API.
+type payload struct {
+	Action           string          `json:"action"`
+	Type             string          `json:"type"`
+	URL              string          `json:"url"`
+	Data             json.RawMessage `json:"data"`
+	WebhookTimestamp int64           `json:"webhookTimestamp"`
+}
+
+// issueData holds the fields of an Issue event that aren't part of
+// linear.Issue.
+type issueData struct {
+	Number     int    `json:"number"`
+	AssigneeID string `json:"assigneeId"`
+	ProjectID  string `json:"projectId"`
+	Team       struct {
+		Key string `json:"key"`
+	} `json:"team"`
+}
+
+// ErrIgnored is returned by Parse for deliveries about anything but issues.
+var ErrIgnored = errors.New("not an issue event")
+
+// Sign returns the signature of body under secret, as Linear sends it in
+// SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body under secret.
+func Verify(secret string, body []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// Parse decodes an Issue event. Deliveries of other types are reported as
+// ErrIgnored.
+func Parse(body []byte) (Event, error) {
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return Event{}, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if p.Type != "Issue" {
+		return Event{}, fmt.Errorf("%w: %s", ErrIgnored, p.Type)
+	}
+	switch p.Action {
+	case ActionCreate, ActionUpdate, ActionRemove:
+	default:
+		return Event{}, fmt.Errorf("unknown webhook action %q", p.Action)
+	}
+
+	var data struct {
+		linear.Issue
+		// Deliveries list labels directly rather than as a connection
+		Labels []linear.Label `json:"labels"`
+	}
+	var extra issueData
+	if err := json.Unmarshal(p.Data, &data); err != nil {
+		return Event{}, fmt.Errorf("invalid issue in webhook payload: %w", err)
+	}
+	issue := data.Issue
+	issue.Labels.Nodes = data.Labels
+	if err := json.Unmarshal(p.Data, &extra); err != nil {
+		return Event{}, fmt.Errorf("invalid issue in webhook payload: %w", err)
+	}
+	if issue.Id == "" {
+		return Event{}, errors.New("webhook payload has no issue ID")
+	}
+	// Deliveries name issues by team key and number rather than identifier
+	if issue.Identifier == "" && extra.Team.Key != "" && extra.Number > 0 {
+		issue.Identifier = extra.Team.Key + "-" + strconv.Itoa(extra.Number)
+	}
+	if issue.Url == "" {
+		issue.Url = p.URL
+	}
+	if issue.Assignee.Id == "" {
+		issue.Assignee.Id = extra.AssigneeID
+	}
+	if issue.Project.Id == "" {
+		issue.Project.Id = extra.ProjectID
+	}
+	return Event{
+		Action:     p.Action,
+		Issue:      issue,
+		AssigneeID: issue.Assignee.Id,
+		Timestamp:  time.UnixMilli(p.WebhookTimestamp),
+	}, nil
+}
+
+// ApplyTo applies event to the issues assigned to viewerID, returning the
+// new list and what changed.
+func ApplyTo(issues []linear.Issue, viewerID string, event Event) ([]linear.Issue, linear.Changes) {
+	if event.Action != ActionRemove && event.AssigneeID == viewerID {
+		updated := event.Issue
+		for _, issue := range issues {
+			// Deliveries only carry the project's ID
+			if issue.Id == updated.Id && issue.Project.Id == updated.Project.Id && updated.Project.Name == "" {
+				updated.Project = issue.Project
+			}
+		}
+		return linear.Merge(issues, []linear.Issue{updated})
+	}
+	// Deleted, or assigned to someone else
+	var changes linear.Changes
+	kept := make([]linear.Issue, 0, len(issues))
+	for _, issue := range issues {
+		if issue.Id == event.Issue.Id {
+			changes.Removed = append(changes.Removed, issue.Id)
+			continue
+		}
+		kept = append(kept, issue)
+	}
+	return kept, changes
+}
+
+// Handler receives webhook deliveries, verifies them and passes their Issue
+// events on.
+type Handler struct {
+	// Secret returns the signing secret of the webhook delivering to r.
+	Secret func(r *http.Request) (string, bool)
+	// Apply is called with each verified Issue event before the delivery is
+	// answered, so it should hand slow work off rather than do it.
+	Apply func(r *http.Request, event Event)
+	now   func() time.Time
+}
+
+// NewHandler returns a handler verifying deliveries with secret and passing
+// their events to apply.
+func NewHandler(secret func(r *http.Request) (string, bool), apply func(r *http.Request, event Event)) *Handler {
+	return &Handler{Secret: secret, Apply: apply, now: time.Now}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	secret, ok := h.Secret(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if !Verify(secret, body, r.Header.Get(SignatureHeader)) {
+		log.Printf("Rejected webhook delivery to %s with an invalid signature", r.URL.Path)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := Parse(body)
+	if errors.Is(err, ErrIgnored) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		log.Printf("Rejected webhook delivery to %s: %v", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if age := h.now().Sub(event.Timestamp); age > MaxAge || age < -MaxAge {
+		log.Printf("Rejected webhook delivery to %s sent at %s", r.URL.Path, event.Timestamp.Format(time.RFC3339))
+		http.Error(w, "stale delivery", http.StatusBadRequest)
+		return
+	}
+	h.Apply(r, event)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pzurek/lil/internal/linear"
+)
+
+const testSecret = "lin_wh_test"
+
+// deliver posts body with signature to h as Linear would at sentAt.
+func deliver(t *testing.T, h *Handler, body []byte, signature string, sentAt time.Time) int {
+	t.Helper()
+	h.now = func() time.Time { return sentAt }
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set(SignatureHeader, signature)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// TestReplayFixtures replays the recorded deliveries in testdata, in order,
+// against an issue list and checks what each one did to it.
+func TestReplayFixtures(t *testing.T) {
+	existing := func(id, identifier string) linear.Issue {
+		issue := linear.Issue{Id: id, Identifier: identifier}
+		issue.State.Type = "started"
+		return issue
+	}
+	issues := []linear.Issue{existing("issue-7", "ENG-7"), existing("issue-9", "ENG-9")}
+
+	steps := []struct {
+		fixture string
+		status  int
+		changes string
+		issues  string
+	}{
+		{fixture: "issue_create.json", status: http.StatusNoContent, changes: "{[issue-42] [] []}", issues: "[ENG-7 ENG-9 ENG-42]"},
+		{fixture: "issue_update.json", status: http.StatusNoContent, changes: "{[] [issue-42] []}", issues: "[ENG-7 ENG-9 ENG-42]"},
+		{fixture: "comment_create.json", status: http.StatusNoContent},
+		{fixture: "issue_completed.json", status: http.StatusNoContent, changes: "{[] [] [issue-7]}", issues: "[ENG-9 ENG-42]"},
+		{fixture: "issue_remove.json", status: http.StatusNoContent, changes: "{[] [] [issue-9]}", issues: "[ENG-42]"},
+		{fixture: "issue_unassigned.json", status: http.StatusNoContent, changes: "{[] [] [issue-42]}", issues: "[]"},
+	}
+
+	var applied []Event
+	h := NewHandler(
+		func(r *http.Request) (string, bool) { return testSecret, true },
+		func(r *http.Request, event Event) { applied = append(applied, event) },
+	)
+	for _, step := range steps {
+		t.Run(strings.TrimSuffix(step.fixture, ".json"), func(t *testing.T) {
+			body := readFixture(t, step.fixture)
+			event, err := Parse(body)
+			sentAt := event.Timestamp.Add(2 * time.Second)
+			if err != nil {
+				sentAt = time.Now()
+			}
+
+			applied = nil
+			if status := deliver(t, h, body, Sign(testSecret, body), sentAt); status != step.status {
+				t.Fatalf("Expected status %d, got %d", step.status, status)
+			}
+			if step.changes == "" {
+				if len(applied) != 0 {
+					t.Errorf("Expected the delivery to be ignored, got %+v", applied)
+				}
+				return
+			}
+			if len(applied) != 1 {
+				t.Fatalf("Expected one event, got %+v", applied)
+			}
+
+			var changes linear.Changes
+			issues, changes = ApplyTo(issues, "user-1", applied[0])
+			if fmt.Sprint(changes) != step.changes {
+				t.Errorf("Expected changes %s, got %v", step.changes, changes)
+			}
+			var identifiers []string
+			for _, issue := range issues {
+				identifiers = append(identifiers, issue.Identifier)
+			}
+			if got := fmt.Sprint(identifiers); got != step.issues {
+				t.Errorf("Expected issues %s, got %s", step.issues, got)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	event, err := Parse(readFixture(t, "issue_update.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	issue := event.Issue
+	if event.Action != ActionUpdate || issue.Id != "issue-42" || issue.Identifier != "ENG-42" || issue.Title != "Fix the login page" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if issue.Url != "https://linear.app/acme/issue/ENG-42/fix-the-login-page" || issue.DueDate != "2024-03-08" || issue.Priority != 2 {
+		t.Errorf("Expected the URL, due date and priority to be set, got %+v", issue)
+	}
+	if issue.State.Id != "state-doing" || issue.State.Type != "started" || issue.Team.Key != "ENG" || event.AssigneeID != "user-1" {
+		t.Errorf("Expected the state, team and assignee to be set, got %+v", issue)
+	}
+	if issue.Cycle.Number != 12 || len(issue.Labels.Nodes) != 1 || issue.Labels.Nodes[0].Name != "Bug" {
+		t.Errorf("Expected the cycle and labels to be set, got %+v and %+v", issue.Cycle, issue.Labels)
+	}
+	if !event.Timestamp.Equal(time.Date(2024, 3, 1, 14, 35, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected timestamp %s", event.Timestamp)
+	}
+
+	// Updates keep the project details deliveries leave out
+	created, _ := Parse(readFixture(t, "issue_create.json"))
+	issues, _ := ApplyTo(nil, "user-1", created)
+	issues, _ = ApplyTo(issues, "user-1", event)
+	if issues[0].Project.Name != "Authentication" {
+		t.Errorf("Expected the project name to be kept, got %+v", issues[0].Project)
+	}
+}
+
+func TestHandlerRejects(t *testing.T) {
+	body := readFixture(t, "issue_create.json")
+	event, _ := Parse(body)
+	var applied int
+	h := NewHandler(
+		func(r *http.Request) (string, bool) { return testSecret, true },
+		func(r *http.Request, event Event) { applied++ },
+	)
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		sentAt    time.Time
+		status    int
+	}{
+		{name: "Wrong secret", body: body, signature: Sign("other", body), sentAt: event.Timestamp, status: http.StatusUnauthorized},
+		{name: "Missing signature", body: body, sentAt: event.Timestamp, status: http.StatusUnauthorized},
+		{name: "Tampered body", body: bytes.Replace(body, []byte("Fix the login page"), []byte("Pwned"), 1), signature: Sign(testSecret, body), sentAt: event.Timestamp, status: http.StatusUnauthorized},
+		{name: "Replayed", body: body, signature: Sign(testSecret, body), sentAt: event.Timestamp.Add(10 * time.Minute), status: http.StatusBadRequest},
+		{name: "Malformed", body: []byte(`{"type":`), signature: Sign(testSecret, []byte(`{"type":`)), sentAt: event.Timestamp, status: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if status := deliver(t, h, tc.body, tc.signature, tc.sentAt); status != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, status)
+			}
+		})
+	}
+	if applied != 0 {
+		t.Errorf("Expected no rejected delivery to be applied, got %d", applied)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhook", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be refused, got %d", rec.Code)
+	}
+
+	unknown := NewHandler(func(r *http.Request) (string, bool) { return "", false }, nil)
+	if status := deliver(t, unknown, body, Sign(testSecret, body), event.Timestamp); status != http.StatusNotFound {
+		t.Errorf("Expected deliveries to unknown webhooks to be refused, got %d", status)
+	}
+}
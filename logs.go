@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/pzurek/lil/internal/cache"
+)
+
+// maxLogSize is the size above which the log file is rotated at startup.
+const maxLogSize = 1 << 20
+
+// logPath is the file the tray app logs to, empty if it couldn't be opened
+var logPath string
+
+// openLogFile copies the log to lil.log in the cache directory, so errors
+// shown in the menu can be looked into. The previous log is kept as
+// lil.log.1 once it grows past maxLogSize.
+func openLogFile() {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		log.Printf("Warning: %v, logging to stderr only", err)
+		return
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		log.Printf("Warning: Failed to create %s, logging to stderr only: %v", dir, err)
+		return
+	}
+	path := filepath.Join(dir, "lil.log")
+	if info, err := os.Stat(path); err == nil && info.Size() > maxLogSize {
+		_ = os.Rename(path, path+".1")
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Printf("Warning: Failed to open log file, logging to stderr only: %v", err)
+		return
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, f))
+	logPath = path
+}
+
+// openLogs shows the log file in the default viewer.
+func openLogs() {
+	if logPath == "" {
+		log.Println("Error: There is no log file to open")
+		return
+	}
+	openURL((&url.URL{Scheme: "file", Path: logPath}).String())
+}
@@ -3,354 +3,343 @@ package main
 import (
 	"context"
 	_ "embed"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
-	"sort"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/progrium/darwinkit/dispatch"
-	"github.com/progrium/darwinkit/macos/appkit"
-	"github.com/progrium/darwinkit/macos/foundation"
-	"github.com/progrium/darwinkit/objc"
-
+	"github.com/pzurek/lil/internal/cache"
+	"github.com/pzurek/lil/internal/config"
+	"github.com/pzurek/lil/internal/credentials"
 	"github.com/pzurek/lil/internal/linear"
-	"github.com/pzurek/lil/internal/linear/schema"
+	"github.com/pzurek/lil/internal/menu"
+	"github.com/pzurek/lil/internal/notify"
+	"github.com/pzurek/lil/internal/scheduler"
 )
 
-// Global variables for UI elements
-var (
-	statusItem appkit.StatusItem
-)
+// refresher periodically re-fetches issues in the background
+var refresher *scheduler.Scheduler
+
+// refreshInterval overrides the configured refresh interval when set with -interval
+var refreshInterval time.Duration
+
+// configPath is the config file that is watched for changes
+var configPath string
+
+// configWatcher reloads the config file when it changes. It is created before
+// the file is first loaded, so edits saved while lil starts aren't missed.
+var configWatcher *config.Watcher
+
+// appConfig is the current configuration. Use currentConfig and setConfig,
+// the config file watcher replaces it while the app runs.
+var appConfig = struct {
+	sync.Mutex
+	cfg *config.Config
+}{cfg: config.Default()}
 
 //go:embed assets/icon_template_36.png
 var iconData []byte
 
-// Define a far future time for sorting items without dates
-var distantFuture = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+// cacheStore keeps issue data between restarts
+var cacheStore *cache.Store
 
-// CacheFile is where we store issue data between restarts
-const CacheFile = "/tmp/lil_issues_cache.json"
+// legacyCacheFile is where issues were cached before the per-user cache store
+const legacyCacheFile = "/tmp/lil_issues_cache.json"
 
 // Version and build information - set at build time
 var version string
 var buildTime string
 
-// Structure to hold project info for sorting
-type projectSortInfo struct {
-	name         string
-	earliestDate time.Time
-	issues       []*schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue
+// startRefreshing shows cached issues, if any, and starts the background
+// refresh scheduler. Tray backends call it once their menu is ready.
+func startRefreshing() {
+	// Attempt to load and display cached issues first. Workspaces without a
+	// cache show "Loading..." until the first fetch completes.
+	for _, w := range currentWorkspaces() {
+		w.loadCache()
+	}
+	renderMenu()
+
+	// Fetch issues now and keep refreshing in the background (will replace the menu again)
+	refresher = scheduler.New(fetchIssuesAndUpdateMenu, scheduler.Config{
+		Interval:  effectiveInterval(currentConfig()),
+		NotBefore: rateLimitHold,
+	})
+	go refresher.Run(context.Background())
+
+	// Apply changes pushed by Linear between refreshes
+	listenForWebhooks(currentConfig().Webhook.Listen)
+
+	// Pick up config changes without a restart
+	go configWatcher.Run(context.Background(), config.DefaultPollInterval)
 }
 
-// ApplicationDidFinishLaunching is called when the app has finished launching.
-func applicationDidFinishLaunching(notification foundation.Notification) {
-	log.Println("Application finished launching. Setting up status bar item...")
+// currentConfig returns the configuration in effect.
+func currentConfig() *config.Config {
+	appConfig.Lock()
+	defer appConfig.Unlock()
+	return appConfig.cfg
+}
 
-	// Get the system status bar
-	statusBar := appkit.StatusBar_SystemStatusBar()
+// setConfig replaces the configuration in effect.
+func setConfig(cfg *config.Config) {
+	appConfig.Lock()
+	defer appConfig.Unlock()
+	appConfig.cfg = cfg
+}
 
-	// Create a new status item and assign to the global variable
-	statusItem = statusBar.StatusItemWithLength(appkit.VariableStatusItemLength)
-	objc.Retain(&statusItem) // Explicitly retain the global status item
+// groupingChoice is the grouping picked from the menu. It overrides the
+// config file's until the file's grouping changes or lil quits.
+var groupingChoice = struct {
+	sync.Mutex
+	mode string
+}{}
+
+// effectiveGrouping is the grouping picked from the menu, or the one from the
+// config if none was.
+func effectiveGrouping(cfg *config.Config) string {
+	groupingChoice.Lock()
+	defer groupingChoice.Unlock()
+	if groupingChoice.mode != "" {
+		return groupingChoice.mode
+	}
+	return cfg.Grouping
+}
 
-	// Get the status item's button
-	button := statusItem.Button()
-	if button.IsNil() {
-		log.Fatalln("Could not get status item button")
+// setGrouping picks the grouping of the menu, until the config file says
+// otherwise. An empty mode goes back to the config file's.
+func setGrouping(mode string) {
+	groupingChoice.Lock()
+	defer groupingChoice.Unlock()
+	groupingChoice.mode = mode
+}
+
+// effectiveInterval is the refresh interval from the config, unless it was
+// overridden on the command line.
+func effectiveInterval(cfg *config.Config) time.Duration {
+	if refreshInterval > 0 {
+		return refreshInterval
 	}
+	return cfg.RefreshInterval
+}
 
-	// Create NSImage from embedded data
-	if len(iconData) == 0 {
-		log.Fatalln("Icon data is empty")
+// applyConfig is called by the config file watcher. An invalid file is
+// reported and the previous configuration stays in effect.
+func applyConfig(cfg *config.Config, err error) {
+	if err != nil {
+		log.Printf("Error: Ignoring changes to %s: %v", configPath, err)
+		return
 	}
-	image := appkit.ImageClass.Alloc().InitWithData(iconData)
-	if image.IsNil() {
-		log.Fatalln("Could not create appkit.Image from icon data")
+	log.Printf("Reloaded configuration from %s", configPath)
+	previous := currentConfig()
+	setConfig(cfg)
+	if cfg.Grouping != previous.Grouping {
+		setGrouping("")
 	}
-	image.SetTemplate(true)
-	image.SetSize(foundation.Size{Width: 18, Height: 18})
-
-	// Set the button's image
-	button.SetImage(image)
-
-	// Create the initial menu with Loading... and Quit
-	initialMenu := appkit.MenuClass.New()
-	loadingItem := appkit.MenuItemClass.New()
-	loadingItem.SetTitle("Loading...")
-	loadingItem.SetEnabled(false)
-	initialMenu.AddItem(loadingItem)
-	initialMenu.AddItem(appkit.MenuItemClass.SeparatorItem())
-	quitItem := appkit.MenuItemClass.New()
-	quitItem.SetTitle("Quit Lil")
-	quitItem.SetAction(objc.Sel("terminate:"))
-	quitItem.SetTarget(appkit.Application_SharedApplication())
-	initialMenu.AddItem(quitItem)
-
-	// Assign the initial menu to the status item
-	statusItem.SetMenu(initialMenu)
-
-	// Attempt to load and display cached issues first
-	cachedIssues, err := loadCachedIssues()
-	if err == nil && len(cachedIssues) > 0 {
-		log.Printf("Loaded %d issues from cache.", len(cachedIssues))
-		// Update menu immediately with cached data (will replace the initial menu)
-		updateMenu(cachedIssues)
-	} else {
-		if err != nil && !os.IsNotExist(err) {
-			log.Printf("Warning: Failed to load cached issues: %v", err)
+	added := syncWorkspaces(cfg)
+	listenForWebhooks(cfg.Webhook.Listen)
+	if refresher != nil {
+		refresher.SetInterval(effectiveInterval(cfg))
+		// New filters fetch different issues
+		if len(added) > 0 || !reflect.DeepEqual(previous.Filters, cfg.Filters) {
+			refresher.RefreshNow()
 		}
-		// If no cache, the "Loading..." state persists until fetch completes
 	}
-
-	// Fetch issues in the background (will replace the menu again)
-	go fetchIssuesAndUpdateMenu()
+	renderMenu()
 }
 
-// updateMenu rebuilds the menu based on the provided issues.
-// It now creates a NEW menu and assigns it to the statusItem.
-func updateMenu(issues []schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) {
-	log.Println("Updating menu...")
-	// Create a new menu instance for this update
-	newMenu := appkit.MenuClass.New()
-
-	if issues == nil { // Handle error case from fetch
-		errorItem := appkit.MenuItemClass.Alloc().InitWithTitleActionKeyEquivalent("Error fetching issues", objc.Sel(""), "")
-		errorItem.SetEnabled(false)
-		newMenu.AddItem(errorItem)
-	} else if len(issues) == 0 {
-		noIssuesItem := appkit.MenuItemClass.Alloc().InitWithTitleActionKeyEquivalent("No active assigned issues", objc.Sel(""), "")
-		noIssuesItem.SetEnabled(false)
-		newMenu.AddItem(noIssuesItem)
-	} else {
-		// Group by project (Re-using the existing logic)
-		projectsMap := make(map[string]*projectSortInfo)
-		noProjectKey := "__no_project__" // Internal key that won't be displayed
-
-		for i := range issues {
-			issueRef := issues[i]
-			projectName := noProjectKey
-			projectTargetDate := distantFuture
-			if issueRef.Project.Id != "" {
-				projectName = issueRef.Project.Name
-				projectTargetDate = parseLinearDate(issueRef.Project.TargetDate)
-			}
-
-			issueDueDate := parseLinearDate(issueRef.DueDate)
-			issueCreateDate := parseLinearDate(issueRef.CreatedAt)
-			effectiveIssueDate := issueDueDate
-			if effectiveIssueDate.Equal(distantFuture) {
-				effectiveIssueDate = issueCreateDate
-			}
-
-			if _, exists := projectsMap[projectName]; !exists {
-				projectsMap[projectName] = &projectSortInfo{
-					name:         projectName,
-					earliestDate: distantFuture,
-					issues:       []*schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue{},
-				}
-				if !projectTargetDate.Equal(distantFuture) {
-					projectsMap[projectName].earliestDate = projectTargetDate
-				}
-			}
-
-			projectsMap[projectName].issues = append(projectsMap[projectName].issues, &issueRef)
-
-			if !projectTargetDate.Equal(distantFuture) {
-				if projectTargetDate.Before(projectsMap[projectName].earliestDate) {
-					projectsMap[projectName].earliestDate = projectTargetDate
-				}
-			} else {
-				if effectiveIssueDate.Before(projectsMap[projectName].earliestDate) {
-					projectsMap[projectName].earliestDate = effectiveIssueDate
-				}
-			}
-		}
+// renderMenu builds the menu from what was last fetched from each workspace
+// and the current config, without fetching again.
+func renderMenu() {
+	cfg := currentConfig()
+	var list []menu.Workspace
+	for _, w := range currentWorkspaces() {
+		list = append(list, w.menuWorkspace(cfg))
+	}
+	showMenu(menu.BuildWorkspaces(list))
+}
 
-		// Convert map to slice for sorting
-		sortedProjects := make([]*projectSortInfo, 0, len(projectsMap))
-		for _, info := range projectsMap {
-			sortedProjects = append(sortedProjects, info)
+// handleAction performs the action attached to a clicked menu item.
+func handleAction(item menu.Item) {
+	action := item.Action
+	switch action.Kind {
+	case menu.ActionOpenURL:
+		openURL(action.URL)
+	case menu.ActionRefresh:
+		log.Println("Manual refresh requested")
+		if refresher != nil {
+			refresher.RefreshNow()
 		}
-
-		// Sort projects by earliest date
-		sort.Slice(sortedProjects, func(i, j int) bool {
-			if sortedProjects[i].name == noProjectKey {
-				return false // No-project group always last
-			}
-			if sortedProjects[j].name == noProjectKey {
-				return true // No-project group always last
-			}
-			return sortedProjects[i].earliestDate.Before(sortedProjects[j].earliestDate)
+	case menu.ActionQuit:
+		quit()
+	case menu.ActionSetState:
+		go inWorkspace(action.Workspace, "updating issue state", func(ctx context.Context, w *workspace) error {
+			log.Printf("Moving issue %s to state %s", action.IssueID, action.StateID)
+			w.expect(action.IssueID, notify.StateChanged)
+			return w.client.UpdateIssueState(ctx, action.IssueID, action.StateID)
 		})
-
-		// Add projects and issues to the new menu
-		for i, projectInfo := range sortedProjects {
-			if i > 0 {
-				newMenu.AddItem(appkit.MenuItemClass.SeparatorItem())
-			}
-
-			if projectInfo.name != noProjectKey {
-				projectHeader := appkit.MenuItemClass.Alloc().InitWithTitleActionKeyEquivalent(projectInfo.name, objc.Sel(""), "")
-				projectHeader.SetEnabled(false)
-				newMenu.AddItem(projectHeader)
-			}
-
-			// Sort issues within project
-			sort.Slice(projectInfo.issues, func(i, j int) bool {
-				issueI := projectInfo.issues[i]
-				issueJ := projectInfo.issues[j]
-				dateI := parseLinearDate(issueI.DueDate)
-				if dateI.Equal(distantFuture) {
-					dateI = parseLinearDate(issueI.CreatedAt)
-				}
-				dateJ := parseLinearDate(issueJ.DueDate)
-				if dateJ.Equal(distantFuture) {
-					dateJ = parseLinearDate(issueJ.CreatedAt)
-				}
-				return dateI.Before(dateJ)
-			})
-
-			// Add issue items
-			for _, issuePtr := range projectInfo.issues {
-				localIssue := *issuePtr // Important: Make a copy for the closure
-				menuTitle := localIssue.Identifier + ": " + localIssue.Title
-
-				// Create tooltip
-				tooltipLines := []string{}
-				if localIssue.Project.Id != "" {
-					tooltipLines = append(tooltipLines, "Project: "+localIssue.Project.Name)
-				}
-				if localIssue.DueDate != "" {
-					dueDate := parseLinearDate(localIssue.DueDate)
-					if !dueDate.Equal(distantFuture) {
-						tooltipLines = append(tooltipLines, "Due: "+dueDate.Format("Jan 2, 2006"))
-					}
-				}
-				if localIssue.Assignee.Id != "" {
-					assigneeName := localIssue.Assignee.Name
-					if localIssue.Assignee.DisplayName != "" {
-						assigneeName = localIssue.Assignee.DisplayName
-					}
-					tooltipLines = append(tooltipLines, "Assignee: "+assigneeName)
-				}
-				if localIssue.State.Id != "" {
-					tooltipLines = append(tooltipLines, "Status: "+localIssue.State.Type)
-				}
-				tooltip := strings.Join(tooltipLines, "\n")
-
-				// Create menu item with inline action closure
-				newItem := appkit.NewMenuItemWithAction(menuTitle, "", func(sender objc.Object) {
-					log.Printf("Clicked issue: %s", localIssue.Identifier)
-					url := foundation.URLClass.URLWithString(localIssue.Url)
-					if url.IsNil() {
-						log.Printf("Error: Could not create URL from string: %s", localIssue.Url)
-						return
-					}
-					ok := appkit.Workspace_SharedWorkspace().OpenURL(url)
-					if !ok {
-						log.Printf("Error: Failed to open URL %s", localIssue.Url)
-					}
-				})
-				newItem.SetToolTip(tooltip)
-
-				newMenu.AddItem(newItem)
-			}
-		}
+	case menu.ActionCreateIssue:
+		go createIssue(action.Workspace, action.TeamID)
+	case menu.ActionOpenNotification:
+		openURL(action.URL)
+		go inWorkspace(action.Workspace, "updating notification", func(ctx context.Context, w *workspace) error {
+			return w.client.MarkNotificationRead(ctx, action.NotificationID)
+		})
+	case menu.ActionMarkRead:
+		go inWorkspace(action.Workspace, "updating notification", func(ctx context.Context, w *workspace) error {
+			return w.client.MarkNotificationRead(ctx, action.NotificationID)
+		})
+	case menu.ActionArchive:
+		go inWorkspace(action.Workspace, "archiving notification", func(ctx context.Context, w *workspace) error {
+			return w.client.ArchiveNotification(ctx, action.NotificationID)
+		})
+	case menu.ActionSignIn:
+		go signIn(action.Workspace)
+	case menu.ActionOpenLogs:
+		openLogs()
+	case menu.ActionSetGrouping:
+		setGrouping(action.Grouping)
+		renderMenu()
 	}
-
-	// Add separator and Quit item to the new menu
-	newMenu.AddItem(appkit.MenuItemClass.SeparatorItem())
-	quitItem := appkit.MenuItemClass.New()
-	quitItem.SetTitle("Quit Lil")
-	quitItem.SetAction(objc.Sel("terminate:"))
-	quitItem.SetTarget(appkit.Application_SharedApplication())
-	newMenu.AddItem(quitItem)
-
-	// Assign the completely new menu to the status item
-	statusItem.SetMenu(newMenu)
-	log.Println("Menu updated successfully.")
 }
 
-// fetchIssuesAndUpdateMenu fetches issues from Linear and updates the menu.
-func fetchIssuesAndUpdateMenu() {
-	log.Println("Fetching issues and triggering menu update...")
+// inWorkspace runs a change against the named workspace and refreshes the
+// menu so it reflects the change.
+func inWorkspace(name, what string, change func(ctx context.Context, w *workspace) error) {
+	w := findWorkspace(name)
+	if w == nil {
+		log.Printf("Error %s: workspace %s is no longer configured", what, name)
+		return
+	}
 
-	ctx := context.Background()
-	issues, err := linear.FetchAssignedIssues(ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), linear.DefaultTimeout)
+	defer cancel()
 
-	// Use a separate variable for the issues/error to pass to the main thread
-	var issuesToUpdate []schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue
-	var fetchErr error
+	if err := change(ctx, w); err != nil {
+		log.Printf("Error %s: %v", what, err)
+	}
+	if refresher != nil {
+		refresher.RefreshNow()
+	}
+}
 
-	if err != nil {
-		log.Printf("Error fetching issues: %v", err)
-		fetchErr = err // Store the error
-	} else {
-		log.Printf("Successfully fetched %d active issues.", len(issues))
-		issuesToUpdate = issues // Store the fetched issues
-		if cacheErr := cacheIssues(issues); cacheErr != nil {
-			log.Printf("Error caching issues: %v", cacheErr)
-			// Continue anyway, caching is not critical
+// createIssue prompts for a title and creates an issue in the given team,
+// assigned to the viewer, then refreshes the menu so it shows up.
+func createIssue(workspaceName, teamID string) {
+	message := "Title of the new issue:"
+	if w := findWorkspace(workspaceName); w != nil {
+		if team, ok := w.team(teamID); ok {
+			message = fmt.Sprintf("Title of the new %s issue:", team.Key)
 		}
 	}
+	title, ok := promptText("New Issue", message)
+	if !ok || title == "" {
+		return
+	}
 
-	// Update menu on the main thread
-	dispatch.MainQueue().DispatchAsync(func() {
-		if fetchErr != nil {
-			updateMenu(nil) // Pass nil to indicate error
-		} else {
-			updateMenu(issuesToUpdate)
+	inWorkspace(workspaceName, "creating issue", func(ctx context.Context, w *workspace) error {
+		issue, err := w.client.CreateIssue(ctx, linear.IssueInput{TeamID: teamID, Title: title})
+		if err == nil {
+			log.Printf("Created issue %s", issue.Identifier)
+			w.expect(issue.Id, notify.Assigned)
 		}
+		return err
 	})
 }
 
-// parseLinearDate parses Linear's date format.
-// Returns distantFuture if parsing fails or input is empty.
-func parseLinearDate(dateStr string) time.Time {
-	if dateStr == "" {
-		return distantFuture
+// signIn runs the OAuth sign-in of the workspace, or asks for a new API key
+// and stores it after checking it with Linear. Either way the workspace is
+// refreshed with the new credentials.
+func signIn(name string) {
+	ws, ok := currentConfig().Workspace(name)
+	w := findWorkspace(name)
+	if !ok || w == nil {
+		log.Printf("Error signing in: workspace %s is no longer configured", name)
+		return
 	}
-	t, err := time.Parse(time.RFC3339, dateStr)
-	if err != nil {
-		t, err = time.Parse("2006-01-02", dateStr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+
+	if login := oauthLogin(ws); login != nil {
+		if err := login(ctx, openURL); err != nil {
+			log.Printf("Error signing in to %s: %v", ws.Name, err)
+			return
+		}
+		log.Printf("Signed in to %s", ws.Name)
+	} else {
+		key, ok := promptSecret("Linear API Key", fmt.Sprintf("Paste a personal API key for %s (Linear settings, Security & access):", ws.Name))
+		if !ok || key == "" {
+			return
+		}
+		client := linear.NewClient(linear.WithUserAgent(userAgent()), linear.WithAPIKey(key))
+		viewer, err := client.Viewer(ctx)
 		if err != nil {
-			log.Printf("Warning: Could not parse date '%s': %v", dateStr, err)
-			return distantFuture
+			log.Printf("Error signing in to %s: linear rejected the API key: %v", ws.Name, err)
+			return
 		}
+		store, err := storeAPIKey(credentials.Resolve(ws.Credentials), ws.Name, key)
+		if err != nil {
+			log.Printf("Error signing in to %s: failed to store API key: %v", ws.Name, err)
+			return
+		}
+		log.Printf("Signed in to %s as %s, key stored in %s", ws.Name, viewer.Name, store.Name())
+		w.apiKey.reset()
 	}
-	return t
-}
 
-// cacheIssues saves the issues to a cache file for later use when restarting
-func cacheIssues(issues []schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue) error {
-	data, err := json.Marshal(issues)
-	if err != nil {
-		return err
+	if refresher != nil {
+		refresher.RefreshNow()
 	}
-	return os.WriteFile(CacheFile, data, 0644)
 }
 
-// loadCachedIssues loads issues from the cache file
-func loadCachedIssues() ([]schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue, error) {
-	data, err := os.ReadFile(CacheFile)
+// fetchIssuesAndUpdateMenu fetches issues from every workspace and updates
+// the menu. The returned error lets the refresh scheduler back off after
+// failures.
+func fetchIssuesAndUpdateMenu(ctx context.Context) error {
+	log.Println("Fetching issues and triggering menu update...")
+	err := fetchAll(ctx)
+	renderMenu()
+	return err
+}
+
+// openCacheStore opens the per-user cache and removes the world-readable
+// cache file older versions wrote to /tmp.
+func openCacheStore() *cache.Store {
+	if err := os.Remove(legacyCacheFile); err == nil {
+		log.Printf("Removed legacy cache file %s", legacyCacheFile)
+	}
+	dir, err := cache.DefaultDir()
 	if err != nil {
-		return nil, err
+		log.Printf("Warning: %v, caching in the temporary directory", err)
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("lil-%d", os.Getuid()))
 	}
+	return cache.New(dir)
+}
 
-	var issues []schema.GetAssignedIssuesViewerUserAssignedIssuesIssueConnectionNodesIssue
-	err = json.Unmarshal(data, &issues)
-	return issues, err
+// userAgent identifies lil and its version to the Linear API.
+func userAgent() string {
+	if version == "" {
+		return "lil/dev"
+	}
+	return "lil/" + version
 }
 
 func main() {
 	runtime.LockOSThread()
 
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\n%s", commandUsage)
+	}
 	versionFlag := flag.Bool("version", false, "Print version information and exit")
+	flag.DurationVar(&refreshInterval, "interval", 0, "How often to refresh issues in the background (overrides the config file)")
+	flag.StringVar(&configPath, "config", "", "Path to the config file (default: lil/config.yaml in the user config directory)")
 	flag.Parse()
 
 	if *versionFlag {
@@ -361,6 +350,36 @@ func main() {
 		}
 		return
 	}
+	if refreshInterval != 0 && refreshInterval < config.MinRefreshInterval {
+		log.Fatalf("Error: -interval must be at least %s, got %s", config.MinRefreshInterval, refreshInterval)
+	}
+
+	if configPath == "" {
+		path, err := config.DefaultPath()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		configPath = path
+	}
+	configWatcher = config.NewWatcher(configPath, applyConfig)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Error in %s: %v", configPath, err)
+	}
+	setConfig(cfg)
+	syncWorkspaces(cfg)
+	cacheStore = openCacheStore()
+
+	// Run a headless subcommand instead of the tray if one was given
+	if flag.NArg() > 0 {
+		os.Exit(newCLI(os.Stdin, os.Stdout, os.Stderr).run(context.Background(), flag.Args()))
+	}
+
+	openLogFile()
+	notifier = notify.System()
+	credentials.PromptPassphrase = func(path string) (string, bool) {
+		return promptSecret("Lil Credentials", fmt.Sprintf("Enter the passphrase of %s:", path))
+	}
 
 	// Log version info early
 	if version != "" {
@@ -369,13 +388,7 @@ func main() {
 		log.Printf("Lil development version")
 	}
 
-	// Setup and run the AppKit application manually
-	app := appkit.Application_SharedApplication()
-	delegate := &appkit.ApplicationDelegate{}
-	// Assign the launch handler
-	delegate.SetApplicationDidFinishLaunching(applicationDidFinishLaunching)
-	app.SetDelegate(delegate)
-	app.SetActivationPolicy(appkit.ApplicationActivationPolicyProhibited)
-	// app.ActivateIgnoringOtherApps(true) // Removed: May interfere with accessory apps
-	app.Run()
+	if err := runTray(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 }
@@ -0,0 +1,232 @@
+//go:build darwin
+
+package main
+
+import (
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/progrium/darwinkit/dispatch"
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/macos/foundation"
+	"github.com/progrium/darwinkit/objc"
+
+	"github.com/pzurek/lil/internal/menu"
+)
+
+// Global variables for UI elements
+var (
+	statusItem appkit.StatusItem
+)
+
+// runTray sets up and runs the AppKit application. It does not return.
+func runTray() error {
+	// Setup and run the AppKit application manually
+	app := appkit.Application_SharedApplication()
+	delegate := &appkit.ApplicationDelegate{}
+	// Assign the launch handler
+	delegate.SetApplicationDidFinishLaunching(applicationDidFinishLaunching)
+	app.SetDelegate(delegate)
+	app.SetActivationPolicy(appkit.ApplicationActivationPolicyProhibited)
+	// app.ActivateIgnoringOtherApps(true) // Removed: May interfere with accessory apps
+	app.Run()
+	return nil
+}
+
+// ApplicationDidFinishLaunching is called when the app has finished launching.
+func applicationDidFinishLaunching(notification foundation.Notification) {
+	log.Println("Application finished launching. Setting up status bar item...")
+
+	// Get the system status bar
+	statusBar := appkit.StatusBar_SystemStatusBar()
+
+	// Create a new status item and assign to the global variable
+	statusItem = statusBar.StatusItemWithLength(appkit.VariableStatusItemLength)
+	objc.Retain(&statusItem) // Explicitly retain the global status item
+
+	// Get the status item's button
+	button := statusItem.Button()
+	if button.IsNil() {
+		log.Fatalln("Could not get status item button")
+	}
+
+	// Create NSImage from embedded data
+	if len(iconData) == 0 {
+		log.Fatalln("Icon data is empty")
+	}
+	image := appkit.ImageClass.Alloc().InitWithData(iconData)
+	if image.IsNil() {
+		log.Fatalln("Could not create appkit.Image from icon data")
+	}
+	image.SetTemplate(true)
+	image.SetSize(foundation.Size{Width: 18, Height: 18})
+
+	// Set the button's image, to the left of the badge
+	button.SetImage(image)
+	button.SetImagePosition(appkit.ImageLeft)
+
+	// Create the initial menu with Loading... and Quit
+	updateMenu(menu.Loading())
+
+	startRefreshing()
+}
+
+// showMenu renders the menu model on the main thread.
+func showMenu(model menu.Menu) {
+	dispatch.MainQueue().DispatchAsync(func() {
+		updateMenu(model)
+	})
+}
+
+// updateMenu renders the menu model into a NEW AppKit menu and assigns it
+// to the statusItem. It must be called on the main thread.
+func updateMenu(model menu.Menu) {
+	log.Println("Updating menu...")
+	// Create a new menu instance for this update
+	newMenu := appkit.MenuClass.New()
+	addSections(newMenu, model.Sections)
+
+	// Assign the completely new menu to the status item
+	statusItem.SetMenu(newMenu)
+	statusItem.Button().SetTitle(model.Badge)
+	log.Println("Menu updated successfully.")
+}
+
+// addSections appends sections to an AppKit menu, separated by separators and
+// introduced by their (disabled) headers.
+func addSections(target appkit.Menu, sections []menu.Section) {
+	for i, section := range sections {
+		if i > 0 {
+			target.AddItem(appkit.MenuItemClass.SeparatorItem())
+		}
+
+		if section.Header != "" {
+			header := appkit.MenuItemClass.Alloc().InitWithTitleActionKeyEquivalent(section.Header, objc.Sel(""), "")
+			header.SetEnabled(false)
+			target.AddItem(header)
+		}
+
+		for _, item := range section.Items {
+			target.AddItem(newMenuItem(item))
+		}
+	}
+}
+
+// newMenuItem creates the AppKit item for a single model item.
+func newMenuItem(item menu.Item) appkit.MenuItem {
+	var menuItem appkit.MenuItem
+	switch item.Action.Kind {
+	case menu.ActionNone:
+		menuItem = appkit.MenuItemClass.Alloc().InitWithTitleActionKeyEquivalent(item.Title, objc.Sel(""), item.KeyEquivalent)
+	case menu.ActionQuit:
+		menuItem = appkit.MenuItemClass.New()
+		menuItem.SetTitle(item.Title)
+		menuItem.SetAction(objc.Sel("terminate:"))
+		menuItem.SetTarget(appkit.Application_SharedApplication())
+	default:
+		localItem := item // Important: Make a copy for the closure
+		menuItem = appkit.NewMenuItemWithAction(item.Title, item.KeyEquivalent, func(sender objc.Object) {
+			handleAction(localItem)
+		})
+	}
+
+	menuItem.SetEnabled(item.Enabled)
+	if item.Tooltip != "" {
+		menuItem.SetToolTip(item.Tooltip)
+	}
+	if item.Checked {
+		menuItem.SetState(appkit.ControlStateValueOn)
+	}
+	if color, ok := urgencyColor(item.Urgency); ok {
+		attributes := map[foundation.AttributedStringKey]objc.IObject{foregroundColor: color}
+		menuItem.SetAttributedTitle(foundation.NewAttributedStringWithStringAttributes(item.Title, attributes))
+	}
+	if r, g, b, ok := item.Indicator.RGB(); ok {
+		menuItem.SetImage(statusDot(r, g, b))
+	}
+	if len(item.Submenu) > 0 {
+		submenu := appkit.MenuClass.New()
+		addSections(submenu, item.Submenu)
+		menuItem.SetSubmenu(submenu)
+	}
+	return menuItem
+}
+
+// openURL opens a URL in the default browser.
+func openURL(rawURL string) {
+	log.Printf("Opening %s", rawURL)
+	url := foundation.URLClass.URLWithString(rawURL)
+	if url.IsNil() {
+		log.Printf("Error: Could not create URL from string: %s", rawURL)
+		return
+	}
+	ok := appkit.Workspace_SharedWorkspace().OpenURL(url)
+	if !ok {
+		log.Printf("Error: Failed to open URL %s", rawURL)
+	}
+}
+
+// promptScript shows a text entry dialog and prints what was entered. The
+// title and message are passed as arguments to avoid quoting issues.
+const promptScript = `on run argv
+	activate
+	text returned of (display dialog (item 2 of argv) default answer "" with title (item 1 of argv))
+end run`
+
+// promptText asks the user for a line of text. It reports false if the
+// dialog was cancelled.
+func promptText(title, message string) (string, bool) {
+	out, err := exec.Command("osascript", "-e", promptScript, title, message).Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+// secretScript is promptScript with the answer hidden as it is typed.
+const secretScript = `on run argv
+	activate
+	text returned of (display dialog (item 2 of argv) default answer "" with title (item 1 of argv) with hidden answer)
+end run`
+
+// promptSecret is promptText for passwords and keys.
+func promptSecret(title, message string) (string, bool) {
+	out, err := exec.Command("osascript", "-e", secretScript, title, message).Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+// quit terminates the application.
+func quit() {
+	dispatch.MainQueue().DispatchAsync(func() {
+		appkit.Application_SharedApplication().Terminate(nil)
+	})
+}
+
+// foregroundColor is the value of NSForegroundColorAttributeName.
+const foregroundColor = foundation.AttributedStringKey("NSColor")
+
+// urgencyColor is the title color of items of the given urgency.
+func urgencyColor(urgency menu.Urgency) (appkit.Color, bool) {
+	switch urgency {
+	case menu.Overdue:
+		return appkit.Color_SystemRedColor(), true
+	case menu.DueToday:
+		return appkit.Color_SystemOrangeColor(), true
+	}
+	return appkit.Color{}, false
+}
+
+// statusDot draws the small colored circle shown next to items with an
+// indicator.
+func statusDot(r, g, b float64) appkit.Image {
+	size := foundation.Size{Width: 10, Height: 10}
+	return appkit.Image_ImageWithSizeFlippedDrawingHandler(size, false, func(rect foundation.Rect) bool {
+		appkit.Color_ColorWithSRGBRedGreenBlueAlpha(r, g, b, 1).SetFill()
+		appkit.BezierPath_BezierPathWithOvalInRect(rect).Fill()
+		return true
+	})
+}
@@ -0,0 +1,140 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/pzurek/lil/internal/menu"
+	"github.com/pzurek/lil/internal/sni"
+)
+
+// Global variables for UI elements
+var (
+	tray     *sni.Tray
+	quitting = make(chan struct{})
+	quitOnce sync.Once
+)
+
+// runTray publishes a StatusNotifierItem on the session bus and blocks until
+// the user quits or the process is signalled.
+func runTray() error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the D-Bus session bus: %w", err)
+	}
+	defer conn.Close()
+
+	icon, err := trayIcon()
+	if err != nil {
+		return err
+	}
+
+	tray, err = sni.New(conn, sni.Options{
+		ID:      "lil",
+		Title:   "Lil",
+		Icon:    icon,
+		OnClick: handleAction,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tray icon: %w", err)
+	}
+	defer tray.Close()
+
+	// Create the initial menu with Loading... and Quit
+	tray.SetMenu(menu.Loading())
+
+	startRefreshing()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-quitting:
+	case sig := <-signals:
+		log.Printf("Received %s, exiting", sig)
+	}
+	return nil
+}
+
+// trayIcon decodes the embedded template icon and recolors it for Linux
+// panels, which don't tint template images the way macOS does.
+func trayIcon() (image.Image, error) {
+	src, err := png.Decode(bytes.NewReader(iconData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode icon: %w", err)
+	}
+	// A mid grey stays visible on both light and dark panels.
+	icon := image.NewNRGBA(src.Bounds())
+	draw.DrawMask(icon, icon.Bounds(), image.NewUniform(color.NRGBA{R: 0xbe, G: 0xbe, B: 0xbe, A: 0xff}), image.Point{}, src, src.Bounds().Min, draw.Src)
+	return icon, nil
+}
+
+// showMenu replaces the tray menu. The D-Bus backend is safe to call from
+// any goroutine.
+func showMenu(model menu.Menu) {
+	tray.SetMenu(model)
+}
+
+// openURL opens a URL in the default browser.
+func openURL(rawURL string) {
+	log.Printf("Opening %s", rawURL)
+	if err := exec.Command("xdg-open", rawURL).Start(); err != nil {
+		log.Printf("Error: Failed to open URL %s: %v", rawURL, err)
+	}
+}
+
+// promptText asks the user for a line of text using zenity or kdialog,
+// whichever is installed. It reports false if the dialog was cancelled.
+func promptText(title, message string) (string, bool) {
+	var cmd *exec.Cmd
+	if path, err := exec.LookPath("zenity"); err == nil {
+		cmd = exec.Command(path, "--entry", "--title="+title, "--text="+message)
+	} else if path, err := exec.LookPath("kdialog"); err == nil {
+		cmd = exec.Command(path, "--title", title, "--inputbox", message)
+	} else {
+		log.Printf("Error: Neither zenity nor kdialog is installed, cannot prompt for input")
+		return "", false
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+// promptSecret is promptText for passwords and keys.
+func promptSecret(title, message string) (string, bool) {
+	var cmd *exec.Cmd
+	if path, err := exec.LookPath("zenity"); err == nil {
+		cmd = exec.Command(path, "--entry", "--hide-text", "--title="+title, "--text="+message)
+	} else if path, err := exec.LookPath("kdialog"); err == nil {
+		cmd = exec.Command(path, "--title", title, "--password", message)
+	} else {
+		log.Printf("Error: Neither zenity nor kdialog is installed, cannot prompt for input")
+		return "", false
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+// quit makes runTray return.
+func quit() {
+	quitOnce.Do(func() { close(quitting) })
+}
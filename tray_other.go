@@ -0,0 +1,25 @@
+//go:build !darwin && !linux
+
+package main
+
+import (
+	"errors"
+	"runtime"
+
+	"github.com/pzurek/lil/internal/menu"
+)
+
+// runTray reports that this platform has no tray backend yet.
+func runTray() error {
+	return errors.New("the tray is not supported on " + runtime.GOOS)
+}
+
+func showMenu(model menu.Menu) {}
+
+func openURL(rawURL string) {}
+
+func promptText(title, message string) (string, bool) { return "", false }
+
+func promptSecret(title, message string) (string, bool) { return "", false }
+
+func quit() {}
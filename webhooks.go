@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pzurek/lil/internal/webhook"
+)
+
+// webhookTimeout bounds applying a single delivery, which may look up the
+// viewer first.
+const webhookTimeout = 30 * time.Second
+
+// webhookQueueSize is how many verified deliveries can wait to be applied.
+const webhookQueueSize = 100
+
+// webhookQueue holds verified events until applyQueuedWebhooks gets to them.
+// Deliveries are answered once queued, as applying an event can wait on
+// Linear for longer than Linear waits for the answer.
+var webhookQueue = make(chan queuedWebhook, webhookQueueSize)
+
+// startApplyingWebhooks starts applyQueuedWebhooks with the first receiver.
+var startApplyingWebhooks sync.Once
+
+// queuedWebhook is an event waiting to be applied to its workspace.
+type queuedWebhook struct {
+	w     *workspace
+	event webhook.Event
+}
+
+// webhookShutdownTimeout bounds waiting for deliveries in progress when the
+// receiver moves to another address.
+const webhookShutdownTimeout = 5 * time.Second
+
+// webhookReceiver is the running webhook server and the address it listens
+// on, if any.
+var webhookReceiver = struct {
+	sync.Mutex
+	listen string
+	server *http.Server
+}{}
+
+// listenForWebhooks receives webhook deliveries on listen, restarting the
+// receiver if it listens elsewhere. An empty listen stops it.
+func listenForWebhooks(listen string) {
+	webhookReceiver.Lock()
+	defer webhookReceiver.Unlock()
+	if listen == webhookReceiver.listen {
+		return
+	}
+	if server := webhookReceiver.server; server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Error stopping the webhook receiver: %v", err)
+		}
+		log.Printf("Stopped receiving webhooks on http://%s/webhook", webhookReceiver.listen)
+	}
+	webhookReceiver.listen, webhookReceiver.server = listen, nil
+	if listen != "" {
+		webhookReceiver.server = startWebhooks(listen)
+	}
+}
+
+// startWebhooks receives Linear webhook deliveries on listen: at /webhook for
+// the first workspace and at /webhook/NAME for each workspace by name.
+func startWebhooks(listen string) *http.Server {
+	startApplyingWebhooks.Do(func() { go applyQueuedWebhooks(webhookQueue) })
+	handler := webhook.NewHandler(webhookSecret, queueWebhook)
+	mux := http.NewServeMux()
+	mux.Handle("/webhook", handler)
+	mux.Handle("/webhook/{workspace}", handler)
+	server := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		log.Printf("Receiving webhooks on http://%s/webhook", listen)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Error receiving webhooks: %v", err)
+		}
+	}()
+	return server
+}
+
+// webhookSecret returns the signing secret for deliveries to r's workspace.
+func webhookSecret(r *http.Request) (string, bool) {
+	return currentConfig().WebhookSecret(r.PathValue("workspace"))
+}
+
+// queueWebhook queues a delivered event for r's workspace. A full queue
+// drops it, leaving the change to the next refresh.
+func queueWebhook(r *http.Request, event webhook.Event) {
+	ws, ok := currentConfig().Workspace(r.PathValue("workspace"))
+	if !ok {
+		return
+	}
+	w := findWorkspace(ws.Name)
+	if w == nil {
+		return
+	}
+	select {
+	case webhookQueue <- queuedWebhook{w: w, event: event}:
+	default:
+		log.Printf("Warning: Dropping webhook event for %s, too many are waiting", w.name)
+	}
+}
+
+// applyQueuedWebhooks applies the events of queue in the order they were
+// delivered, showing each result right away.
+func applyQueuedWebhooks(queue <-chan queuedWebhook) {
+	for queued := range queue {
+		applyWebhook(queued.w, queued.event)
+	}
+}
+
+// applyWebhook applies event to w and shows the result.
+func applyWebhook(w *workspace, event webhook.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	if err := w.applyEvent(ctx, event); err != nil {
+		log.Printf("Error applying webhook event to %s: %v", w.name, err)
+		return
+	}
+	renderMenu()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pzurek/lil/internal/config"
+	"github.com/pzurek/lil/internal/webhook"
+)
+
+func TestQueueWebhook(t *testing.T) {
+	defer func() { workspaces.list = nil }()
+	defer setConfig(currentConfig())
+	cfg := config.Default()
+	cfg.Workspaces = []config.Workspace{{Name: "acme"}, {Name: "globex"}}
+	setConfig(cfg)
+	syncWorkspaces(cfg)
+	defer func(queue chan queuedWebhook) { webhookQueue = queue }(webhookQueue)
+	webhookQueue = make(chan queuedWebhook, 1)
+
+	// Deliveries are queued for their workspace without applying them, which
+	// may take a call to Linear
+	r := httptest.NewRequest(http.MethodPost, "/webhook/globex", nil)
+	r.SetPathValue("workspace", "globex")
+	queueWebhook(r, webhook.Event{Action: webhook.ActionCreate})
+	select {
+	case queued := <-webhookQueue:
+		if queued.w != findWorkspace("globex") || queued.event.Action != webhook.ActionCreate {
+			t.Errorf("Expected the event to be queued for globex, got %+v", queued)
+		}
+	default:
+		t.Fatal("Expected the event to be queued")
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/webhook/initech", nil)
+	r.SetPathValue("workspace", "initech")
+	queueWebhook(r, webhook.Event{Action: webhook.ActionCreate})
+	if len(webhookQueue) != 0 {
+		t.Errorf("Expected events for unknown workspaces to be dropped, got %d queued", len(webhookQueue))
+	}
+}
+
+func TestListenForWebhooks(t *testing.T) {
+	defer listenForWebhooks("")
+
+	addr := freeAddr(t)
+	listenForWebhooks(addr)
+	first := webhookReceiver.server
+	if first == nil || first.Addr != addr {
+		t.Fatalf("Expected a receiver on %s, got %+v", addr, first)
+	}
+
+	listenForWebhooks(addr)
+	if webhookReceiver.server != first {
+		t.Error("Expected the receiver to keep running when the address is unchanged")
+	}
+
+	moved := freeAddr(t)
+	listenForWebhooks(moved)
+	if webhookReceiver.server == first || webhookReceiver.server.Addr != moved {
+		t.Errorf("Expected the receiver to move to %s, got %+v", moved, webhookReceiver.server)
+	}
+	if err := first.ListenAndServe(); err != http.ErrServerClosed {
+		t.Errorf("Expected the old receiver to be shut down, got %v", err)
+	}
+
+	listenForWebhooks("")
+	if webhookReceiver.server != nil {
+		t.Errorf("Expected an empty address to stop the receiver, got %+v", webhookReceiver.server)
+	}
+}
+
+// freeAddr returns a loopback address nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pzurek/lil/internal/cache"
+	"github.com/pzurek/lil/internal/config"
+	"github.com/pzurek/lil/internal/credentials"
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/menu"
+	"github.com/pzurek/lil/internal/notify"
+	"github.com/pzurek/lil/internal/oauth"
+	"github.com/pzurek/lil/internal/webhook"
+)
+
+// workspace is a Linear organization lil shows issues from, together with
+// everything last fetched from it.
+type workspace struct {
+	name   string
+	client *linear.Client
+	apiKey *workspaceKey
+
+	mu     sync.Mutex
+	issues []linear.Issue
+	err    error
+	loaded bool
+	// fetchedAt is when issues were fetched, possibly by an earlier run.
+	fetchedAt time.Time
+	// fullSyncAt is when every issue was last fetched in this run, and
+	// watermark the latest update among the issues fetched since. Both are
+	// zero until the first full sync, so cached issues are never merged into.
+	// filter is what the full sync fetched; a different one needs another.
+	fullSyncAt time.Time
+	watermark  time.Time
+	filter     linear.IssueFilter
+	// own holds changes made from the menu, by issue ID, so they aren't
+	// notified about.
+	own map[string]notify.Kind
+	// viewerID is the authenticated user, looked up for the first webhook
+	// event.
+	viewerID string
+	// fetching counts the fetches in flight, and events holds the webhook
+	// events applied meanwhile, which are replayed onto their results.
+	fetching int
+	events   []webhook.Event
+	states   map[string][]linear.WorkflowState
+	teams    []linear.Team
+	inbox    *linear.Inbox
+}
+
+// notifier shows desktop notifications about changed issues
+var notifier notify.Notifier = notify.Nop{}
+
+// workspaces are the configured workspaces, in config order
+var workspaces = struct {
+	sync.Mutex
+	list []*workspace
+}{}
+
+// newWorkspace creates a workspace whose client looks its credentials up in
+// the current config on every request, so credential changes apply
+// immediately.
+func newWorkspace(name string) *workspace {
+	apiKey := &workspaceKey{name: name}
+	return &workspace{
+		name:   name,
+		apiKey: apiKey,
+		client: linear.NewClient(
+			linear.WithUserAgent(userAgent()),
+			linear.WithAPIKeySource(apiKey.Key),
+			linear.WithTokenSource(&workspaceTokens{name: name}),
+		),
+	}
+}
+
+// workspaceKey is the API key source of a workspace. The store is resolved
+// again whenever the workspace's credentials in the config change.
+type workspaceKey struct {
+	name string
+
+	mu     sync.Mutex
+	creds  config.Credentials
+	source linear.APIKeySource
+}
+
+// Key returns the workspace's API key.
+func (k *workspaceKey) Key() (string, error) {
+	ws, ok := currentConfig().Workspace(k.name)
+	if !ok {
+		return "", errors.New("workspace " + k.name + " is no longer configured")
+	}
+	k.mu.Lock()
+	if k.source == nil || ws.Credentials != k.creds {
+		k.creds = ws.Credentials
+		k.source = credentials.APIKeySource(credentials.Resolve(k.creds), k.name)
+	}
+	source := k.source
+	k.mu.Unlock()
+	return source()
+}
+
+// reset drops the cached key, so a key that was just stored is used by the
+// next request.
+func (k *workspaceKey) reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.source = nil
+}
+
+// workspaceTokens is the OAuth token source of a workspace. Unless the
+// workspace's credentials configure OAuth, it has no tokens and the client
+// uses the API key.
+type workspaceTokens struct {
+	name string
+
+	mu     sync.Mutex
+	creds  config.Credentials
+	source *oauth.Source
+}
+
+// current returns the token source for the workspace's credentials in the
+// current config, or nil if they don't use OAuth.
+func (t *workspaceTokens) current() (*oauth.Source, error) {
+	ws, ok := currentConfig().Workspace(t.name)
+	if !ok {
+		return nil, errors.New("workspace " + t.name + " is no longer configured")
+	}
+	if !ws.Credentials.OAuth.Enabled() {
+		return nil, linear.ErrNoToken
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.source == nil || ws.Credentials != t.creds {
+		t.creds = ws.Credentials
+		t.source = oauth.NewSource(oauth.NewConfig(ws.Credentials.OAuth), credentials.Resolve(ws.Credentials), t.name)
+	}
+	return t.source, nil
+}
+
+func (t *workspaceTokens) Token(ctx context.Context) (string, error) {
+	source, err := t.current()
+	if err != nil {
+		return "", err
+	}
+	return source.Token(ctx)
+}
+
+func (t *workspaceTokens) Refresh(ctx context.Context, rejected string) (string, error) {
+	source, err := t.current()
+	if err != nil {
+		return "", err
+	}
+	return source.Refresh(ctx, rejected)
+}
+
+// syncWorkspaces makes the workspace list match cfg, keeping the state of
+// workspaces that are still configured. It returns the workspaces that were
+// added.
+func syncWorkspaces(cfg *config.Config) []*workspace {
+	workspaces.Lock()
+	defer workspaces.Unlock()
+
+	existing := map[string]*workspace{}
+	for _, w := range workspaces.list {
+		existing[w.name] = w
+	}
+	var list, added []*workspace
+	for _, ws := range cfg.AllWorkspaces() {
+		w, ok := existing[ws.Name]
+		if !ok {
+			w = newWorkspace(ws.Name)
+			added = append(added, w)
+		}
+		list = append(list, w)
+	}
+	workspaces.list = list
+	return added
+}
+
+// currentWorkspaces returns the configured workspaces.
+func currentWorkspaces() []*workspace {
+	workspaces.Lock()
+	defer workspaces.Unlock()
+	return append([]*workspace(nil), workspaces.list...)
+}
+
+// findWorkspace returns the workspace with the given name, or the first one
+// if name is empty.
+func findWorkspace(name string) *workspace {
+	for _, w := range currentWorkspaces() {
+		if name == "" || w.name == name {
+			return w
+		}
+	}
+	return nil
+}
+
+// loadCache shows the workspace's cached issues until the first fetch.
+func (w *workspace) loadCache() {
+	cached, err := cacheStore.Load(w.name)
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotCached) {
+			log.Printf("Warning: Failed to load cached issues of %s: %v", w.name, err)
+		}
+		return
+	}
+	log.Printf("Loaded %d issues of %s from cache (fetched %s).", len(cached.Issues), w.name, cached.FetchedAt.Format(time.RFC1123))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.loaded {
+		w.issues, w.inbox, w.loaded = cached.Issues, cached.Inbox, true
+		w.fetchedAt = cached.FetchedAt
+	}
+}
+
+// fetch refreshes the workspace's issues and inbox and caches them. Workflow
+// states and teams rarely change, so they are only fetched once.
+func (w *workspace) fetch(ctx context.Context) error {
+	now := time.Now()
+	limit := w.client.RateLimit()
+	if until := limit.HoldUntil(now); !until.IsZero() && !limit.LimitedUntil.After(now) {
+		log.Printf("Skipping refresh of %s until %s to save rate limit budget.", w.name, until.Format(time.Kitchen))
+		return nil
+	}
+
+	cfg := currentConfig()
+	w.mu.Lock()
+	previous, known := w.issues, !w.fetchedAt.IsZero()
+	w.fetching++
+	w.mu.Unlock()
+	defer w.doneFetching()
+
+	issues, changes, err := w.sync(ctx, cfg.Sync, cfg.Filters.IssueFilter(), now)
+	if err != nil {
+		log.Printf("Error fetching issues of %s: %v", w.name, err)
+		w.mu.Lock()
+		w.err, w.loaded = err, true
+		w.mu.Unlock()
+		return err
+	}
+	log.Printf("Successfully fetched %d active issues of %s.", len(issues), w.name)
+	reportChanges(w.name, changes)
+	// Without an earlier list, every issue would look newly assigned
+	if known && !changes.Empty() && cfg.Notifications.Enabled {
+		w.notify(previous, issues, cfg)
+	}
+
+	w.mu.Lock()
+	states, teams, inbox := w.states, w.teams, w.inbox
+	w.mu.Unlock()
+
+	if states == nil {
+		fetched, err := w.client.FetchWorkflowStates(ctx)
+		if err != nil {
+			log.Printf("Error fetching workflow states of %s: %v", w.name, err)
+		}
+		states = fetched
+	}
+	if teams == nil {
+		fetched, err := w.client.FetchTeams(ctx)
+		if err != nil {
+			log.Printf("Error fetching teams of %s: %v", w.name, err)
+		}
+		teams = fetched
+	}
+	// A failed inbox fetch keeps showing the previous notifications
+	if fetched, err := w.client.FetchInbox(ctx); err != nil {
+		log.Printf("Error fetching inbox of %s: %v", w.name, err)
+	} else {
+		inbox = &fetched
+	}
+
+	w.mu.Lock()
+	// The fetch may have started before webhook events that changed
+	// issues since
+	issues = replayEvents(issues, w.viewerID, w.events)
+	w.issues, w.err, w.loaded = issues, nil, true
+	w.fetchedAt = now
+	w.states, w.teams, w.inbox = states, teams, inbox
+	w.mu.Unlock()
+
+	if err := cacheStore.Save(cache.Entry{Workspace: w.name, Issues: issues, Inbox: inbox}); err != nil {
+		log.Printf("Error caching issues of %s: %v", w.name, err)
+		// Continue anyway, caching is not critical
+	}
+	return nil
+}
+
+// doneFetching ends a fetch started by fetch. The events kept for it are
+// dropped once no fetch is left in flight.
+func (w *workspace) doneFetching() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fetching--
+	if w.fetching == 0 {
+		w.events = nil
+	}
+}
+
+// replayEvents applies events to issues fetched while they arrived, except
+// where the fetch already has a newer version of their issue.
+func replayEvents(issues []linear.Issue, viewerID string, events []webhook.Event) []linear.Issue {
+	for _, event := range events {
+		i := slices.IndexFunc(issues, func(issue linear.Issue) bool { return issue.Id == event.Issue.Id })
+		if i >= 0 && !updatedAfter(event.Issue, issues[i]) {
+			continue
+		}
+		issues, _ = webhook.ApplyTo(issues, viewerID, event)
+	}
+	return issues
+}
+
+// updatedAfter reports whether a was updated after b.
+func updatedAfter(a, b linear.Issue) bool {
+	at, errA := time.Parse(time.RFC3339, a.UpdatedAt)
+	bt, errB := time.Parse(time.RFC3339, b.UpdatedAt)
+	return errA == nil && errB == nil && at.After(bt)
+}
+
+// sync fetches the workspace's issues matching filter, either all of them or,
+// when incremental sync is on and the last full sync is recent enough and
+// used the same filter, only those updated since the watermark. It returns
+// the new issue list and how it differs from the previous one.
+func (w *workspace) sync(ctx context.Context, cfg config.Sync, filter linear.IssueFilter, now time.Time) ([]linear.Issue, linear.Changes, error) {
+	w.mu.Lock()
+	previous, fullSyncAt, watermark, synced := w.issues, w.fullSyncAt, w.watermark, w.filter
+	w.mu.Unlock()
+
+	if cfg.Incremental && !watermark.IsZero() && now.Sub(fullSyncAt) < cfg.FullInterval && reflect.DeepEqual(filter, synced) {
+		updated, err := w.client.FetchUpdatedIssues(ctx, watermark, filter)
+		if err != nil {
+			return nil, linear.Changes{}, err
+		}
+		issues, changes := linear.Merge(previous, updated)
+		w.mu.Lock()
+		w.watermark = linear.Watermark(watermark, updated)
+		w.mu.Unlock()
+		return issues, changes, nil
+	}
+
+	issues, err := w.client.FetchMatchingIssues(ctx, filter)
+	if err != nil {
+		return nil, linear.Changes{}, err
+	}
+	w.mu.Lock()
+	w.fullSyncAt, w.watermark, w.filter = now, linear.Watermark(time.Time{}, issues), filter
+	w.mu.Unlock()
+	return issues, linear.Diff(previous, issues), nil
+}
+
+// applyEvent applies an issue event delivered by webhook to the workspace's
+// issues and caches them. Issues the filters exclude are removed rather than
+// added or updated. Events arriving before the first fetch are dropped; the
+// fetch picks up their changes.
+func (w *workspace) applyEvent(ctx context.Context, event webhook.Event) error {
+	w.mu.Lock()
+	viewerID, loaded := w.viewerID, w.loaded
+	w.mu.Unlock()
+	if !loaded {
+		return nil
+	}
+	if viewerID == "" {
+		viewer, err := w.client.Viewer(ctx)
+		if err != nil {
+			return err
+		}
+		viewerID = viewer.Id
+	}
+
+	cfg := currentConfig()
+	if event.Action != webhook.ActionRemove && event.AssigneeID == viewerID {
+		matches, err := w.matches(ctx, cfg.Filters, event.Issue)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			event.Action = webhook.ActionRemove
+		}
+	}
+
+	w.mu.Lock()
+	w.viewerID = viewerID
+	previous, known := w.issues, !w.fetchedAt.IsZero()
+	issues, changes := webhook.ApplyTo(previous, viewerID, event)
+	w.issues = issues
+	if w.fetching > 0 {
+		w.events = append(w.events, event)
+	}
+	w.mu.Unlock()
+
+	if changes.Empty() {
+		return nil
+	}
+	reportChanges(w.name, changes)
+	if known && cfg.Notifications.Enabled {
+		w.notify(previous, issues, cfg)
+	}
+	if err := cacheStore.SaveIssues(w.name, issues, time.Time{}); err != nil {
+		log.Printf("Error caching issues of %s: %v", w.name, err)
+	}
+	return nil
+}
+
+// matches reports whether issue passes filters. Whether its cycle is the
+// current, next or previous one is up to Linear.
+func (w *workspace) matches(ctx context.Context, filters config.Filters, issue linear.Issue) (bool, error) {
+	if !filters.Matches(issue, time.Now()) {
+		return false, nil
+	}
+	switch filters.Cycle {
+	case linear.CycleCurrent, linear.CycleNext, linear.CyclePrevious:
+		return w.client.IssueMatches(ctx, issue.Id, filters.IssueFilter())
+	}
+	return true, nil
+}
+
+// reportChanges logs what a sync changed.
+func reportChanges(workspace string, changes linear.Changes) {
+	if changes.Empty() {
+		return
+	}
+	log.Printf("Issues of %s: %d added, %d changed, %d removed.", workspace, len(changes.Added), len(changes.Changed), len(changes.Removed))
+}
+
+// notify shows desktop notifications for what changed from previous to
+// issues, except for the changes made from the menu.
+func (w *workspace) notify(previous, issues []linear.Issue, cfg *config.Config) {
+	opts := notify.Options{
+		Assigned: cfg.Notifications.Assigned,
+		DueDate:  cfg.Notifications.DueDate,
+		State:    cfg.Notifications.State,
+		Priority: cfg.Notifications.Priority,
+	}
+	w.mu.Lock()
+	var notifications []notify.Notification
+	for _, n := range notify.Changes(previous, issues, opts) {
+		if kind, ok := w.own[n.IssueID]; ok && kind == n.Kind {
+			delete(w.own, n.IssueID)
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+	w.mu.Unlock()
+
+	workspaceName := ""
+	if len(cfg.Workspaces) > 1 {
+		workspaceName = w.name
+	}
+	for _, n := range notify.Coalesce(notifications, workspaceName) {
+		if err := notifier.Notify(n); err != nil {
+			log.Printf("Error showing notification: %v", err)
+		}
+	}
+}
+
+// expect records a change made from the menu, so that it isn't notified
+// about once the next refresh picks it up.
+func (w *workspace) expect(issueID string, kind notify.Kind) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.own == nil {
+		w.own = map[string]notify.Kind{}
+	}
+	w.own[issueID] = kind
+}
+
+// team returns the team with the given ID, if it has been fetched.
+func (w *workspace) team(id string) (linear.Team, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, team := range w.teams {
+		if team.Id == id {
+			return team, true
+		}
+	}
+	return linear.Team{}, false
+}
+
+// menuWorkspace returns what the menu shows for the workspace under cfg.
+func (w *workspace) menuWorkspace(cfg *config.Config) menu.Workspace {
+	ws, _ := cfg.Workspace(w.name)
+	grouping := effectiveGrouping(cfg)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return menu.Workspace{
+		Name:      w.name,
+		Loading:   !w.loaded,
+		Err:       w.err,
+		UpdatedAt: w.fetchedAt,
+		OAuth:     ws.Credentials.OAuth.Enabled(),
+		Data: menu.Data{
+			Issues: cfg.Filters.Apply(w.issues),
+			States: w.states,
+			Teams:  w.teams,
+			Inbox:  w.inbox,
+			Options: menu.Options{
+				Grouping:     grouping,
+				Sorting:      cfg.SortingFor(grouping),
+				HideTooltips: !cfg.Display.Tooltips,
+				HideInbox:    !cfg.Display.Inbox,
+				InboxLimit:   cfg.Display.InboxLimit,
+				HighlightDue: cfg.Display.HighlightDue,
+				DueSoonDays:  cfg.Display.DueSoon,
+				Badge:        cfg.Display.Badge,
+			},
+		},
+	}
+}
+
+// rateLimitHold returns when the next refresh should run at the earliest:
+// once the rate limit of any workspace allows it. It returns the zero time
+// if some workspace can be fetched now.
+func rateLimitHold() time.Time {
+	now := time.Now()
+	var earliest time.Time
+	for _, w := range currentWorkspaces() {
+		until := w.client.RateLimit().HoldUntil(now)
+		if until.IsZero() {
+			return time.Time{}
+		}
+		if earliest.IsZero() || until.Before(earliest) {
+			earliest = until
+		}
+	}
+	return earliest
+}
+
+// fetchAll fetches every workspace concurrently. It fails only if every
+// workspace failed, so one broken workspace doesn't slow down the refreshes
+// of the others.
+func fetchAll(ctx context.Context) error {
+	list := currentWorkspaces()
+	errs := make([]error, len(list))
+	var wg sync.WaitGroup
+	for i, w := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = w.fetch(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.Join(errs...)
+}
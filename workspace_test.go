@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pzurek/lil/internal/cache"
+	"github.com/pzurek/lil/internal/config"
+	"github.com/pzurek/lil/internal/linear"
+	"github.com/pzurek/lil/internal/notify"
+	"github.com/pzurek/lil/internal/webhook"
+)
+
+func TestSyncWorkspaces(t *testing.T) {
+	defer func() { workspaces.list = nil }()
+
+	cfg := config.Default()
+	cfg.Workspaces = []config.Workspace{{Name: "acme"}, {Name: "globex"}}
+	if added := syncWorkspaces(cfg); len(added) != 2 {
+		t.Fatalf("Expected 2 new workspaces, got %d", len(added))
+	}
+	acme := findWorkspace("acme")
+	if findWorkspace("") != acme {
+		t.Error("Expected the first workspace to be the default")
+	}
+
+	cfg.Workspaces = []config.Workspace{{Name: "initech"}, {Name: "acme"}}
+	added := syncWorkspaces(cfg)
+	if len(added) != 1 || added[0].name != "initech" {
+		t.Errorf("Expected only initech to be added, got %v", added)
+	}
+	if findWorkspace("acme") != acme {
+		t.Error("Expected acme to keep its state across reloads")
+	}
+	if findWorkspace("globex") != nil {
+		t.Error("Expected globex to be removed")
+	}
+	if findWorkspace("") != added[0] {
+		t.Error("Expected the workspaces to follow the config order")
+	}
+}
+
+func TestWorkspaceSync(t *testing.T) {
+	var filters []map[string]interface{}
+	var nodes string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables struct {
+				Filter map[string]interface{} `json:"filter"`
+			} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		filters = append(filters, req.Variables.Filter)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":{"viewer":{"assignedIssues":{"pageInfo":{"hasNextPage":false},"nodes":[%s]}}}}`, nodes)
+	}))
+	defer server.Close()
+
+	w := &workspace{name: "acme", client: linear.NewClient(linear.WithEndpoint(server.URL), linear.WithAPIKey("test-key"))}
+	cfg := config.Default().Sync
+	var filter linear.IssueFilter
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sync := func(now time.Time) linear.Changes {
+		issues, changes, err := w.sync(context.Background(), cfg, filter, now)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w.issues = issues
+		return changes
+	}
+
+	nodes = `{"id":"1","updatedAt":"2024-03-01T10:00:00Z","state":{"type":"started"}},{"id":"2","updatedAt":"2024-03-01T11:00:00Z","state":{"type":"started"}}`
+	if changes := sync(start); len(changes.Added) != 2 || filters[0]["state"] == nil {
+		t.Errorf("Expected a full sync adding both issues, got %+v with filter %v", changes, filters[0])
+	}
+
+	nodes = `{"id":"2","updatedAt":"2024-03-01T12:01:00Z","state":{"type":"completed"}}`
+	if changes := sync(start.Add(5 * time.Minute)); fmt.Sprint(changes.Removed) != "[2]" || len(w.issues) != 1 {
+		t.Errorf("Expected the completed issue to be removed, got %+v", changes)
+	}
+	if want := map[string]interface{}{"gt": "2024-03-01T11:00:00Z"}; fmt.Sprint(filters[1]["updatedAt"]) != fmt.Sprint(want) {
+		t.Errorf("Expected an incremental sync since the watermark, got filter %v", filters[1])
+	}
+
+	nodes = ``
+	sync(start.Add(10 * time.Minute))
+	if want := map[string]interface{}{"gt": "2024-03-01T12:01:00Z"}; fmt.Sprint(filters[2]["updatedAt"]) != fmt.Sprint(want) {
+		t.Errorf("Expected the watermark to advance, got filter %v", filters[2])
+	}
+
+	// Unassigned issues are only noticed by the periodic full sync
+	if changes := sync(start.Add(time.Hour)); fmt.Sprint(changes.Removed) != "[1]" || filters[3]["state"] == nil {
+		t.Errorf("Expected a full sync removing the unassigned issue, got %+v with filter %v", changes, filters[3])
+	}
+
+	// Changed filters need a full sync
+	nodes = `{"id":"3","updatedAt":"2024-03-01T12:30:00Z","state":{"type":"started"}}`
+	filter.Teams = []string{"ENG"}
+	sync(start.Add(time.Hour + time.Minute))
+	if filters[4]["state"] == nil || filters[4]["team"] == nil {
+		t.Errorf("Expected a full sync with the new filter, got filter %v", filters[4])
+	}
+	sync(start.Add(time.Hour + 2*time.Minute))
+	if filters[5]["updatedAt"] == nil || filters[5]["team"] == nil {
+		t.Errorf("Expected an incremental sync with the new filter, got filter %v", filters[5])
+	}
+
+	cfg.Incremental = false
+	sync(start.Add(time.Hour + 3*time.Minute))
+	if filters[6]["state"] == nil {
+		t.Errorf("Expected only full syncs when incremental sync is off, got filter %v", filters[6])
+	}
+}
+
+func TestWorkspaceNotify(t *testing.T) {
+	recorder := &notify.Recorder{}
+	defer func(previous notify.Notifier) { notifier = previous }(notifier)
+	notifier = recorder
+
+	issue := func(id, stateID string) linear.Issue {
+		i := linear.Issue{Id: id, Identifier: "ENG-" + id}
+		i.State.Id, i.State.Type = stateID, "started"
+		return i
+	}
+	w := &workspace{name: "acme"}
+	w.expect("1", notify.StateChanged)
+	w.notify(
+		[]linear.Issue{issue("1", "todo"), issue("2", "todo")},
+		[]linear.Issue{issue("1", "done"), issue("2", "done"), issue("3", "todo")},
+		config.Default(),
+	)
+
+	var titles []string
+	for _, n := range recorder.Notifications() {
+		titles = append(titles, n.Title)
+	}
+	if fmt.Sprint(titles) != "[ENG-2 moved to started ENG-3 was assigned to you]" {
+		t.Errorf("Expected notifications about the changes not made from the menu, got %q", titles)
+	}
+	if len(w.own) != 0 {
+		t.Errorf("Expected the expected change to be used up, got %v", w.own)
+	}
+}
+
+func TestWorkspaceApplyEvent(t *testing.T) {
+	defer func(previous *cache.Store) { cacheStore = previous }(cacheStore)
+	cacheStore = cache.New(t.TempDir())
+	var viewerQueries int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		viewerQueries++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"viewer":{"id":"user-1","name":"Ada"}}}`)
+	}))
+	defer server.Close()
+
+	w := &workspace{name: "acme", client: linear.NewClient(linear.WithEndpoint(server.URL), linear.WithAPIKey("test-key"))}
+	event := func(action, id, assigneeID string) webhook.Event {
+		issue := linear.Issue{Id: id, Identifier: "ENG-" + id}
+		issue.State.Type = "started"
+		return webhook.Event{Action: action, Issue: issue, AssigneeID: assigneeID}
+	}
+
+	if err := w.applyEvent(context.Background(), event(webhook.ActionCreate, "1", "user-1")); err != nil || len(w.issues) != 0 {
+		t.Errorf("Expected events before the first fetch to be dropped, got %v and %+v", err, w.issues)
+	}
+
+	w.loaded = true
+	steps := []struct {
+		event  webhook.Event
+		expect string
+	}{
+		{event: event(webhook.ActionCreate, "1", "user-1"), expect: "[ENG-1]"},
+		{event: event(webhook.ActionCreate, "2", "user-2"), expect: "[ENG-1]"},
+		{event: event(webhook.ActionUpdate, "2", "user-1"), expect: "[ENG-1 ENG-2]"},
+		{event: event(webhook.ActionRemove, "1", "user-1"), expect: "[ENG-2]"},
+	}
+	for _, step := range steps {
+		if err := w.applyEvent(context.Background(), step.event); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var identifiers []string
+		for _, issue := range w.issues {
+			identifiers = append(identifiers, issue.Identifier)
+		}
+		if fmt.Sprint(identifiers) != step.expect {
+			t.Errorf("After %s of %s: expected %s, got %v", step.event.Action, step.event.Issue.Id, step.expect, identifiers)
+		}
+	}
+	if viewerQueries != 1 {
+		t.Errorf("Expected the viewer to be looked up once, got %d queries", viewerQueries)
+	}
+	if cached, err := cacheStore.Load("acme"); err != nil || len(cached.Issues) != 1 {
+		t.Errorf("Expected the issues to be cached, got %+v (%v)", cached, err)
+	}
+
+	// Issues the filters exclude are dropped, and events arriving during a
+	// fetch are kept for it
+	defer setConfig(currentConfig())
+	cfg := config.Default()
+	cfg.Filters.Teams = []string{"OPS"}
+	setConfig(cfg)
+	w.fetching = 1
+	if err := w.applyEvent(context.Background(), event(webhook.ActionUpdate, "2", "user-1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := w.applyEvent(context.Background(), event(webhook.ActionCreate, "3", "user-1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(w.issues) != 0 {
+		t.Errorf("Expected filtered out issues to be removed, got %+v", w.issues)
+	}
+	if len(w.events) != 2 || w.events[0].Action != webhook.ActionRemove {
+		t.Errorf("Expected the events to be kept as removals, got %+v", w.events)
+	}
+}
+
+func TestReplayEvents(t *testing.T) {
+	issue := func(id, updatedAt string) linear.Issue {
+		issue := linear.Issue{Id: id, Identifier: "ENG-" + id, Title: "Fetched", UpdatedAt: updatedAt}
+		issue.State.Type = "started"
+		return issue
+	}
+	event := func(action, id, updatedAt string) webhook.Event {
+		updated := issue(id, updatedAt)
+		updated.Title = "Webhook"
+		return webhook.Event{Action: action, Issue: updated, AssigneeID: "user-1"}
+	}
+
+	fetched := []linear.Issue{
+		issue("1", "2024-03-01T14:30:00.000Z"),
+		issue("2", "2024-03-01T14:40:00.000Z"),
+		issue("3", "2024-03-01T14:30:00.000Z"),
+	}
+	events := []webhook.Event{
+		event(webhook.ActionUpdate, "1", "2024-03-01T14:35:00.000Z"),
+		event(webhook.ActionUpdate, "2", "2024-03-01T14:35:00.000Z"),
+		event(webhook.ActionRemove, "3", "2024-03-01T14:35:00.000Z"),
+		event(webhook.ActionCreate, "4", "2024-03-01T14:35:00.000Z"),
+	}
+
+	var got []string
+	for _, issue := range replayEvents(fetched, "user-1", events) {
+		got = append(got, issue.Identifier+" "+issue.Title)
+	}
+	expected := "[ENG-1 Webhook ENG-2 Fetched ENG-4 Webhook]"
+	if fmt.Sprint(got) != expected {
+		t.Errorf("Expected %s, got %v", expected, got)
+	}
+}